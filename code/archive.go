@@ -0,0 +1,155 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Archive values: how to bundle the output folder.
+const (
+	archiveZip = "zip"
+	archiveTgz = "tgz"
+)
+
+// archiveExts are the file extensions for each Archive format.
+var archiveExts = map[string]string{
+	archiveZip: ".zip",
+	archiveTgz: ".tar.gz",
+}
+
+// archivePath is where createArchive writes dir's archive: next to the
+// folder, named after it ("out/Tuesday" -> "out/Tuesday.zip"), so it never
+// ends up inside itself.
+func archivePath(dir, format string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return abs + archiveExts[format], nil
+}
+
+// createArchive packs every file in dir, except -cache's state file, into a
+// zip or gzipped tarball at archivePath, with paths relative to dir. It
+// returns the archive's path.
+func createArchive(dir, format string) (string, error) {
+	if _, ok := archiveExts[format]; !ok {
+		return "", fmt.Errorf("unknown archive format '%s'", format)
+	}
+	path, err := archivePath(dir, format)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if format == archiveZip {
+		err = writeZip(f, dir)
+	} else {
+		err = writeTgz(f, dir)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// archiveFiles calls add for each file to archive, with its path relative
+// to dir in slash form.
+func archiveFiles(dir string, add func(name, path string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || uploadExcluded(dir, path) {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return add(filepath.ToSlash(rel), path, info)
+	})
+}
+
+func writeZip(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := archiveFiles(dir, func(name, path string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		header.Method = zip.Store // the songs are already compressed
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return copyFileTo(entry, path)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeTgz(w io.Writer, dir string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	err := archiveFiles(dir, func(name, path string, info fs.FileInfo) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		return copyFileTo(tw, path)
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// uploadArchive uploads the archive made from outputDir to where the folder
+// itself would have gone, i.e. dest's folder for outputDir's parent.
+// upload_mode "sync" is treated as "copy": there's one file to send and no
+// folder to mirror.
+func uploadArchive(archive, outputDir string, dest UploadDestination, mode string, globalFlags []string) error {
+	destination := buildRemotePath(dest.Remote, dest.Subfolder, filepath.Dir(outputDir))
+	if mode == uploadSync {
+		mode = uploadCopy
+	}
+	log.Printf("Uploading archive '%s' to '%s' (%s)", archive, destination, mode)
+	cmd := rcloneCommand(globalFlags, rcloneUploadArgs(mode, archive, destination)...)
+	cmd.Stdout = log.Writer()
+	cmd.Stderr = log.Writer()
+	return cmd.Run()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeArchiveTestDir makes an output folder with two songs, a labels file
+// in a subfolder, a failed export's error log and -cache's state file.
+func writeArchiveTestDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "Tuesday")
+	for name, data := range map[string]string{
+		"01 - Reba.mp4":                "reba",
+		"02 - Tweezer.mp4":             "tweezer",
+		"notes/labels.txt":             "0\t245\tReba\n",
+		stateFileName:                  "{}",
+		"Song_03.mp4" + errorLogSuffix: "Invalid data found when processing input",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+var wantArchiveEntries = []string{"01 - Reba.mp4", "02 - Tweezer.mp4", "notes/labels.txt"}
+
+func TestCreateArchiveZip(t *testing.T) {
+	dir := writeArchiveTestDir(t)
+
+	path, err := createArchive(dir, archiveZip)
+	if err != nil {
+		t.Fatalf("createArchive failed: %v", err)
+	}
+	if path != dir+".zip" {
+		t.Errorf("Expected the archive next to the folder, got %s", path)
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Could not open the archive: %v", err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "02 - Tweezer.mp4" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if string(data) != "tweezer" {
+				t.Errorf("Expected the song's contents, got %q", data)
+			}
+		}
+	}
+	slices.Sort(names)
+	if !reflect.DeepEqual(names, wantArchiveEntries) {
+		t.Errorf("Expected entries %q, got %q", wantArchiveEntries, names)
+	}
+}
+
+func TestCreateArchiveTgz(t *testing.T) {
+	dir := writeArchiveTestDir(t)
+
+	path, err := createArchive(dir, archiveTgz)
+	if err != nil {
+		t.Fatalf("createArchive failed: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Expected a gzip stream: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Could not read the tarball: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	slices.Sort(names)
+	if !reflect.DeepEqual(names, wantArchiveEntries) {
+		t.Errorf("Expected entries %q, got %q", wantArchiveEntries, names)
+	}
+}
+
+func TestUploadUploadsArchive(t *testing.T) {
+	fake := installFakeExec(t, nil)
+	cfg := Config{OutputDir: "out/Tuesday", Archive: archiveTgz, RcloneRemote: "gdrive:", DriveSubfolder: "Band", UploadMode: uploadSync}
+
+	if results := uploadToDrive(cfg); results[0].Status != statusUploaded {
+		t.Fatalf("Expected the upload to succeed, got %+v", results)
+	}
+	archive, _ := archivePath("out/Tuesday", archiveTgz)
+	got := strings.Join(fake.calls[0].args, " ")
+	if want := "copy " + archive + " gdrive:Band/out -P"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+// auto_tune moves the threshold autoTuneStep dB per pass, for at most
+// autoTuneMaxPasses detection passes, and never outside these bounds.
+const (
+	autoTuneStep      = 3.0
+	autoTuneMaxPasses = 5
+	autoTuneLoudest   = -10.0
+	autoTuneQuietest  = -70.0
+)
+
+// expectedSongRange returns the song counts auto_tune accepts: within a
+// fifth (at least one song) of ExpectedSongs, or else the min/max expected
+// segments. ok is false when there's nothing to aim for.
+func expectedSongRange(cfg Config) (lo, hi int, ok bool) {
+	if cfg.ExpectedSongs > 0 {
+		slack := max(1, cfg.ExpectedSongs/5)
+		return max(1, cfg.ExpectedSongs-slack), cfg.ExpectedSongs + slack, true
+	}
+	if cfg.MinExpectedSegments > 0 || cfg.MaxExpectedSegments > 0 {
+		hi := cfg.MaxExpectedSegments
+		if hi == 0 {
+			hi = int(^uint(0) >> 1)
+		}
+		return cfg.MinExpectedSegments, hi, true
+	}
+	return 0, 0, false
+}
+
+// tuneDirection says which way to move the threshold: +1 to raise it (less
+// negative) when too few songs were found, since quieter gaps then count as
+// silence; -1 to lower it when quiet passages are splitting songs; 0 when
+// the count is in range.
+func tuneDirection(count, lo, hi int) int {
+	switch {
+	case count < lo:
+		return 1
+	case count > hi:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// autoTuneThreshold runs detection, adjusting the silence threshold between
+// passes until the song count lands in the expected range. It returns the
+// threshold to use and the silences found with it. If no pass lands in
+// range it returns the closest one along with an error.
+func autoTuneThreshold(cfg Config, totalDuration float64) (string, []segment, error) {
+	return tuneThreshold(cfg, totalDuration, detectSilence)
+}
+
+// tuneThreshold is autoTuneThreshold with the detection pass passed in.
+func tuneThreshold(cfg Config, totalDuration float64, detect func(Config) []segment) (string, []segment, error) {
+	lo, hi, ok := expectedSongRange(cfg)
+	db, err := parseDecibels(cfg.SilenceThreshold)
+	if !ok || err != nil {
+		if err == nil {
+			err = fmt.Errorf("auto_tune needs expected_songs or min/max_expected_segments")
+		}
+		return cfg.SilenceThreshold, detect(cfg), err
+	}
+
+	var bestThreshold string
+	var bestSilences []segment
+	bestMiss, bestCount, lastDir := -1, 0, 0
+	for pass := 1; pass <= autoTuneMaxPasses; pass++ {
+		try := cfg
+		try.SilenceThreshold = fmt.Sprintf("%gdB", db)
+		silences := detect(try)
+		count := len(songsFromSilences(try, silences, totalDuration))
+		log.Printf("Auto-tune pass %d: threshold %s gives %d song(s).", pass, try.SilenceThreshold, count)
+
+		miss := max(lo-count, count-hi, 0)
+		if bestMiss < 0 || miss < bestMiss {
+			bestThreshold, bestSilences, bestMiss, bestCount = try.SilenceThreshold, silences, miss, count
+		}
+		dir := tuneDirection(count, lo, hi)
+		next := db + float64(dir)*autoTuneStep
+		// Stop once in range, after stepping past the range (the pass before
+		// was on the other side of it), or at the end of the sensible range.
+		if dir == 0 || (lastDir != 0 && dir != lastDir) || next > autoTuneLoudest || next < autoTuneQuietest {
+			break
+		}
+		lastDir, db = dir, next
+	}
+	if bestMiss > 0 {
+		return bestThreshold, bestSilences, fmt.Errorf("auto_tune found no threshold giving the expected song count; using %s (%d songs)", bestThreshold, bestCount)
+	}
+	return bestThreshold, bestSilences, nil
+}
+
+// targetCountPrecision is how narrow the threshold range searched by
+// target_count gets before the search gives up on an exact match.
+const targetCountPrecision = 0.5 // dB
+
+// searchThresholdForCount binary-searches the silence threshold between
+// autoTuneQuietest and autoTuneLoudest for one that gives exactly
+// TargetCount songs. A higher threshold counts more as silence, so it
+// splits into more songs. If no threshold gives exactly that many, the
+// closest is returned along with an error.
+func searchThresholdForCount(cfg Config, totalDuration float64) (string, []segment, error) {
+	return searchThreshold(cfg, totalDuration, detectSilence)
+}
+
+// searchThreshold is searchThresholdForCount with the detection pass passed
+// in.
+func searchThreshold(cfg Config, totalDuration float64, detect func(Config) []segment) (string, []segment, error) {
+	lo, hi := autoTuneQuietest, autoTuneLoudest
+	var bestThreshold string
+	var bestSilences []segment
+	bestMiss, bestCount := -1, 0
+	for pass := 1; hi-lo >= targetCountPrecision; pass++ {
+		mid := math.Round((lo+hi)/2*10) / 10
+		try := cfg
+		try.SilenceThreshold = fmt.Sprintf("%gdB", mid)
+		silences := detect(try)
+		count := len(songsFromSilences(try, silences, totalDuration))
+		log.Printf("Threshold search pass %d: %s gives %d song(s).", pass, try.SilenceThreshold, count)
+
+		miss := max(count-cfg.TargetCount, cfg.TargetCount-count)
+		if bestMiss < 0 || miss < bestMiss {
+			bestThreshold, bestSilences, bestMiss, bestCount = try.SilenceThreshold, silences, miss, count
+		}
+		switch {
+		case count == cfg.TargetCount:
+			return bestThreshold, bestSilences, nil
+		case count < cfg.TargetCount:
+			lo = mid
+		default:
+			hi = mid
+		}
+	}
+	return bestThreshold, bestSilences, fmt.Errorf("no threshold between %gdB and %gdB gives exactly %d songs; using %s (%d songs)",
+		autoTuneQuietest, autoTuneLoudest, cfg.TargetCount, bestThreshold, bestCount)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeDetector finds more silences the higher (less negative) the threshold:
+// one gap every 600/(n+1) seconds for n = threshold+45 dB, capped at 0.
+func fakeDetector(tried *[]string) func(Config) []segment {
+	return func(cfg Config) []segment {
+		*tried = append(*tried, cfg.SilenceThreshold)
+		db, _ := parseDecibels(cfg.SilenceThreshold)
+		n := max(int(db)+45, 0)
+		var silences []segment
+		for i := 1; i <= n; i++ {
+			at := 600 * float64(i) / float64(n+1)
+			silences = append(silences, segment{start: at, end: at + 2})
+		}
+		return silences
+	}
+}
+
+func TestTuneThreshold(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cfg       Config
+		wantTried []string
+		wantErr   bool
+	}{
+		// -45dB finds no gaps (one song); -42dB finds 3 gaps (4 songs).
+		{"RaisesUntilInRange", Config{SilenceThreshold: "-45dB", ExpectedSongs: 4}, []string{"-45dB", "-42dB"}, false},
+		// -30dB finds 15 gaps (16 songs); each step down drops 3, to 7 at -39dB.
+		{"LowersUntilInRange", Config{SilenceThreshold: "-30dB", ExpectedSongs: 8}, []string{"-30dB", "-33dB", "-36dB", "-39dB"}, false},
+		{"InRangeFirstTime", Config{SilenceThreshold: "-40dB", ExpectedSongs: 6}, []string{"-40dB"}, false},
+		// 5 songs at -41dB, 2 at -44dB: stepping past exactly 3 stops there.
+		{"StopsWhenItOvershoots", Config{SilenceThreshold: "-41dB", MinExpectedSegments: 3, MaxExpectedSegments: 3}, []string{"-41dB", "-44dB"}, true},
+		{"GivesUpAfterMaxPasses", Config{SilenceThreshold: "-69dB", ExpectedSongs: 20}, []string{"-69dB", "-66dB", "-63dB", "-60dB", "-57dB"}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var tried []string
+			threshold, _, err := tuneThreshold(tc.cfg, 600, fakeDetector(&tried))
+			if !reflect.DeepEqual(tried, tc.wantTried) {
+				t.Errorf("Expected passes %q, got %q", tc.wantTried, tried)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error=%v, got %v", tc.wantErr, err)
+			}
+			if !tc.wantErr && threshold != tried[len(tried)-1] {
+				t.Errorf("Expected the last pass's threshold, got %s", threshold)
+			}
+		})
+	}
+}
+
+func TestTuneDirection(t *testing.T) {
+	testCases := []struct{ count, want int }{{2, 1}, {4, 0}, {6, 0}, {7, -1}}
+	for _, tc := range testCases {
+		if got := tuneDirection(tc.count, 4, 6); got != tc.want {
+			t.Errorf("tuneDirection(%d, 4, 6) = %d, want %d", tc.count, got, tc.want)
+		}
+	}
+}
+
+func TestSearchThreshold(t *testing.T) {
+	var tried []string
+	threshold, silences, err := searchThreshold(Config{TargetCount: 8}, 600, fakeDetector(&tried))
+	if err != nil {
+		t.Fatalf("Expected a threshold giving 8 songs, got %v", err)
+	}
+	if want := []string{"-40dB", "-25dB", "-32.5dB", "-36.3dB", "-38.2dB"}; !reflect.DeepEqual(tried, want) {
+		t.Errorf("Expected passes %q, got %q", want, tried)
+	}
+	if threshold != "-38.2dB" || len(silences) != 7 {
+		t.Errorf("Expected -38.2dB with 7 silences, got %s with %d", threshold, len(silences))
+	}
+
+	tried = nil
+	threshold, _, err = searchThreshold(Config{TargetCount: 100}, 600, fakeDetector(&tried))
+	if err == nil {
+		t.Error("Expected an error when no threshold gives 100 songs")
+	}
+	// -10.5dB and -10.3dB both find 35 gaps; the first one is kept.
+	if len(tried) > 8 || threshold != "-10.5dB" {
+		t.Errorf("Expected a capped search settling on -10.5dB, got %s after %q", threshold, tried)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// batchInput is one recording of a batch run, with the config to split it.
+type batchInput struct {
+	name string // unique per batch, from the input's file name
+	cfg  Config
+}
+
+// batchInputs builds a config per input file given on the command line. By
+// default each input gets its own subfolder of OutputDir, named after it;
+// with OutputFlat the songs all go in OutputDir, prefixed with the input's
+// name. Two inputs with the same name ("tue/practice.mp4",
+// "wed/practice.mp4") get "practice" and "practice (2)", so their songs never
+// collide in either layout.
+func batchInputs(cfg Config, inputs []string) []batchInput {
+	taken := make(map[string]bool)
+	batch := make([]batchInput, 0, len(inputs))
+	for _, input := range inputs {
+		stem := sanitizeFilename(strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)))
+		// Compare case-insensitively: "Practice" and "practice" are the same
+		// folder on macOS and Windows.
+		name := uniqueName(stem, "", func(candidate string) bool { return taken[strings.ToLower(candidate)] })
+		taken[strings.ToLower(name)] = true
+
+		c := cfg
+		c.InputFile = input
+		if cfg.OutputFlat {
+			c.OutputPrefix = name + "_" + cfg.OutputPrefix
+		} else {
+			c.OutputDir = filepath.Join(cfg.OutputDir, name)
+		}
+		batch = append(batch, batchInput{name: name, cfg: c})
+	}
+	return batch
+}
+
+// batchReportPath gives each input of a batch its own -report-json file,
+// "run.json" becoming "run_practice.json".
+func batchReportPath(path, name string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + name + ext
+}
+
+// runBatch runs each input given on the command line in turn and returns the
+// first non-zero exit code, if any. A "-" setlist is read from stdin once and
+// shared by every input. With OutputFlat all inputs export into the same
+// folder, so it is cleaned out (or checked with -fail-if-not-empty) once
+// before the first input and uploaded once after the last, instead of for
+// each input, which would delete or trip over the songs just exported.
+func runBatch(cfg Config, inputs []string, reportPath string) int {
+	if cfg.SetlistFile == stdinPath {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Printf("Error: Could not read the setlist from stdin: %v", err)
+			data = []byte{}
+		}
+		cfg.stdinSetlist = data
+	}
+	// A "{count}" output_dir names a folder per input even when flat.
+	shared := cfg.OutputFlat && !strings.Contains(cfg.OutputDir, "{count}") && !cfg.CheckSetlist && !cfg.DetectOnly
+	if shared {
+		if err := prepareRunOutputDir(cfg, inputs); err != nil {
+			log.Printf("Error: %v", err)
+			return exitCodeFor(err)
+		}
+		cfg.CleanOutput, cfg.FailIfNotEmpty = false, false
+	}
+	sharedUpload := shared && cfg.UploadToDrive
+	cfg.skipUpload = sharedUpload
+
+	exitCode := exitOK
+	exported := false
+	for i, in := range batchInputs(cfg, inputs) {
+		log.Printf("--- Input %d of %d: %s ---", i+1, len(inputs), in.cfg.InputFile)
+		code := runInput(in.cfg, batchReportPath(reportPath, in.name))
+		exported = exported || code == exitOK
+		if exitCode == exitOK {
+			exitCode = code
+		}
+	}
+	if !sharedUpload {
+		return exitCode
+	}
+	if !exported {
+		log.Println("Skipping upload, no input was exported.")
+		return exitCode
+	}
+	if failed := failedUploads(uploadToDrive(cfg)); failed > 0 && exitCode == exitOK {
+		exitCode = exitUploadFailed
+	}
+	return exitCode
+}
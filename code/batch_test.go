@@ -0,0 +1,199 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBatchLayouts(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []string{filepath.Join(dir, "tue", "practice.mp4"), filepath.Join(dir, "wed", "Practice.mp4")}
+	for _, input := range inputs {
+		if err := os.MkdirAll(filepath.Dir(input), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "out")
+	testCases := []struct {
+		name string
+		flat bool
+		want []string
+	}{
+		{"Nested", false, []string{
+			filepath.Join(out, "practice", "Song_01.mp4"), filepath.Join(out, "practice", "Song_02.mp4"),
+			filepath.Join(out, "Practice (2)", "Song_01.mp4"), filepath.Join(out, "Practice (2)", "Song_02.mp4"),
+		}},
+		{"Flat", true, []string{
+			filepath.Join(out, "practice_Song_01.mp4"), filepath.Join(out, "practice_Song_02.mp4"),
+			filepath.Join(out, "Practice (2)_Song_01.mp4"), filepath.Join(out, "Practice (2)_Song_02.mp4"),
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var outputs []string
+			installFakeExec(t, func(call fakeCall) fakeResult {
+				args := strings.Join(call.args, " ")
+				switch {
+				case strings.Contains(args, "silencedetect"):
+					return fakeResult{stderr: "silence_start: 140\nsilence_end: 150\n"}
+				case strings.Contains(args, "-t "):
+					outputs = append(outputs, call.args[len(call.args)-1])
+				case strings.HasSuffix(args, ".mp4"):
+					return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+				}
+				return fakeResult{}
+			})
+			cfg := Config{OutputDir: out, OutputPrefix: "Song", SilenceThreshold: "-20dB", MinSilenceDur: 5, MinSongLength: 60, OutputFlat: tc.flat}
+
+			for _, in := range batchInputs(cfg, inputs) {
+				if err := NewSplitter(in.cfg).Run(); err != nil {
+					t.Fatalf("Run for %s failed: %v", in.cfg.InputFile, err)
+				}
+			}
+			if !reflect.DeepEqual(outputs, tc.want) {
+				t.Errorf("Expected outputs %q, got %q", tc.want, outputs)
+			}
+		})
+	}
+}
+
+func TestBatchDecidesReencodePerInput(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []string{filepath.Join(dir, "tue.mp4"), filepath.Join(dir, "wed.mkv")}
+	for _, input := range inputs {
+		if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	codecs := make(map[string]string)
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case call.name == "ffprobe":
+			return fakeResult{exitCode: 1} // no ffprobe: a container change re-encodes
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 140\nsilence_end: 150\n"}
+		case strings.Contains(args, "-t "):
+			input := call.args[slices.Index(call.args, "-i")+1]
+			codecs[filepath.Base(input)] = call.args[slices.Index(call.args, "-c:v")+1]
+		case strings.HasSuffix(args, ".mp4"), strings.HasSuffix(args, ".mkv"):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{InputFile: inputs[0], OutputDir: filepath.Join(dir, "out"), OutputPrefix: "Song", OutputContainer: "mp4",
+		SilenceThreshold: "-20dB", MinSilenceDur: 5, MinSongLength: 60}
+
+	for _, in := range batchInputs(cfg, inputs) {
+		if err := NewSplitter(in.cfg).Run(); err != nil {
+			t.Fatalf("Run for %s failed: %v", in.cfg.InputFile, err)
+		}
+	}
+
+	if want := map[string]string{"tue.mp4": "copy", "wed.mkv": "libx264"}; !reflect.DeepEqual(codecs, want) {
+		t.Errorf("Expected only the mkv re-encoded into mp4, got %v", codecs)
+	}
+}
+
+func TestBatchReportPath(t *testing.T) {
+	if got, want := batchReportPath("reports/run.json", "practice (2)"), "reports/run_practice (2).json"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := batchReportPath("", "practice"); got != "" {
+		t.Errorf("Expected no report without -report-json, got %q", got)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []string{filepath.Join(dir, "tue.mp4"), filepath.Join(dir, "wed.mp4")}
+	for _, input := range inputs {
+		if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("Reba\nTom\n")
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+
+	var uploads int
+	var titles []string
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case call.name == "rclone":
+			if slices.Contains(call.args, "copy") {
+				uploads++
+			}
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 140\nsilence_end: 150\n"}
+		case strings.Contains(args, "-t "):
+			titles = append(titles, call.args[slices.Index(call.args, "-metadata")+1])
+		case strings.HasSuffix(args, ".mp4"):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{OutputDir: out, OutputPrefix: "Song", SetlistFile: stdinPath, EmbedTitleMetadata: true, OutputFlat: true,
+		SilenceThreshold: "-20dB", MinSilenceDur: 5, MinSongLength: 60, UploadToDrive: true, RcloneRemote: "gdrive:"}
+
+	if code := runBatch(cfg, inputs, ""); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d", exitOK, code)
+	}
+	if want := []string{"title=Reba", "title=Tom", "title=Reba", "title=Tom"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("Expected both inputs to get the stdin setlist's titles %q, got %q", want, titles)
+	}
+	if uploads != 1 {
+		t.Errorf("Expected the shared folder uploaded once, got %d uploads", uploads)
+	}
+}
+
+func TestRunBatchChecksFlatFolderOnce(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []string{filepath.Join(dir, "tue.mp4"), filepath.Join(dir, "wed.mp4")}
+	for _, input := range inputs {
+		if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 140\nsilence_end: 150\n"}
+		case strings.Contains(args, "-t "):
+			// Leave a song behind, as a real export would.
+			os.WriteFile(call.args[len(call.args)-1], []byte("song"), 0644)
+		case strings.HasSuffix(args, ".mp4"):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{OutputDir: filepath.Join(dir, "out"), OutputPrefix: "Song", OutputFlat: true,
+		SilenceThreshold: "-20dB", MinSilenceDur: 5, MinSongLength: 60}
+	cfg.FailIfNotEmpty = true
+
+	if code := runBatch(cfg, inputs, ""); code != exitOK {
+		t.Errorf("Expected the second input not to trip over the first one's songs, got exit code %d", code)
+	}
+	if code := runBatch(cfg, inputs, ""); code != exitAborted {
+		t.Errorf("Expected a second batch into the same folder to stop, got exit code %d", code)
+	}
+}
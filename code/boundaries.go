@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadBoundaries reads song boundaries from an editor's cut list instead of
+// detecting them. Two formats are accepted:
+//
+//   - an FFmetadata file (";FFMETADATA1" on the first line) with [CHAPTER]
+//     sections, as written by `ffmpeg -f ffmetadata` or output_mode
+//     chapters; START and END are in TIMEBASE units (1/1000 by default);
+//   - a plain cut list, one song per line: "START END [title]", with times
+//     as seconds, M:SS or H:MM:SS. Blank lines and lines starting with '#'
+//     are skipped.
+//
+// Songs must be in order and can't overlap.
+func loadBoundaries(path string) ([]segment, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	head, _ := r.Peek(len(";FFMETADATA1"))
+	var segments []segment
+	var titles []string
+	if string(head) == ";FFMETADATA1" {
+		segments, titles, err = parseFFMetadataChapters(r)
+	} else {
+		segments, titles, err = parseCutList(r)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(segments) == 0 {
+		return nil, nil, fmt.Errorf("no songs found in '%s'", path)
+	}
+	return segments, titles, nil
+}
+
+// parseCutList parses the "START END [title]" format.
+func parseCutList(r io.Reader) ([]segment, []string, error) {
+	var segments []segment
+	var titles []string
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, nil, fmt.Errorf("line %d: expected START END [title], got '%s'", lineNum, line)
+		}
+		start, err := parseTimestamp(fields[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		end, err := parseTimestamp(fields[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		if err := checkBoundary(segments, segment{start: start, end: end}); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		segments = append(segments, segment{start: start, end: end})
+		titles = append(titles, strings.Join(fields[2:], " "))
+	}
+	return segments, titles, scanner.Err()
+}
+
+// parseFFMetadataChapters parses the [CHAPTER] sections of an FFmetadata
+// file, ignoring global and stream metadata.
+func parseFFMetadataChapters(r io.Reader) ([]segment, []string, error) {
+	type chapter struct {
+		num, den   int64
+		start, end int64
+		title      string
+		line       int
+	}
+	var chapters []chapter
+	inChapter := false
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inChapter = line == "[CHAPTER]"
+			if inChapter {
+				chapters = append(chapters, chapter{num: 1, den: 1000, start: -1, end: -1, line: lineNum})
+			}
+			continue
+		}
+		if !inChapter {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: expected KEY=value, got '%s'", lineNum, line)
+		}
+		c := &chapters[len(chapters)-1]
+		var err error
+		switch key {
+		case "TIMEBASE":
+			num, den, _ := strings.Cut(value, "/")
+			c.num, err = strconv.ParseInt(num, 10, 64)
+			if err == nil {
+				c.den, err = strconv.ParseInt(den, 10, 64)
+			}
+			if err == nil && (c.num <= 0 || c.den <= 0) {
+				err = fmt.Errorf("invalid TIMEBASE '%s'", value)
+			}
+		case "START":
+			c.start, err = strconv.ParseInt(value, 10, 64)
+		case "END":
+			c.end, err = strconv.ParseInt(value, 10, 64)
+		case "title":
+			c.title = unescapeMetadata(value)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var segments []segment
+	var titles []string
+	for _, c := range chapters {
+		if c.start < 0 || c.end < 0 {
+			return nil, nil, fmt.Errorf("line %d: chapter needs both START and END", c.line)
+		}
+		scale := float64(c.num) / float64(c.den)
+		seg := segment{start: float64(c.start) * scale, end: float64(c.end) * scale}
+		if err := checkBoundary(segments, seg); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", c.line, err)
+		}
+		segments = append(segments, seg)
+		titles = append(titles, c.title)
+	}
+	return segments, titles, nil
+}
+
+// checkBoundary rejects a song that is empty or starts before the previous
+// one ends.
+func checkBoundary(previous []segment, seg segment) error {
+	if seg.end <= seg.start {
+		return fmt.Errorf("end %.2fs is not after start %.2fs", seg.end, seg.start)
+	}
+	if n := len(previous); n > 0 && seg.start < previous[n-1].end {
+		return fmt.Errorf("song starting at %.2fs overlaps the one before, which ends at %.2fs", seg.start, previous[n-1].end)
+	}
+	return nil
+}
+
+// clampBoundaries checks loaded boundaries against the recording: a song
+// starting past the end is an error (likely a cut list for another file),
+// while an end slightly past it is trimmed to fit.
+func clampBoundaries(segments []segment, totalDuration float64) ([]segment, error) {
+	clamped := append([]segment(nil), segments...)
+	for i := range clamped {
+		if clamped[i].start >= totalDuration {
+			return nil, fmt.Errorf("song %d starts at %.2fs, after the recording ends (%.2fs)", i+1, clamped[i].start, totalDuration)
+		}
+		clamped[i].end = min(clamped[i].end, totalDuration)
+	}
+	return clamped, nil
+}
+
+// unescapeMetadata undoes escapeMetadata.
+func unescapeMetadata(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
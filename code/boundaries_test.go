@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadBoundariesCutList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cuts.txt")
+	content := "# exported from the editor\n12.5 4:10 Opener\n\n4:20 8:00.125\n0:08:10 0:12:00 Slow  One\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	segments, titles, err := loadBoundaries(path)
+	if err != nil {
+		t.Fatalf("loadBoundaries failed: %v", err)
+	}
+	wantSegments := []segment{{start: 12.5, end: 250}, {start: 260, end: 480.125}, {start: 490, end: 720}}
+	if !reflect.DeepEqual(segments, wantSegments) {
+		t.Errorf("Expected segments %v, got %v", wantSegments, segments)
+	}
+	if wantTitles := []string{"Opener", "", "Slow One"}; !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("Expected titles %q, got %q", wantTitles, titles)
+	}
+}
+
+func TestLoadBoundariesFFMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chapters.txt")
+	content := buildChapterMetadata([]segment{{start: 12.5, end: 250}, {start: 260, end: 480.125}}, []string{"Tom; Jerry = #1"}) +
+		"[CHAPTER]\nTIMEBASE=1/90000\nSTART=45000000\nEND=54000000\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	segments, titles, err := loadBoundaries(path)
+	if err != nil {
+		t.Fatalf("loadBoundaries failed: %v", err)
+	}
+	wantSegments := []segment{{start: 12.5, end: 250}, {start: 260, end: 480.125}, {start: 500, end: 600}}
+	if !reflect.DeepEqual(segments, wantSegments) {
+		t.Errorf("Expected segments %v, got %v", wantSegments, segments)
+	}
+	if wantTitles := []string{"Tom; Jerry = #1", "Chapter 2", ""}; !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("Expected titles %q, got %q", wantTitles, titles)
+	}
+}
+
+func TestLoadBoundariesValidation(t *testing.T) {
+	testCases := map[string]string{
+		"bad time":      "12.5 4:xx Opener\n",
+		"missing end":   "12.5\n",
+		"end <= start":  "250 12.5 Backwards\n",
+		"overlap":       "0 250 One\n240 400 Two\n",
+		"empty":         "# nothing here\n",
+		"chapter start": ";FFMETADATA1\n[CHAPTER]\nEND=1000\n",
+	}
+	for name, content := range testCases {
+		path := filepath.Join(t.TempDir(), "cuts.txt")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := loadBoundaries(path); err == nil {
+			t.Errorf("%s: expected an error for %q", name, content)
+		}
+	}
+
+	if _, err := clampBoundaries([]segment{{start: 0, end: 100}, {start: 400, end: 500}}, 300); err == nil || !strings.Contains(err.Error(), "song 2") {
+		t.Errorf("Expected song 2 to be rejected for starting past the end, got %v", err)
+	}
+	if got, _ := clampBoundaries([]segment{{start: 0, end: 100}, {start: 200, end: 301}}, 300); got[1].end != 300 {
+		t.Errorf("Expected the last song to be trimmed to 300s, got %v", got)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// stateFileName is the file in OutputDir where -cache remembers what earlier
+// runs exported and uploaded.
+const stateFileName = ".splitter-state.json"
+
+// exportState maps each export's original output path (e.g.
+// output/Song_01.mp4) to what was made from it. Entries are keyed by that
+// path rather than the final name, so setlist renames don't defeat it.
+type exportState struct {
+	Entries map[string]stateEntry `json:"entries"`
+}
+
+type stateEntry struct {
+	ParamHash   string   `json:"param_hash"`   // source file and ffmpeg args
+	File        string   `json:"file"`         // where the output ended up
+	ContentHash string   `json:"content_hash"` // SHA-256 of File
+	Uploaded    []string `json:"uploaded,omitempty"`
+}
+
+// newExportState returns an empty state, as used by -force.
+func newExportState() *exportState {
+	return &exportState{Entries: map[string]stateEntry{}}
+}
+
+// loadState reads the state file from dir. A missing or unreadable file
+// gives an empty state, so everything is exported.
+func loadState(dir string) *exportState {
+	state := newExportState()
+	data, err := os.ReadFile(filepath.Join(dir, stateFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Could not read export state: %v. Exporting everything.", err)
+		}
+		return state
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		log.Printf("Warning: Could not parse export state: %v. Exporting everything.", err)
+		return newExportState()
+	}
+	if state.Entries == nil {
+		state.Entries = map[string]stateEntry{}
+	}
+	return state
+}
+
+// save writes the state file into dir.
+func (s *exportState) save(dir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, stateFileName), append(data, '\n'), 0644)
+}
+
+// unchanged returns where a previous run put the output for key, if it was
+// made with the same parameters and the file there is still intact.
+func (s *exportState) unchanged(key, paramHash string) (string, bool) {
+	entry, ok := s.Entries[key]
+	if !ok || entry.ParamHash != paramHash {
+		return "", false
+	}
+	hash, err := fileHash(entry.File)
+	if err != nil || hash != entry.ContentHash {
+		return "", false
+	}
+	return entry.File, true
+}
+
+// record remembers the results of this run. Outputs that were reused keep
+// their upload history; new ones start without one.
+func (s *exportState) record(results []segmentResult) {
+	for _, r := range results {
+		if r.Status != statusExported || r.stateKey == "" {
+			continue
+		}
+		entry := s.Entries[r.stateKey]
+		if !r.Cached || entry.File != r.File {
+			hash, err := fileHash(r.File)
+			if err != nil {
+				log.Printf("Warning: Could not hash '%s': %v", r.File, err)
+				delete(s.Entries, r.stateKey)
+				continue
+			}
+			entry = stateEntry{ContentHash: hash}
+		}
+		entry.ParamHash = r.paramHash
+		entry.File = r.File
+		s.Entries[r.stateKey] = entry
+	}
+}
+
+// pendingDestinations returns the destinations that still need an upload:
+// all of them unless every result was reused and already uploaded there.
+func (s *exportState) pendingDestinations(results []segmentResult, dests []UploadDestination) []UploadDestination {
+	var pending []UploadDestination
+	for _, dest := range dests {
+		if !s.allUploaded(results, dest.path()) {
+			pending = append(pending, dest)
+		}
+	}
+	return pending
+}
+
+func (s *exportState) allUploaded(results []segmentResult, dest string) bool {
+	for _, r := range results {
+		if !r.Cached || !slices.Contains(s.Entries[r.stateKey].Uploaded, dest) {
+			return false
+		}
+	}
+	return true
+}
+
+// markUploaded records a successful upload of this run's results to dest.
+func (s *exportState) markUploaded(results []segmentResult, dest string) {
+	for _, r := range results {
+		entry, ok := s.Entries[r.stateKey]
+		if ok && !slices.Contains(entry.Uploaded, dest) {
+			entry.Uploaded = append(entry.Uploaded, dest)
+			s.Entries[r.stateKey] = entry
+		}
+	}
+}
+
+// paramHash fingerprints an export: the source file's path, size and
+// modification time plus the exact ffmpeg arguments.
+func paramHash(inputFile string, args []string) string {
+	h := sha256.New()
+	if info, err := os.Stat(inputFile); err == nil {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", inputFile, info.Size(), info.ModTime().UnixNano())
+	}
+	io.WriteString(h, strings.Join(args, "\x00"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fileHash returns the SHA-256 of a file's contents.
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
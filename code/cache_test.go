@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportStateSkipsUnchangedSegments(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "practice.mp4")
+	if err := os.WriteFile(input, []byte("source"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outDir := filepath.Join(dir, "out")
+	fake := installFakeExec(t, nil)
+	cfg := Config{InputFile: input, OutputDir: outDir, OutputPrefix: "Song"}
+	segs := []segment{{start: 0, end: 10}}
+	output := outDir + "/Song_01.mp4"
+
+	// First run exports; the fake ffmpeg doesn't write files, so do it here.
+	results := splitVideoIntoSegments(cfg, segs, exportOptions{state: newExportState()})
+	if err := os.WriteFile(output, []byte("song"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := loadState(outDir)
+	state.record(results)
+	if err := state.save(outDir); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.calls) != 1 {
+		t.Fatalf("Expected the first run to export, got %d calls", len(fake.calls))
+	}
+
+	// Same source and settings: reused.
+	results = splitVideoIntoSegments(cfg, segs, exportOptions{state: loadState(outDir)})
+	if len(fake.calls) != 1 || !results[0].Cached || results[0].File != output {
+		t.Errorf("Expected the unchanged segment to be reused, got %d calls and %+v", len(fake.calls), results[0])
+	}
+
+	// Different settings: exported again.
+	reencodeCfg := cfg
+	reencodeCfg.Reencode = true
+	splitVideoIntoSegments(reencodeCfg, segs, exportOptions{state: loadState(outDir)})
+	if len(fake.calls) != 2 {
+		t.Errorf("Expected changed settings to re-export, got %d calls", len(fake.calls))
+	}
+
+	// Output modified since: exported again.
+	if err := os.WriteFile(output, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	splitVideoIntoSegments(cfg, segs, exportOptions{state: loadState(outDir)})
+	if len(fake.calls) != 3 {
+		t.Errorf("Expected a modified output to re-export, got %d calls", len(fake.calls))
+	}
+}
+
+func TestExportStatePendingDestinations(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "01 - Opener.mp4")
+	if err := os.WriteFile(file, []byte("song"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := newExportState()
+	results := []segmentResult{{File: file, Status: statusExported, stateKey: "out/Song_01.mp4", paramHash: "p"}}
+	state.record(results)
+	state.markUploaded(results, "gdrive:Band")
+	dests := []UploadDestination{{Remote: "gdrive:", Subfolder: "Band"}, {Remote: "backup:", Subfolder: "Band"}}
+
+	// Freshly exported files always go up.
+	if got := state.pendingDestinations(results, dests); !reflect.DeepEqual(got, dests) {
+		t.Errorf("Expected new exports to be uploaded everywhere, got %v", got)
+	}
+
+	results[0].Cached = true
+	want := []UploadDestination{{Remote: "backup:", Subfolder: "Band"}}
+	if got := state.pendingDestinations(results, dests); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected only the destination without the upload, got %v", got)
+	}
+}
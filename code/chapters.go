@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildChapterMetadata renders segments as an ffmetadata file with one
+// chapter per song, in milliseconds. Songs without a title are named
+// "Chapter <n>".
+func buildChapterMetadata(segments []segment, titles []string) string {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for i, seg := range segments {
+		title := fmt.Sprintf("Chapter %d", i+1)
+		if i < len(titles) && titles[i] != "" {
+			title = titles[i]
+		}
+		fmt.Fprintf(&b, "[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			int64(seg.start*1000+0.5), int64(seg.end*1000+0.5), escapeMetadata(title))
+	}
+	return b.String()
+}
+
+// escapeMetadata backslash-escapes the characters ffmetadata treats as
+// syntax: '=', ';', '#', '\' and newlines.
+func escapeMetadata(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '=', ';', '#', '\\', '\n':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// chaptersOutputPath is where chapters mode writes the remuxed input,
+// e.g. output/practice_chapters.mp4.
+func chaptersOutputPath(cfg Config) string {
+	ext := filepath.Ext(cfg.InputFile)
+	name := strings.TrimSuffix(filepath.Base(cfg.InputFile), ext)
+	return filepath.Join(cfg.OutputDir, name+"_chapters"+ext)
+}
+
+// exportChapters remuxes the whole input with a chapter at each song, leaving
+// every stream untouched. Each song gets a result pointing at the one file.
+func exportChapters(cfg Config, segments []segment, titles []string) []segmentResult {
+	outputFilename := chaptersOutputPath(cfg)
+	results := make([]segmentResult, len(segments))
+	for i, seg := range segments {
+		results[i] = segmentResult{Index: i + 1, Start: seg.start, End: seg.end, File: outputFilename, Status: statusExported}
+	}
+	fail := func(err error) []segmentResult {
+		log.Printf("Error writing chapters to '%s': %v", outputFilename, err)
+		for i := range results {
+			results[i].Status = statusFailed
+			results[i].Error = err.Error()
+		}
+		return results
+	}
+
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		return fail(err)
+	}
+	metaFile, err := os.CreateTemp(cfg.OutputDir, ".chapters-*.txt")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.Remove(metaFile.Name())
+	_, err = metaFile.WriteString(buildChapterMetadata(segments, titles))
+	metaFile.Close()
+	if err != nil {
+		return fail(err)
+	}
+
+	log.Printf("Writing %d chapter(s) to %s", len(segments), outputFilename)
+	stderr, err := runFFmpeg(cfg.FFmpegLogLevel,
+		"-y", "-i", cfg.InputFile, "-i", metaFile.Name(),
+		"-map", "0", "-map_metadata", "0", "-map_chapters", "1", "-c", "copy",
+		outputFilename,
+	)
+	if err != nil {
+		return fail(fmt.Errorf("%v: %s", err, stderr))
+	}
+	return results
+}
+
+// sourceChapterSegments uses the input's own chapter markers as the songs,
+// returning nothing (so detection runs instead) if it has none or they can't
+// be read.
+func sourceChapterSegments(cfg Config) ([]segment, []string) {
+	if !isFFprobeInstalled() {
+		log.Println("Warning: 'ffprobe' not found, can't read the input's chapters. Falling back to silence detection.")
+		return nil, nil
+	}
+	segments, titles, err := probeChapters(cfg.InputFile)
+	if err != nil {
+		log.Printf("Warning: Could not read chapters from '%s': %v. Falling back to silence detection.", cfg.InputFile, err)
+		return nil, nil
+	}
+	if len(segments) == 0 {
+		log.Println("No chapters found in the input. Falling back to silence detection.")
+		return nil, nil
+	}
+	log.Printf("Using %d chapter(s) from the input as songs.", len(segments))
+	return segments, titles
+}
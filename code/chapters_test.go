@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildChapterMetadata(t *testing.T) {
+	segs := []segment{{start: 12.5, end: 250}, {start: 260.0004, end: 480.1236}}
+
+	got := buildChapterMetadata(segs, []string{"Tom; Jerry = #1"})
+
+	want := ";FFMETADATA1\n" +
+		"[CHAPTER]\nTIMEBASE=1/1000\nSTART=12500\nEND=250000\ntitle=Tom\\; Jerry \\= \\#1\n" +
+		"[CHAPTER]\nTIMEBASE=1/1000\nSTART=260000\nEND=480124\ntitle=Chapter 2\n"
+	if got != want {
+		t.Errorf("Expected metadata:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func TestExportChapters(t *testing.T) {
+	outDir := t.TempDir()
+	fake := installFakeExec(t, nil)
+	cfg := Config{InputFile: "/recordings/practice.mp4", OutputDir: outDir}
+
+	results := exportChapters(cfg, []segment{{start: 0, end: 100}, {start: 120, end: 300}}, nil)
+
+	output := outDir + "/practice_chapters.mp4"
+	if len(fake.calls) != 1 {
+		t.Fatalf("Expected one ffmpeg call, got %+v", fake.calls)
+	}
+	args := fake.calls[0].args
+	if args[len(args)-1] != output || !reflect.DeepEqual(args[len(args)-9:len(args)-1], []string{"-map", "0", "-map_metadata", "0", "-map_chapters", "1", "-c", "copy"}) {
+		t.Errorf("Unexpected ffmpeg args %v", args)
+	}
+	for _, r := range results {
+		if r.File != output || r.Status != statusExported {
+			t.Errorf("Expected every song to point at %s, got %+v", output, r)
+		}
+	}
+}
+
+func TestSourceChapterSegmentsFallsBack(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		if call.args[0] == "-version" {
+			return fakeResult{}
+		}
+		return fakeResult{stdout: `{"chapters": []}`}
+	})
+
+	if segments, titles := sourceChapterSegments(Config{InputFile: "practice.mp4"}); segments != nil || titles != nil {
+		t.Errorf("Expected no songs from a file without chapters, got %v %q", segments, titles)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumsFileName is the file in OutputDir that checksums writes, in the
+// format `sha256sum -c` reads.
+const checksumsFileName = "SHA256SUMS"
+
+// writeChecksums writes dir/SHA256SUMS with one "<hash>  <name>" line per
+// file, names relative to dir, so `cd dir && sha256sum -c SHA256SUMS`
+// verifies them.
+func writeChecksums(dir string, files []string) error {
+	var b strings.Builder
+	for _, file := range files {
+		hash, err := fileHash(file)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s  %s\n", hash, filepath.ToSlash(name))
+	}
+	return os.WriteFile(filepath.Join(dir, checksumsFileName), []byte(b.String()), 0644)
+}
+
+// checksumFiles returns the files the run exported: songs, chatter and the
+// joined gaps.
+func checksumFiles(rep *runReport) []string {
+	var files []string
+	for _, r := range append(append([]segmentResult(nil), rep.Segments...), rep.Chatter...) {
+		if r.Status == statusExported {
+			files = append(files, r.File)
+		}
+	}
+	if rep.GapsFile != "" {
+		files = append(files, rep.GapsFile)
+	}
+	if rep.FullSetFile != "" {
+		files = append(files, rep.FullSetFile)
+	}
+	return files
+}
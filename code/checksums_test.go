@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteChecksums(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, f := range []struct{ name, data string }{
+		{"01 - Reba.mp4", "reba"},
+		{"chatter/Chatter_01.mp4", "hello\n"},
+		{"empty.mp4", ""},
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	if err := writeChecksums(dir, files); err != nil {
+		t.Fatalf("writeChecksums failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, checksumsFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "6f5d45892868b7d4d0545a659ada045f531f646dc1fe7a8cdbd6a9a690f3508e  01 - Reba.mp4\n" +
+		"5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  chatter/Chatter_01.mp4\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  empty.mp4\n"
+	if string(data) != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestWriteChecksumsMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeChecksums(dir, []string{filepath.Join(dir, "gone.mp4")}); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, checksumsFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected no %s after a failure, got %v", checksumsFileName, err)
+	}
+}
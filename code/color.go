@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// -color values.
+const (
+	colorAuto   = "auto"   // color when the log goes to a terminal and NO_COLOR isn't set
+	colorAlways = "always" // color even into a pipe or file
+	colorNever  = "never"
+)
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiGreen  = "\x1b[32m"
+	ansiReset  = "\x1b[0m"
+)
+
+// colorLogWriter returns the writer for the standard logger: w itself, or w
+// coloring each entry when mode wants color. The log goes to stderr, so
+// auto checks whether that is a terminal, and turns color off for the
+// NO_COLOR convention (https://no-color.org); always ignores NO_COLOR.
+func colorLogWriter(w io.Writer, mode string, terminal bool) io.Writer {
+	switch {
+	case mode == colorAlways:
+	case mode == colorNever, !terminal, os.Getenv("NO_COLOR") != "":
+		return w
+	}
+	return colorWriter{w}
+}
+
+// colorWriter colors whole log entries, which the standard logger writes
+// one per Write call.
+type colorWriter struct {
+	w io.Writer
+}
+
+func (c colorWriter) Write(p []byte) (int, error) {
+	color := entryColor(string(p))
+	if color == "" {
+		return c.w.Write(p)
+	}
+	entry := strings.TrimSuffix(string(p), "\n")
+	if _, err := io.WriteString(c.w, color+entry+ansiReset+"\n"); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// logTimestamp is the date and time log.LstdFlags puts before each entry.
+var logTimestamp = regexp.MustCompile(`^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d `)
+
+// entryColor picks an entry's color from how the tool words its messages:
+// errors and warnings start with "Error" or "Warning", and finished steps
+// end in "complete".
+func entryColor(entry string) string {
+	msg := strings.TrimSpace(logTimestamp.ReplaceAllString(entry, ""))
+	switch {
+	case strings.HasPrefix(msg, "Error"):
+		return ansiRed
+	case strings.HasPrefix(msg, "Warning"):
+		return ansiYellow
+	case msg == "All done!", strings.HasSuffix(msg, "complete."), strings.HasSuffix(strings.ToLower(msg), "complete ---"):
+		return ansiGreen
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"testing"
+)
+
+// logThrough logs a warning, an error, a success and a plain line through
+// colorLogWriter and returns what came out.
+func logThrough(mode string, terminal bool) string {
+	var out strings.Builder
+	logger := log.New(colorLogWriter(&out, mode, terminal), "", log.LstdFlags)
+	logger.Println("Warning: Silence threshold is very low.")
+	logger.Printf("Error: %v", "ffmpeg failed")
+	logger.Println("--- Google Drive Upload Complete ---")
+	logger.Println("Exporting segment 1")
+	return out.String()
+}
+
+func TestColorOff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mode     string
+		terminal bool
+		noColor  string
+	}{
+		{"Never", colorNever, true, ""},
+		{"AutoNotTerminal", colorAuto, false, ""},
+		{"AutoNoColor", colorAuto, true, "1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			if out := logThrough(tc.mode, tc.terminal); strings.Contains(out, "\x1b[") {
+				t.Errorf("Expected no escape codes, got %q", out)
+			}
+		})
+	}
+}
+
+func TestColorOn(t *testing.T) {
+	t.Setenv("NO_COLOR", "1") // always ignores it
+	out := logThrough(colorAlways, false)
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 lines, got %q", out)
+	}
+	for i, color := range []string{ansiYellow, ansiRed, ansiGreen} {
+		if !strings.HasPrefix(lines[i], color) || !strings.HasSuffix(lines[i], ansiReset) {
+			t.Errorf("Expected line %d colored %q, got %q", i+1, color, lines[i])
+		}
+	}
+	if strings.Contains(lines[3], "\x1b[") {
+		t.Errorf("Expected a plain line left alone, got %q", lines[3])
+	}
+
+	t.Setenv("NO_COLOR", "")
+	if out := logThrough(colorAuto, true); !strings.Contains(out, ansiRed) {
+		t.Errorf("Expected auto to color a terminal, got %q", out)
+	}
+}
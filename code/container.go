@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// containerCodecs are the encoders used when a song has to be re-encoded
+// for an output container.
+type containerCodecs struct {
+	video string
+	audio string
+	// anyCodec marks containers that hold whatever the input carries, so
+	// switching to them never forces a re-encode.
+	anyCodec bool
+	// audioOnly marks audio formats: songs are exported without video.
+	audioOnly bool
+}
+
+// outputContainers are the containers output_container accepts.
+var outputContainers = map[string]containerCodecs{
+	"mp4":  {video: "libx264", audio: "aac"},
+	"mov":  {video: "libx264", audio: "aac"},
+	"mkv":  {video: "libx264", audio: "aac", anyCodec: true},
+	"webm": {video: "libvpx-vp9", audio: "libopus"},
+	"mp3":  {audio: "libmp3lame", audioOnly: true},
+	"m4a":  {audio: "aac", audioOnly: true},
+	"flac": {audio: "flac", audioOnly: true},
+}
+
+// normalizeContainer turns ".MP4" or "mp4" into "mp4".
+func normalizeContainer(name string) string {
+	return strings.ToLower(strings.TrimPrefix(name, "."))
+}
+
+// outputExt returns the extension for exported songs: the input's unless
+// OutputContainer picks another one.
+func outputExt(cfg Config) string {
+	if cfg.OutputContainer == "" {
+		return filepath.Ext(cfg.InputFile)
+	}
+	return "." + normalizeContainer(cfg.OutputContainer)
+}
+
+// containerForcesReencode reports whether the input's streams can't be
+// copied into OutputContainer. Until checkCopyCompatibility has probed the
+// codecs we only trust a copy into the same container or into one that
+// takes anything (mkv); an mkv's Opus audio, say, won't go into an mp4 as-is.
+func containerForcesReencode(cfg Config) bool {
+	if cfg.OutputContainer == "" || cfg.streamsCopyable {
+		return false
+	}
+	out := normalizeContainer(cfg.OutputContainer)
+	if out == normalizeContainer(filepath.Ext(cfg.InputFile)) {
+		return false
+	}
+	return !outputContainers[out].anyCodec
+}
+
+// reencoding reports whether exports re-encode, either because Reencode is
+// set or because the output container needs it.
+func reencoding(cfg Config) bool {
+	return cfg.Reencode || containerForcesReencode(cfg)
+}
+
+// audioOnlyOutput reports whether OutputContainer is an audio format
+// (mp3, m4a, flac), which exports each song's audio without the video.
+func audioOnlyOutput(cfg Config) bool {
+	return outputContainers[normalizeContainer(cfg.OutputContainer)].audioOnly
+}
+
+// exportCodecs returns the video and audio encoders for a re-encode,
+// H.264/AAC unless the output container needs something else.
+func exportCodecs(cfg Config) (video, audio string) {
+	if c, ok := outputContainers[normalizeContainer(outputExt(cfg))]; ok {
+		return c.video, c.audio
+	}
+	return "libx264", "aac"
+}
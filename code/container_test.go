@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOutputContainerArgs(t *testing.T) {
+	seg := segment{start: 300, end: 360}
+	testCases := []struct {
+		name      string
+		cfg       Config
+		wantExt   string
+		wantCodec []string
+		wantSeek  []string
+	}{
+		{
+			name:      "MkvToMp4Reencodes",
+			cfg:       Config{InputFile: "in.mkv", OutputContainer: "mp4"},
+			wantExt:   ".mp4",
+			wantCodec: []string{"-c:v", "libx264", "-crf", "20", "-c:a", "aac"},
+			wantSeek:  []string{"-i", "in.mkv", "-ss", "300.000"},
+		},
+		{
+			name:      "Mp4ToMkvCopies",
+			cfg:       Config{InputFile: "in.mp4", OutputContainer: ".MKV"},
+			wantExt:   ".mkv",
+			wantCodec: []string{"-c:v", "copy", "-c:a", "copy"},
+			wantSeek:  []string{"-ss", "300.000", "-i", "in.mp4"},
+		},
+		{
+			name:      "mp4 to webm uses VP9 and Opus",
+			cfg:       Config{InputFile: "in.mp4", OutputContainer: "webm", AudioBitrate: "128k"},
+			wantExt:   ".webm",
+			wantCodec: []string{"-c:v", "libvpx-vp9", "-crf", "20", "-c:a", "libopus", "-b:a", "128k"},
+			wantSeek:  []string{"-i", "in.mp4", "-ss", "300.000"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := outputExt(tc.cfg); got != tc.wantExt {
+				t.Errorf("Expected extension %q, got %q", tc.wantExt, got)
+			}
+			args := buildExportArgs(tc.cfg, seg, "out"+tc.wantExt, nil)
+			if !strings.Contains(strings.Join(args, " "), strings.Join(tc.wantCodec, " ")) {
+				t.Errorf("Expected codec args %v in %v", tc.wantCodec, args)
+			}
+			if got := args[3:7]; !reflect.DeepEqual(got, tc.wantSeek) {
+				t.Errorf("Expected seek args %v, got %v", tc.wantSeek, got)
+			}
+		})
+	}
+}
+
+func TestOutputContainerValidation(t *testing.T) {
+	testCases := []struct {
+		args          []string
+		wantContainer string
+		wantReencode  bool
+	}{
+		{[]string{"-input=in.mkv", "-output-container=mp4"}, "mp4", true},
+		{[]string{"-input=in.mp4", "-output-container=.MKV"}, "mkv", false},
+		{[]string{"-input=in.mp4", "-output-container=avi"}, "", false},
+	}
+	for _, tc := range testCases {
+		resetFlags()
+		defineFlags()
+		if err := flag.CommandLine.Parse(append([]string{"-config=non-existent-file.json"}, tc.args...)); err != nil {
+			t.Fatalf("failed to parse flags: %v", err)
+		}
+		cfg, warnings, err := loadConfig()
+		if err != nil {
+			t.Fatalf("loadConfig failed: %v", err)
+		}
+		if cfg.OutputContainer != tc.wantContainer || reencoding(cfg) != tc.wantReencode {
+			t.Errorf("%v: expected container %q reencode=%v, got %q reencode=%v", tc.args, tc.wantContainer, tc.wantReencode, cfg.OutputContainer, reencoding(cfg))
+		}
+		if (tc.wantContainer == "") != (len(warnings) > 0) {
+			t.Errorf("%v: unexpected warnings %q", tc.args, warnings)
+		}
+	}
+}
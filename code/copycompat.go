@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+// copyCodecs are the audio and video codecs (ffprobe codec_name) each output
+// container can take with -c copy. mkv takes anything, and containers not
+// listed are never trusted with a copy from another container. Opus and
+// FLAC are left out of mp4, where ffmpeg's support is still experimental.
+var copyCodecs = map[string][]string{
+	"mp4":  {"h264", "hevc", "av1", "mpeg4", "aac", "mp3", "ac3", "eac3", "alac"},
+	"mov":  {"h264", "hevc", "mpeg4", "prores", "mjpeg", "aac", "mp3", "ac3", "alac", "pcm_s16le", "pcm_s24le"},
+	"webm": {"vp8", "vp9", "av1", "opus", "vorbis"},
+	"mp3":  {"mp3"},
+	"m4a":  {"aac", "alac"},
+	"flac": {"flac"},
+}
+
+// copyCompatible reports whether streams with inCodecs can be stream-copied
+// into a file with extension outExt.
+func copyCompatible(inCodecs []string, outExt string) bool {
+	out := normalizeContainer(outExt)
+	if outputContainers[out].anyCodec {
+		return true
+	}
+	allowed, ok := copyCodecs[out]
+	if !ok {
+		return false
+	}
+	for _, codec := range inCodecs {
+		if !slices.Contains(allowed, codec) {
+			return false
+		}
+	}
+	return true
+}
+
+// copiedCodecs lists the codecs of the input streams an export copies: the
+// audio, and the video unless the output is audio-only.
+func copiedCodecs(cfg Config, info probeResult) []string {
+	var codecs []string
+	for _, s := range info.Streams {
+		keep := s.CodecType == "audio" || s.CodecType == "video" && !audioOnlyOutput(cfg)
+		if keep && !slices.Contains(codecs, s.CodecName) {
+			codecs = append(codecs, s.CodecName)
+		}
+	}
+	return codecs
+}
+
+// checkCopyCompatibility decides, from the input's probe output, whether a
+// change of container really needs a re-encode. Without a probe,
+// containerForcesReencode assumes it does; when the streams fit the new
+// container as they are, they're copied instead.
+func checkCopyCompatibility(cfg Config, probeJSON string) Config {
+	if cfg.Reencode || !containerForcesReencode(cfg) {
+		return cfg
+	}
+	info, err := parseProbeJSON(probeJSON)
+	if err != nil {
+		return cfg
+	}
+	codecs := copiedCodecs(cfg, info)
+	out := normalizeContainer(outputExt(cfg))
+	if copyCompatible(codecs, out) {
+		log.Printf("The input's streams (%s) fit a %s container as they are; copying them.", strings.Join(codecs, ", "), out)
+		cfg.streamsCopyable = true
+		return cfg
+	}
+	log.Printf("Warning: A %s container can't take the input's streams (%s) with stream copy; re-encoding them. "+
+		"Use output_container mkv to keep them as they are.", out, strings.Join(codecs, ", "))
+	return cfg
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestCopyCompatible(t *testing.T) {
+	testCases := []struct {
+		codecs []string
+		outExt string
+		want   bool
+	}{
+		{[]string{"h264", "aac"}, ".mp4", true},
+		{[]string{"h264", "opus"}, ".mp4", false},
+		{[]string{"hevc", "pcm_s16le"}, ".mov", true},
+		{[]string{"hevc", "pcm_s16le"}, ".mp4", false},
+		{[]string{"vp9", "opus"}, ".webm", true},
+		{[]string{"h264", "aac"}, ".webm", false},
+		{[]string{"vp9", "opus", "flac"}, ".MKV", true},
+		{[]string{"aac"}, "m4a", true},
+		{[]string{"mp3"}, ".flac", false},
+		{[]string{"h264"}, ".avi", false},
+		{nil, ".mp4", true},
+	}
+	for _, tc := range testCases {
+		if got := copyCompatible(tc.codecs, tc.outExt); got != tc.want {
+			t.Errorf("copyCompatible(%q, %q): expected %v, got %v", tc.codecs, tc.outExt, tc.want, got)
+		}
+	}
+}
+
+func TestCheckCopyCompatibility(t *testing.T) {
+	const h264AAC = `{"streams": [{"codec_type": "video", "codec_name": "h264"}, {"codec_type": "audio", "codec_name": "aac"}]}`
+	const h264Opus = `{"streams": [{"codec_type": "video", "codec_name": "h264"}, {"codec_type": "audio", "codec_name": "opus"}]}`
+	testCases := []struct {
+		name      string
+		cfg       Config
+		probe     string
+		wantCopy  bool
+		wantCodec string
+	}{
+		{"FitsNewContainer", Config{InputFile: "in.mkv", OutputContainer: "mp4"}, h264AAC, true, "copy"},
+		{"OpusIntoMP4", Config{InputFile: "in.mkv", OutputContainer: "mp4"}, h264Opus, false, "libx264"},
+		{"AudioOnlyIgnoresVideo", Config{InputFile: "in.mp4", OutputContainer: "m4a"}, h264AAC, true, "-vn"},
+		{"ExplicitReencode", Config{InputFile: "in.mkv", OutputContainer: "mp4", Reencode: true}, h264AAC, false, "libx264"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := checkCopyCompatibility(tc.cfg, tc.probe)
+
+			if reencoding(cfg) == tc.wantCopy {
+				t.Errorf("Expected copy=%v, got re-encode=%v", tc.wantCopy, reencoding(cfg))
+			}
+			if args := codecArgs(cfg); args[1] != tc.wantCodec && args[0] != tc.wantCodec {
+				t.Errorf("Expected %s in the codec args, got %q", tc.wantCodec, args)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// coverArtContainers are the audio formats that can carry an attached
+// cover picture.
+var coverArtContainers = map[string]bool{"mp3": true, "m4a": true, "flac": true}
+
+// coverArtApplies reports whether CoverArt is embedded: only in audio-only
+// mode, into a format that holds a cover.
+func coverArtApplies(cfg Config) bool {
+	return cfg.CoverArt != "" && audioOnlyOutput(cfg) && coverArtContainers[normalizeContainer(cfg.OutputContainer)]
+}
+
+// coverArtInputArgs adds the cover image as ffmpeg's second input.
+func coverArtInputArgs(cfg Config) []string {
+	if !coverArtApplies(cfg) {
+		return nil
+	}
+	return []string{"-i", cfg.CoverArt}
+}
+
+// coverArtMapArgs maps the song's audio (every track with MapAllAudio) and
+// the cover image.
+func coverArtMapArgs(cfg Config) []string {
+	audio := "0:a:0"
+	if cfg.MapAllAudio {
+		audio = "0:a"
+	}
+	return []string{"-map", audio, "-map", "1"}
+}
+
+// coverArtDispositionArgs marks the copied image as the cover rather than a
+// video track. mp3 players read covers most reliably from ID3v2.3 tags.
+func coverArtDispositionArgs(cfg Config) []string {
+	args := []string{"-disposition:v:0", "attached_pic"}
+	if normalizeContainer(cfg.OutputContainer) == "mp3" {
+		args = append(args, "-id3v2_version", "3")
+	}
+	return args
+}
+
+// coverArtSignatures are the leading bytes of the image formats players
+// show as covers: PNG and JPEG.
+var coverArtSignatures = []string{"\x89PNG", "\xff\xd8\xff"}
+
+// checkCoverArt makes sure a configured cover image can be read and is a PNG
+// or JPEG, so a typo fails before the export rather than in every song's
+// ffmpeg run. The format is checked from the file's contents, since a
+// renamed .webp or .heic would mux but not show.
+func checkCoverArt(path string) error {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cover_art '%s' not found", path)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("cover_art '%s' is a folder, not an image", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cover_art '%s' could not be read: %v", path, err)
+	}
+	defer f.Close()
+	head := make([]byte, 4)
+	n, _ := io.ReadFull(f, head)
+	for _, sig := range coverArtSignatures {
+		if strings.HasPrefix(string(head[:n]), sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("cover_art '%s' is not a PNG or JPEG image", path)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCoverArtArgs(t *testing.T) {
+	seg := segment{start: 300, end: 360}
+	testCases := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"Mp3WithCover", Config{InputFile: "in.mp4", OutputContainer: "mp3", CoverArt: "cover.jpg"},
+			"-ss 300.000 -i in.mp4 -i cover.jpg -t 60.000 -map 0:a:0 -map 1 -c:v copy -disposition:v:0 attached_pic -id3v2_version 3 -c:a libmp3lame out.mp3"},
+		{"FlacWithCoverAllAudio", Config{InputFile: "in.mp4", OutputContainer: "flac", CoverArt: "cover.png", MapAllAudio: true},
+			"-ss 300.000 -i in.mp4 -i cover.png -t 60.000 -map 0:a -map 1 -c:v copy -disposition:v:0 attached_pic -c:a flac out.flac"},
+		{"AccurateSeekWithCover", Config{InputFile: "in.mp4", OutputContainer: "m4a", CoverArt: "cover.jpg", SeekMode: seekAccurate},
+			"-ss 300.000 -i in.mp4 -i cover.jpg -t 60.000 -map 0:a:0 -map 1 -c:v copy -disposition:v:0 attached_pic -c:a aac out.m4a"},
+		{"Mp3WithoutCover", Config{InputFile: "in.mp4", OutputContainer: "mp3"},
+			"-i in.mp4 -ss 300.000 -t 60.000 -vn -c:a libmp3lame out.mp3"},
+		{"CoverIgnoredForVideo", Config{InputFile: "in.mp4", CoverArt: "cover.jpg"},
+			"-ss 300.000 -i in.mp4 -t 60.000 -c:v copy -c:a copy out.mp4"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := "out" + outputExt(tc.cfg)
+			args := strings.Join(buildExportArgs(tc.cfg, seg, out, nil), " ")
+			if !strings.HasSuffix(args, tc.want) {
+				t.Errorf("Expected args ending in\n%s\ngot\n%s", tc.want, args)
+			}
+		})
+	}
+}
+
+func TestConfigLoadingChecksCoverArt(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "logo.png")
+	if err := os.WriteFile(cover, []byte("\x89PNG"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notImage := filepath.Join(dir, "logo.jpg")
+	if err := os.WriteFile(notImage, []byte("RIFF....WEBPVP8 "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		args        []string
+		wantErr     bool
+		wantWarning bool
+	}{
+		{[]string{"-cover-art=" + cover, "-output-container=mp3"}, false, false},
+		{[]string{"-cover-art=" + filepath.Join(dir, "missing.png"), "-output-container=mp3"}, true, false},
+		{[]string{"-cover-art=" + dir, "-output-container=mp3"}, true, false},
+		{[]string{"-cover-art=" + notImage, "-output-container=mp3"}, true, false},
+		{[]string{"-cover-art=" + cover}, false, true},
+	}
+	for _, tc := range testCases {
+		resetFlags()
+		defineFlags()
+		args := append([]string{"-config=" + filepath.Join(dir, "none.json"), "-input=in.mp4"}, tc.args...)
+		if err := flag.CommandLine.Parse(args); err != nil {
+			t.Fatalf("failed to parse flags: %v", err)
+		}
+		_, warnings, err := loadConfig()
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%v: expected error %v, got %v", tc.args, tc.wantErr, err)
+		}
+		warned := strings.Contains(strings.Join(warnings, "\n"), "cover_art is only embedded")
+		if warned != tc.wantWarning {
+			t.Errorf("%v: expected warning %v, got %q", tc.args, tc.wantWarning, warnings)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// detectionCacheVersion is bumped whenever detection changes enough that
+// cached silences from an older build would be wrong.
+const detectionCacheVersion = 1
+
+// cachedSilence is one silence in a detection cache file.
+type cachedSilence struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// tempDir returns TempDir, or the system temp dir when it isn't set.
+func tempDir(cfg Config) string {
+	if cfg.TempDir != "" {
+		return cfg.TempDir
+	}
+	return os.TempDir()
+}
+
+// cacheKey identifies one detection pass: the input (by path, size and
+// modification time, so an edited or replaced file is detected again) and
+// every setting that changes what detection finds.
+func cacheKey(cfg Config, stat os.FileInfo) string {
+	path, err := filepath.Abs(cfg.InputFile)
+	if err != nil {
+		path = cfg.InputFile
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\n%s\n%d\n%d\n", detectionCacheVersion, path, stat.Size(), stat.ModTime().UnixNano())
+	fmt.Fprintf(h, "%s\n%s\n%g\n%t\n", cfg.DetectionMode, cfg.SilenceThreshold, cfg.MinSilenceDur, cfg.MonoDetection)
+	if cfg.AnalysisWindows != "" { // only when set, so older keys stay valid
+		fmt.Fprintf(h, "%s\n", cfg.AnalysisWindows)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// detectionCachePath returns the cache file for a key.
+func detectionCachePath(cfg Config, key string) string {
+	return filepath.Join(tempDir(cfg), "rehearsal-splitter", "detect-"+key+".json")
+}
+
+// loadCachedSilences returns the silences saved for key, if there are any.
+func loadCachedSilences(cfg Config, key string) ([]segment, bool) {
+	data, err := os.ReadFile(detectionCachePath(cfg, key))
+	if err != nil {
+		return nil, false
+	}
+	var cached []cachedSilence
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	silences := make([]segment, 0, len(cached))
+	for _, c := range cached {
+		silences = append(silences, segment{start: c.Start, end: c.End})
+	}
+	return silences, true
+}
+
+// storeCachedSilences saves silences under key, creating the cache folder.
+func storeCachedSilences(cfg Config, key string, silences []segment) error {
+	cached := make([]cachedSilence, 0, len(silences))
+	for _, s := range silences {
+		cached = append(cached, cachedSilence{Start: s.start, End: s.end})
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	path := detectionCachePath(cfg, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// cachedDetectSilence runs detect unless an earlier run already did with the
+// same input and settings. Passes that found no silence aren't cached, since
+// that is also what a failed ffmpeg run looks like, and -detect-only only
+// reads the cache, never writes it.
+func cachedDetectSilence(cfg Config, detect func(Config) []segment) []segment {
+	stat, err := os.Stat(cfg.InputFile)
+	if cfg.NoDetectCache || err != nil || !stat.Mode().IsRegular() {
+		return detect(cfg)
+	}
+	key := cacheKey(cfg, stat)
+	if silences, ok := loadCachedSilences(cfg, key); ok {
+		log.Printf("Using %d silence(s) cached from an earlier run (-no-cache to detect again).", len(silences))
+		return silences
+	}
+	silences := detect(cfg)
+	if len(silences) > 0 && !cfg.DetectOnly {
+		if err := storeCachedSilences(cfg, key, silences); err != nil {
+			log.Printf("Warning: Could not cache detection results: %v", err)
+		}
+	}
+	return silences
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "practice.mp4")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{InputFile: input, SilenceThreshold: "-30dB", MinSilenceDur: 5, DetectionMode: detectionPeak}
+	key := cacheKey(cfg, stat)
+
+	if again := cacheKey(cfg, stat); again != key {
+		t.Errorf("Expected a stable key, got %s then %s", key, again)
+	}
+	cfg.OutputDir, cfg.Reencode = "elsewhere", true
+	if got := cacheKey(cfg, stat); got != key {
+		t.Error("Expected export settings not to change the key")
+	}
+
+	changes := map[string]func(*Config){
+		"threshold":      func(c *Config) { c.SilenceThreshold = "-35dB" },
+		"duration":       func(c *Config) { c.MinSilenceDur = 4 },
+		"detection mode": func(c *Config) { c.DetectionMode = detectionRMS },
+		"mono":           func(c *Config) { c.MonoDetection = true },
+	}
+	for name, change := range changes {
+		c := cfg
+		change(&c)
+		if cacheKey(c, stat) == key {
+			t.Errorf("Expected changing the %s to change the key", name)
+		}
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(input, later, later); err != nil {
+		t.Fatal(err)
+	}
+	touched, _ := os.Stat(input)
+	if cacheKey(cfg, touched) == key {
+		t.Error("Expected a modified input to change the key")
+	}
+}
+
+func TestCachedDetectSilence(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "practice.mp4")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{InputFile: input, SilenceThreshold: "-30dB", MinSilenceDur: 5, TempDir: t.TempDir()}
+	runs := 0
+	detect := func(Config) []segment {
+		runs++
+		return []segment{{start: 20, end: 30}, {start: 150.5, end: 160}}
+	}
+
+	first := cachedDetectSilence(cfg, detect)
+	second := cachedDetectSilence(cfg, detect)
+	if runs != 1 || !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the second pass to come from the cache, got %d runs and %v vs %v", runs, first, second)
+	}
+
+	cfg.SilenceThreshold = "-35dB"
+	cachedDetectSilence(cfg, detect)
+	cfg.NoDetectCache = true
+	cachedDetectSilence(cfg, detect)
+	if runs != 3 {
+		t.Errorf("Expected a new threshold and -no-cache to detect again, got %d runs", runs)
+	}
+}
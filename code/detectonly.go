@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// boundary is one song in -detect-only's JSON output.
+type boundary struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// printBoundaries writes the songs for -detect-only: one "START END" line per
+// song in seconds, which -boundaries reads back, or with asJSON a JSON array
+// of {"start", "end"} objects. Nothing else is written to out, so it can be
+// piped into another tool.
+func printBoundaries(out io.Writer, segments []segment, asJSON bool) error {
+	if asJSON {
+		list := make([]boundary, len(segments))
+		for i, seg := range segments {
+			list[i] = boundary{Start: seg.start, End: seg.end}
+		}
+		return json.NewEncoder(out).Encode(list)
+	}
+	for _, seg := range segments {
+		if _, err := fmt.Fprintf(out, "%.3f %.3f\n", seg.start, seg.end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPrintBoundariesJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := printBoundaries(&out, []segment{{0, 95.5}, {105.25, 300}}, true); err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"start":0,"end":95.5},{"start":105.25,"end":300}]` + "\n"
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
+
+func TestDetectOnlyPrintsOnlyBoundaries(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "practice.mp4")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 100\nsilence_end: 110\n"}
+		case strings.HasSuffix(args, "-i "+input):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+	outDir := filepath.Join(dir, "out")
+	s := NewSplitter(Config{
+		InputFile:        input,
+		OutputDir:        outDir,
+		OutputPrefix:     "Song",
+		SilenceThreshold: "-20dB",
+		MinSilenceDur:    5,
+		MinSongLength:    60,
+		UploadToDrive:    true,
+		RcloneRemote:     "gdrive:",
+		RunOptions:       RunOptions{DetectOnly: true, NoDetectCache: true},
+	})
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	runErr := s.Run()
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+
+	if runErr != nil {
+		t.Fatalf("Run failed: %v", runErr)
+	}
+	if want := "0.000 100.000\n110.000 300.000\n"; string(printed) != want {
+		t.Errorf("Expected only the boundaries on stdout, %q, got %q", want, printed)
+	}
+	for _, c := range fake.calls {
+		if c.name == "rclone" || slices.Contains(c.args, "-c") {
+			t.Errorf("Expected no upload or export, got %v", c)
+		}
+	}
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no output folder, got %v", err)
+	}
+}
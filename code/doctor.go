@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkResult is the outcome of one -doctor check.
+type checkResult struct {
+	name     string
+	ok       bool
+	critical bool // a failed critical check makes -doctor exit non-zero
+	detail   string
+}
+
+// runDoctor runs every environment check and collects the results, rather
+// than stopping at the first problem like a normal run does.
+func runDoctor(cfg Config) []checkResult {
+	var results []checkResult
+
+	// 1. ffmpeg
+	if version, err := toolVersion("ffmpeg", "-version"); err != nil {
+		results = append(results, checkResult{name: "ffmpeg", critical: true, detail: "not found in PATH"})
+	} else {
+		results = append(results, checkResult{name: "ffmpeg", ok: true, critical: true, detail: version})
+	}
+
+	// 2. ffprobe (ships with ffmpeg; only -probe, source_chapters and snap_keyframes need it)
+	if version, err := toolVersion("ffprobe", "-version"); err != nil {
+		results = append(results, checkResult{name: "ffprobe", detail: "not found in PATH"})
+	} else {
+		results = append(results, checkResult{name: "ffprobe", ok: true, detail: version})
+	}
+
+	// 3. rclone, only when uploads are configured
+	if cfg.UploadToDrive {
+		if version, err := toolVersion("rclone", "version"); err != nil {
+			results = append(results, checkResult{name: "rclone", critical: true, detail: "not found in PATH"})
+		} else {
+			results = append(results, checkResult{name: "rclone", ok: true, critical: true, detail: version})
+			for _, dest := range uploadDestinations(cfg) {
+				if err := testRcloneConnection(dest, cfg.RcloneGlobalFlags); err != nil {
+					results = append(results, checkResult{name: "rclone remote", critical: true, detail: err.Error()})
+				} else {
+					results = append(results, checkResult{name: "rclone remote", ok: true, critical: true, detail: dest.path()})
+				}
+			}
+		}
+	}
+
+	// 4. Output directory
+	if err := checkDirWritable(cfg.OutputDir); err != nil {
+		results = append(results, checkResult{name: "output directory", critical: true, detail: err.Error()})
+	} else {
+		results = append(results, checkResult{name: "output directory", ok: true, critical: true, detail: cfg.OutputDir})
+	}
+
+	// 5. Config files
+	paths, _ := configPaths(configFilePath)
+	for _, path := range paths {
+		if _, err := loadConfigFromFile(path); err == nil {
+			results = append(results, checkResult{name: "config file", ok: true, critical: true, detail: path})
+		} else if os.IsNotExist(err) {
+			results = append(results, checkResult{name: "config file", ok: true, critical: true, detail: fmt.Sprintf("'%s' not found, using defaults", path)})
+		} else {
+			results = append(results, checkResult{name: "config file", critical: true, detail: fmt.Sprintf("'%s': %v", path, err)})
+		}
+	}
+
+	return results
+}
+
+// toolVersion runs a tool's version command and returns the first line of
+// its output.
+func toolVersion(name string, args ...string) (string, error) {
+	output, err := execCommand(name, args...).Output()
+	if err != nil {
+		return "", err
+	}
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return firstLine, nil
+}
+
+// checkDirWritable reports whether files can be created in dir. A missing
+// dir is fine as long as its nearest existing parent is writable, since the
+// export creates it.
+func checkDirWritable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("'%s' is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+	probe, err := os.CreateTemp(dir, ".splitter-doctor-*")
+	if err != nil {
+		return fmt.Errorf("'%s' is not writable: %v", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// printChecklist writes one colored line per check result.
+func printChecklist(w io.Writer, results []checkResult) {
+	for _, r := range results {
+		mark := "\033[32m[OK]\033[0m  "
+		if !r.ok && r.critical {
+			mark = "\033[31m[FAIL]\033[0m"
+		} else if !r.ok {
+			mark = "\033[33m[WARN]\033[0m"
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", mark, r.name, r.detail)
+	}
+}
+
+// hasCriticalFailure reports whether any critical check failed.
+func hasCriticalFailure(results []checkResult) bool {
+	for _, r := range results {
+		if !r.ok && r.critical {
+			return true
+		}
+	}
+	return false
+}
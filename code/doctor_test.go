@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctorAggregatesResults(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		switch call.name {
+		case "ffmpeg":
+			return fakeResult{stdout: "ffmpeg version 7.1 Copyright (c) 2000-2024\nbuilt with clang\n"}
+		default:
+			return fakeResult{exitCode: 1}
+		}
+	})
+	oldConfigPath := configFilePath
+	defer func() { configFilePath = oldConfigPath }()
+	configFilePath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFilePath, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := runDoctor(Config{OutputDir: filepath.Join(t.TempDir(), "new-output")})
+
+	byName := make(map[string]checkResult)
+	for _, r := range results {
+		byName[r.name] = r
+	}
+	if r := byName["ffmpeg"]; !r.ok || r.detail != "ffmpeg version 7.1 Copyright (c) 2000-2024" {
+		t.Errorf("Expected ffmpeg to pass with its version line, got %+v", r)
+	}
+	if r := byName["ffprobe"]; r.ok || r.critical {
+		t.Errorf("Expected ffprobe to be a non-critical failure, got %+v", r)
+	}
+	if _, ok := byName["rclone"]; ok {
+		t.Error("Expected rclone to be skipped when upload is disabled")
+	}
+	if r := byName["output directory"]; !r.ok {
+		t.Errorf("Expected a creatable output directory to pass, got %+v", r)
+	}
+	if r := byName["config file"]; r.ok {
+		t.Errorf("Expected an unparseable config file to fail, got %+v", r)
+	}
+	if !hasCriticalFailure(results) {
+		t.Error("Expected the bad config file to count as a critical failure")
+	}
+}
+
+func TestHasCriticalFailure(t *testing.T) {
+	warningsOnly := []checkResult{
+		{name: "ffmpeg", ok: true, critical: true},
+		{name: "ffprobe", ok: false, critical: false},
+	}
+	if hasCriticalFailure(warningsOnly) {
+		t.Error("Expected a non-critical failure not to fail the doctor run")
+	}
+	withFailure := append(warningsOnly, checkResult{name: "rclone", ok: false, critical: true})
+	if !hasCriticalFailure(withFailure) {
+		t.Error("Expected a critical failure to fail the doctor run")
+	}
+}
+
+func TestPrintChecklist(t *testing.T) {
+	var buf bytes.Buffer
+	printChecklist(&buf, []checkResult{
+		{name: "ffmpeg", ok: true, critical: true, detail: "ffmpeg version 7.1"},
+		{name: "ffprobe", detail: "not found in PATH"},
+		{name: "rclone", critical: true, detail: "not found in PATH"},
+	})
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %q", len(lines), buf.String())
+	}
+	for i, want := range []string{"[OK]", "[WARN]", "[FAIL]"} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Line %d: expected %s, got %q", i, want, lines[i])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// dtsWarnings are what ffmpeg prints, once per bad packet, when the input's
+// decode timestamps go backwards. Stream-copying such a file gives songs
+// that stutter or drift out of sync.
+var dtsWarnings = []string{
+	"non-monotonous dts",
+	"non monotonically increasing dts",
+}
+
+// hasDTSWarnings reports whether an export's ffmpeg output has
+// non-monotonous DTS warnings in it.
+func hasDTSWarnings(output string) bool {
+	text := strings.ToLower(output)
+	for _, pattern := range dtsWarnings {
+		if strings.Contains(text, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// dtsFix is one way of re-cutting a song with bad timestamps.
+type dtsFix struct {
+	name string
+	desc string
+	cfg  Config
+}
+
+// dtsFixes lists the re-cuts to try, in order: regenerating the input's
+// timestamps, then re-encoding with them, unless the export already
+// re-encodes.
+func dtsFixes(cfg Config) []dtsFix {
+	genpts := cfg
+	genpts.genPTS = true
+	fixes := []dtsFix{{name: "genpts", desc: "-fflags +genpts", cfg: genpts}}
+	if !reencoding(cfg) {
+		reencode := genpts
+		reencode.Reencode = true
+		fixes = append(fixes, dtsFix{name: "reencode", desc: "a re-encode", cfg: reencode})
+	}
+	return fixes
+}
+
+// fixDTSWarnings re-cuts a song whose export had non-monotonous DTS
+// warnings, with handle_dts_warnings, until one of dtsFixes comes out clean.
+// The song stays flagged either way, so the report shows which ones to
+// check.
+func fixDTSWarnings(cfg Config, i int, seg segment, result segmentResult, metadata []string, opts exportOptions, logf func(format string, args ...any)) segmentResult {
+	if !cfg.HandleDTSWarnings || !result.DTSWarnings || result.Cached {
+		return result
+	}
+	for _, fix := range dtsFixes(cfg) {
+		logf("Re-cutting segment %d with %s", i+1, fix.desc)
+		os.Remove(result.File) // ffmpeg won't overwrite it
+		recut := exportSegment(fix.cfg, i, seg, result.File, metadata, opts, logf)
+		if recut.Status != statusExported {
+			return recut
+		}
+		clean := !recut.DTSWarnings
+		result = recut
+		result.DTSWarnings = true
+		result.DTSFix = fix.name
+		if clean {
+			return result
+		}
+	}
+	logf("Warning: Segment %d still has DTS warnings after re-cutting", i+1)
+	return result
+}
+
+// genPTSArgs are the input options regenerating missing or broken
+// timestamps for a re-cut.
+func genPTSArgs(cfg Config) []string {
+	if !cfg.genPTS {
+		return nil
+	}
+	return []string{"-fflags", "+genpts"}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+const dtsOutput = `[mp4 @ 0x55d0c8a1f2c0] Non-monotonous DTS in output stream 0:1; previous: 1234567, current: 1233920; changing to 1234568. This may result in incorrect timestamps in the output file.
+[mp4 @ 0x55d0c8a1f2c0] Non-monotonous DTS in output stream 0:1; previous: 1234568, current: 1234304; changing to 1234569. This may result in incorrect timestamps in the output file.
+`
+
+func TestHasDTSWarnings(t *testing.T) {
+	testCases := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{"NonMonotonous", dtsOutput, true},
+		{"InvalidDTS", "[matroska @ 0x7f] Application provided invalid, non monotonically increasing dts to muxer in stream 0: 9000 >= 8999\n", true},
+		{"Clean", "[mp4 @ 0x55d0c8a1f2c0] Starting second pass: moving the moov atom to the beginning of the file\n", false},
+		{"Empty", "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hasDTSWarnings(tc.output); got != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+// ffmpegExports returns the args of each ffmpeg call.
+func ffmpegExports(fake *fakeExec) [][]string {
+	var exports [][]string
+	for _, c := range fake.calls {
+		if c.name == "ffmpeg" {
+			exports = append(exports, c.args)
+		}
+	}
+	return exports
+}
+
+func TestFixDTSWarningsRecutsWithGenPTS(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if slices.Contains(call.args, "+genpts") {
+			return fakeResult{}
+		}
+		return fakeResult{stderr: dtsOutput}
+	})
+	cfg := Config{InputFile: "practice.mkv", OutputDir: t.TempDir(), OutputPrefix: "Song", HandleDTSWarnings: true}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 600, end: 845}}, exportOptions{})
+
+	exports := ffmpegExports(fake)
+	if len(exports) != 2 {
+		t.Fatalf("Expected the song exported then re-cut, got %d export(s)", len(exports))
+	}
+	if flags, input := slices.Index(exports[1], "-fflags"), slices.Index(exports[1], "-i"); flags < 0 || flags > input {
+		t.Errorf("Expected -fflags +genpts before the input, got %q", exports[1])
+	}
+	if r := results[0]; !r.DTSWarnings || r.DTSFix != "genpts" || r.Status != statusExported {
+		t.Errorf("Expected a flagged song fixed with genpts, got %+v", r)
+	}
+}
+
+func TestFixDTSWarningsFallsBackToReencode(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if slices.Contains(call.args, "libx264") {
+			return fakeResult{}
+		}
+		return fakeResult{stderr: dtsOutput}
+	})
+	cfg := Config{InputFile: "practice.mkv", OutputDir: t.TempDir(), OutputPrefix: "Song", HandleDTSWarnings: true}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 600, end: 845}}, exportOptions{})
+
+	if exports := ffmpegExports(fake); len(exports) != 3 {
+		t.Fatalf("Expected the copy, a genpts re-cut and a re-encode, got %d export(s)", len(exports))
+	}
+	if r := results[0]; !r.DTSWarnings || r.DTSFix != "reencode" {
+		t.Errorf("Expected the song fixed by re-encoding, got %+v", r)
+	}
+}
+
+func TestDTSWarningsFlaggedOnly(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: dtsOutput}
+	})
+	cfg := Config{InputFile: "practice.mkv", OutputDir: t.TempDir(), OutputPrefix: "Song"}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 600, end: 845}}, exportOptions{})
+
+	if exports := ffmpegExports(fake); len(exports) != 1 {
+		t.Errorf("Expected no re-cut without handle_dts_warnings, got %d export(s)", len(exports))
+	}
+	if r := results[0]; !r.DTSWarnings || r.DTSFix != "" {
+		t.Errorf("Expected the song flagged but not re-cut, got %+v", r)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+// Kinds of ProgressEvent.
+const (
+	ProgressDetectionStarted  = "detection-started"
+	ProgressDetectionFinished = "detection-finished"
+	ProgressSegmentDone       = "segment-done"
+	ProgressUploadStarted     = "upload-started"
+	ProgressUploadFinished    = "upload-finished"
+)
+
+// ProgressEvent reports a step of Splitter.Run to its ProgressFunc.
+type ProgressEvent struct {
+	Kind        string
+	Index       int    // 1-based song number, for ProgressSegmentDone
+	Total       int    // songs found, or songs being exported
+	File        string // the song's file, for ProgressSegmentDone
+	Destination string // for upload events
+	Err         error  // set if the segment or upload failed
+}
+
+// emit passes an event to the ProgressFunc, if there is one.
+func (s *Splitter) emit(e ProgressEvent) {
+	if s.ProgressFunc != nil {
+		s.ProgressFunc(e)
+	}
+}
+
+// segmentDone emits ProgressSegmentDone for an exported (or failed) song.
+// With several jobs, songs report as they finish, not in song order, unless
+// OrderedLogs holds them back to song order.
+func (s *Splitter) segmentDone(r segmentResult, total int) {
+	e := ProgressEvent{Kind: ProgressSegmentDone, Index: r.Index, Total: total, File: r.File}
+	if r.Status == statusFailed {
+		e.Err = errors.New(r.Error)
+	}
+	s.emit(e)
+}
+
+// logProgress is the CLI's ProgressFunc: a short log line per milestone.
+func logProgress(e ProgressEvent) {
+	switch e.Kind {
+	case ProgressDetectionFinished:
+		log.Printf("Progress: found %d song(s)", e.Total)
+	case ProgressSegmentDone:
+		if e.Err != nil {
+			log.Printf("Progress: song %d/%d failed", e.Index, e.Total)
+		} else {
+			log.Printf("Progress: song %d/%d done", e.Index, e.Total)
+		}
+	case ProgressUploadFinished:
+		if e.Err != nil {
+			log.Printf("Progress: upload to '%s' failed", e.Destination)
+		} else {
+			log.Printf("Progress: upload to '%s' done", e.Destination)
+		}
+	}
+}
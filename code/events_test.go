@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitterProgressEvents(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "practice.mp4")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 100\nsilence_end: 110\n"}
+		case strings.HasSuffix(args, "-i "+input):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		case strings.HasSuffix(args, "Song_02.mp4"):
+			return fakeResult{exitCode: 1}
+		}
+		return fakeResult{}
+	})
+	outDir := filepath.Join(dir, "out")
+	s := NewSplitter(Config{
+		InputFile:        input,
+		OutputDir:        outDir,
+		OutputPrefix:     "Song",
+		SilenceThreshold: "-20dB",
+		MinSilenceDur:    5,
+		MinSongLength:    60,
+		UploadToDrive:    true,
+		RcloneRemote:     "gdrive:",
+		DriveSubfolder:   "Band",
+	})
+	var events []ProgressEvent
+	s.ProgressFunc = func(e ProgressEvent) { events = append(events, e) }
+
+	if err := s.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+	}
+	want := []string{
+		ProgressDetectionStarted, ProgressDetectionFinished,
+		ProgressSegmentDone, ProgressSegmentDone,
+		ProgressUploadStarted, ProgressUploadFinished,
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("Expected events %v, got %v", want, kinds)
+	}
+	// Songs export in parallel, so they can finish in either order.
+	slices.SortFunc(events[2:4], func(a, b ProgressEvent) int { return a.Index - b.Index })
+	if events[1].Total != 2 {
+		t.Errorf("Expected detection to report 2 songs, got %+v", events[1])
+	}
+	if e := events[2]; e.Index != 1 || e.Total != 2 || e.File != outDir+"/Song_01.mp4" || e.Err != nil {
+		t.Errorf("Unexpected first segment event %+v", e)
+	}
+	if e := events[3]; e.Index != 2 || e.Err == nil {
+		t.Errorf("Expected the second segment to report its failure, got %+v", e)
+	}
+	if e := events[5]; e.Destination != "gdrive:Band" || e.Err != nil {
+		t.Errorf("Unexpected upload event %+v", e)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "errors"
+
+// Process exit codes, so scripts can tell failures apart.
+const (
+	exitOK                = 0
+	exitFailure           = 1 // anything not listed below
+	exitConfig            = 2 // bad flags or config (the flag package also uses 2)
+	exitMissingTool       = 3 // ffmpeg, ffprobe or rclone not installed
+	exitMissingInput      = 4
+	exitDetectionFailed   = 5 // no usable song boundaries
+	exitAllSegmentsFailed = 6
+	exitUploadFailed      = 7  // rclone pre-check or upload
+	exitAborted           = 8  // quit from the interactive editor, or the output folder was left alone
+	exitSetlistMismatch   = 9  // -strict-setlist and the song count is off
+	exitDoctorFailed      = 10 // -doctor found a critical problem
+)
+
+// exitError tags an error with the exit code it should end the process with.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode tags err with an exit code for exitCodeFor.
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCodeFor maps the error from Splitter.Run to a process exit code.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if errors.Is(err, errEditAborted) {
+		return exitAborted
+	}
+	return exitFailure
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"Success", nil, exitOK},
+		{"Untagged", errors.New("boom"), exitFailure},
+		{"MissingInput", withExitCode(exitMissingInput, errors.New("input file 'x' not found")), exitMissingInput},
+		{"Wrapped", fmt.Errorf("run: %w", withExitCode(exitUploadFailed, errors.New("rclone"))), exitUploadFailed},
+		{"EditorQuit", errEditAborted, exitAborted},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exitCodeFor(tc.err); got != tc.want {
+				t.Errorf("Expected exit code %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSplitterRunExitCodes(t *testing.T) {
+	t.Run("MissingFFmpeg", func(t *testing.T) {
+		installFakeExec(t, func(call fakeCall) fakeResult { return fakeResult{exitCode: 127} })
+		err := NewSplitter(Config{InputFile: "in.mp4"}).Run()
+		if got := exitCodeFor(err); got != exitMissingTool {
+			t.Errorf("Expected exit code %d, got %d (%v)", exitMissingTool, got, err)
+		}
+	})
+	t.Run("MissingInput", func(t *testing.T) {
+		installFakeExec(t, nil)
+		err := NewSplitter(Config{InputFile: filepath.Join(t.TempDir(), "missing.mp4")}).Run()
+		if got := exitCodeFor(err); got != exitMissingInput {
+			t.Errorf("Expected exit code %d, got %d (%v)", exitMissingInput, got, err)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import "log"
+
+// fixedIntervalSegments cuts the whole recording into back-to-back chunks of
+// interval seconds. A last chunk shorter than minSongLength is merged into
+// the one before it rather than left as a sliver.
+func fixedIntervalSegments(totalDuration, interval, minSongLength float64) []segment {
+	if interval <= 0 || totalDuration <= 0 {
+		return nil
+	}
+	var chunks []segment
+	for start := 0.0; start < totalDuration; start += interval {
+		end := min(start+interval, totalDuration)
+		if n := len(chunks); n > 0 && end-start < minSongLength {
+			chunks[n-1].end = end
+			break
+		}
+		chunks = append(chunks, segment{start: start, end: end})
+	}
+	return chunks
+}
+
+// segmentsByInterval cuts the whole recording every interval seconds, for
+// chunk_length (or fixed_interval). The last chunk is whatever is left,
+// however short.
+func segmentsByInterval(totalDuration, interval float64) []segment {
+	return fixedIntervalSegments(totalDuration, interval, 0)
+}
+
+// intervalFallback replaces detected songs with fixed-interval chunks when
+// fallback_interval is set and detection found fewer than fallback_min_songs,
+// for recordings whose silences detection can't find. It reports whether it
+// did.
+func intervalFallback(cfg Config, songs []segment, totalDuration float64) ([]segment, bool) {
+	if cfg.FallbackInterval <= 0 || cfg.NoSplit || cfg.ChunkLength > 0 || len(songs) >= cfg.FallbackMinSongs {
+		return songs, false
+	}
+	chunks := fixedIntervalSegments(totalDuration, cfg.FallbackInterval, cfg.MinSongLength)
+	if len(chunks) == 0 {
+		return songs, false
+	}
+	log.Printf("Warning: Detection found %d song(s), fewer than fallback_min_songs (%d); splitting into %d chunk(s) of %s instead.",
+		len(songs), cfg.FallbackMinSongs, len(chunks), formatTrackTime(cfg.FallbackInterval))
+	return chunks, true
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFixedIntervalSegments(t *testing.T) {
+	testCases := []struct {
+		name     string
+		total    float64
+		interval float64
+		expected []segment
+	}{
+		{"EvenSplit", 900, 300, []segment{{0, 300}, {300, 600}, {600, 900}}},
+		{"ShortLastChunkKept", 700, 300, []segment{{0, 300}, {300, 600}, {600, 700}}},
+		{"SliverMerged", 610, 300, []segment{{0, 300}, {300, 610}}},
+		{"ShorterThanOneInterval", 200, 300, []segment{{0, 200}}},
+		{"NoInterval", 900, 0, nil},
+	}
+	for _, tc := range testCases {
+		if got := fixedIntervalSegments(tc.total, tc.interval, 30); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestSegmentsByInterval(t *testing.T) {
+	testCases := []struct {
+		name     string
+		total    float64
+		interval float64
+		expected []segment
+	}{
+		{"EvenSplit", 1800, 600, []segment{{0, 600}, {600, 1200}, {1200, 1800}}},
+		{"UnevenSplit", 1500, 600, []segment{{0, 600}, {600, 1200}, {1200, 1500}}},
+		{"SliverKept", 1210, 600, []segment{{0, 600}, {600, 1200}, {1200, 1210}}},
+		{"ShorterThanOneInterval", 200, 600, []segment{{0, 200}}},
+	}
+	for _, tc := range testCases {
+		if got := segmentsByInterval(tc.total, tc.interval); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestFixedIntervalAlias(t *testing.T) {
+	base := defaultConfig
+	base.FixedInterval = 600
+
+	cfg, warnings, err := checkConfig(base)
+	if err != nil || cfg.ChunkLength != 600 || len(warnings) != 0 {
+		t.Errorf("Expected fixed_interval to set chunk_length, got %g (warnings %q, err %v)", cfg.ChunkLength, warnings, err)
+	}
+
+	base.ChunkLength = 300
+	cfg, warnings, _ = checkConfig(base)
+	if cfg.ChunkLength != 300 || len(warnings) != 1 {
+		t.Errorf("Expected chunk_length to win with a warning, got %g (warnings %q)", cfg.ChunkLength, warnings)
+	}
+}
+
+func TestIntervalFallback(t *testing.T) {
+	oneSong := []segment{{0, 1800}}
+	twoSongs := []segment{{0, 600}, {700, 1800}}
+	testCases := []struct {
+		name     string
+		cfg      Config
+		songs    []segment
+		expected bool
+	}{
+		{"TooFewSongs", Config{FallbackInterval: 600, FallbackMinSongs: 2}, oneSong, true},
+		{"NothingFound", Config{FallbackInterval: 600, FallbackMinSongs: 2}, nil, true},
+		{"EnoughSongs", Config{FallbackInterval: 600, FallbackMinSongs: 2}, twoSongs, false},
+		{"HigherThreshold", Config{FallbackInterval: 600, FallbackMinSongs: 3}, twoSongs, true},
+		{"Off", Config{FallbackMinSongs: 2}, oneSong, false},
+		{"NoSplit", Config{FallbackInterval: 600, FallbackMinSongs: 2, NoSplit: true}, oneSong, false},
+	}
+	for _, tc := range testCases {
+		got, ok := intervalFallback(tc.cfg, tc.songs, 1800)
+		if ok != tc.expected {
+			t.Errorf("%s: expected fallback %v, got %v", tc.name, tc.expected, ok)
+			continue
+		}
+		want := tc.songs
+		if ok {
+			want = []segment{{0, 600}, {600, 1200}, {1200, 1800}}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, want, got)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fullSetName is the file name, without extension, of the full_set file.
+const fullSetName = "Full_Set"
+
+// fullSetPath returns where full_set writes the joined songs: Full_Set in
+// OutputDir, after the session name if there is one, with the songs'
+// extension.
+func fullSetPath(cfg Config) string {
+	return filepath.Join(cfg.OutputDir, sessionPrefix(cfg.SessionName)+fullSetName+outputExt(cfg))
+}
+
+// exportFullSet joins the exported songs, in order, into one file for a
+// listen-through without the dead air between them. The songs are already
+// cut, so they're joined as they are with ffmpeg's concat demuxer rather
+// than cut from the recording again.
+func exportFullSet(cfg Config, songs []string) (string, error) {
+	if err := os.MkdirAll(tempDir(cfg), 0755); err != nil {
+		return "", err
+	}
+	list, err := os.CreateTemp(tempDir(cfg), "rehearsal-splitter-full-set-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(list.Name())
+	_, err = list.WriteString(buildConcatList(songs))
+	if closeErr := list.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	out := fullSetPath(cfg)
+	cmd := execCommand("ffmpeg", ffmpegArgs(cfg.FFmpegLogLevel, "-y", "-f", "concat", "-safe", "0", "-i", list.Name(), "-map", "0", "-c", "copy", out)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("joining the songs failed: %v\n%s", err, output)
+	}
+	return out, nil
+}
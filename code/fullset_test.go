@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExportFullSet(t *testing.T) {
+	var list string
+	var concat []string
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if i := slices.Index(call.args, "concat"); i >= 0 {
+			concat = call.args
+			data, _ := os.ReadFile(call.args[i+4])
+			list = string(data)
+		}
+		return fakeResult{}
+	})
+	dir := t.TempDir()
+	cfg := Config{InputFile: "practice.mp4", OutputDir: filepath.Join(dir, "out"), TempDir: dir, SessionName: "Spring"}
+	songs := []string{filepath.Join(dir, "out", "01 - Reba.mp4"), filepath.Join(dir, "out", "02 - Bob's Song.mp4")}
+
+	path, err := exportFullSet(cfg, songs)
+	if err != nil {
+		t.Fatalf("exportFullSet failed: %v", err)
+	}
+
+	if want := filepath.Join(dir, "out", "Spring_Full_Set.mp4"); path != want || concat[len(concat)-1] != want {
+		t.Errorf("Expected the full set at %s, got %s (args %q)", want, path, concat)
+	}
+	if len(fake.calls) != 1 || !slices.Contains(concat, "copy") {
+		t.Errorf("Expected the songs joined as they are in one call, got %v", fake.calls)
+	}
+	if want := buildConcatList(songs); list != want {
+		t.Errorf("Expected the songs listed in order:\n%s\ngot:\n%s", want, list)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "rehearsal-splitter-full-set-*")); len(matches) != 0 {
+		t.Errorf("Expected the concat list to be removed, found %v", matches)
+	}
+}
+
+func TestExportFullSetFailure(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: "Invalid data found when processing input", exitCode: 1}
+	})
+	dir := t.TempDir()
+	cfg := Config{InputFile: "practice.mp4", OutputDir: dir, TempDir: dir}
+
+	_, err := exportFullSet(cfg, []string{filepath.Join(dir, "Song_01.mp4")})
+
+	if err == nil || !strings.Contains(err.Error(), "Invalid data") {
+		t.Errorf("Expected ffmpeg's output in the error, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gapsPath returns where export_gaps writes the joined gaps: GapsName in
+// OutputDir, with the songs' extension.
+func gapsPath(cfg Config) string {
+	return filepath.Join(cfg.OutputDir, sanitizeFilename(cfg.GapsName)+outputExt(cfg))
+}
+
+// buildConcatList writes an ffmpeg concat demuxer list of files, quoting
+// each path so spaces and apostrophes survive.
+func buildConcatList(files []string) string {
+	var b strings.Builder
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			abs = f
+		}
+		fmt.Fprintf(&b, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	return b.String()
+}
+
+// exportGaps joins every gap between songs into one file, so the talk and
+// tuning in between is kept as a deliverable of its own. Each gap is cut
+// the same way as a song into a scratch folder under temp_dir, then the
+// pieces are joined with ffmpeg's concat demuxer without re-encoding them
+// again.
+func exportGaps(cfg Config, gaps []segment) (string, error) {
+	if err := os.MkdirAll(tempDir(cfg), 0755); err != nil {
+		return "", err
+	}
+	scratch, err := os.MkdirTemp(tempDir(cfg), "rehearsal-splitter-gaps-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(scratch)
+
+	pieceCfg := cfg
+	pieceCfg.OutputDir = scratch
+	pieceCfg.OutputPrefix = "Gap"
+	pieceCfg.MetadataTemplates = nil
+	pieceCfg.PreserveAbsoluteTiming = false // concat lays the pieces end to end
+	pieceCfg.PostHook = ""                  // only the joined file is a deliverable
+	pieces := exportedPaths(splitVideoIntoSegments(pieceCfg, gaps, exportOptions{}))
+	if len(pieces) == 0 {
+		return "", fmt.Errorf("none of the %d gap(s) could be exported", len(gaps))
+	}
+	if len(pieces) < len(gaps) {
+		log.Printf("Warning: %d of %d gap(s) failed to export and are missing from the joined file.", len(gaps)-len(pieces), len(gaps))
+	}
+
+	list := filepath.Join(scratch, "gaps.txt")
+	if err := os.WriteFile(list, []byte(buildConcatList(pieces)), 0644); err != nil {
+		return "", err
+	}
+	out := gapsPath(cfg)
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		return "", err
+	}
+	cmd := execCommand("ffmpeg", ffmpegArgs(cfg.FFmpegLogLevel, "-y", "-f", "concat", "-safe", "0", "-i", list, "-map", "0", "-c", "copy", out)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("joining the gaps failed: %v\n%s", err, output)
+	}
+	return out, nil
+}
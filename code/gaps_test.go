@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExportGaps(t *testing.T) {
+	var list string
+	var concat []string
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if i := slices.Index(call.args, "concat"); i >= 0 {
+			concat = call.args
+			data, _ := os.ReadFile(call.args[i+4])
+			list = string(data)
+		}
+		return fakeResult{}
+	})
+	dir := t.TempDir()
+	cfg := Config{InputFile: "practice.mp4", OutputDir: filepath.Join(dir, "out"), OutputPrefix: "Song", TempDir: dir, GapsName: "Between Songs"}
+	gaps := []segment{{start: 0, end: 12}, {start: 190, end: 230}, {start: 400, end: 415}}
+
+	path, err := exportGaps(cfg, gaps)
+	if err != nil {
+		t.Fatalf("exportGaps failed: %v", err)
+	}
+
+	if want := filepath.Join(dir, "out", "Between_Songs.mp4"); path != want || concat[len(concat)-1] != want {
+		t.Errorf("Expected the joined file at %s, got %s (args %q)", want, path, concat)
+	}
+	if len(fake.calls) != 4 {
+		t.Errorf("Expected 3 gap exports and a join, got %d calls", len(fake.calls))
+	}
+	lines := strings.Split(strings.TrimSuffix(list, "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "file '"+dir) || !strings.HasSuffix(lines[2], "Gap_03.mp4'") {
+		t.Errorf("Expected a concat list of the three gaps, got:\n%s", list)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "rehearsal-splitter-gaps-*"))
+	if len(matches) != 0 {
+		t.Errorf("Expected the scratch folder to be removed, found %v", matches)
+	}
+}
+
+func TestBuildConcatListQuotes(t *testing.T) {
+	got := buildConcatList([]string{"/tmp/Bob's gap.mp4"})
+	if want := `file '/tmp/Bob'\''s gap.mp4'` + "\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
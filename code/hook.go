@@ -0,0 +1,34 @@
+package main
+
+import "strings"
+
+// hookCommand builds the post_hook command line for file. Each "{}" in hook
+// becomes the file's path, single-quoted for the shell so that spaces,
+// quotes or a "$" in a song title stay part of the path; the path is added
+// at the end if hook has no "{}".
+func hookCommand(hook, file string) string {
+	quoted := shellQuote(file)
+	if !strings.Contains(hook, "{}") {
+		return hook + " " + quoted
+	}
+	return strings.ReplaceAll(hook, "{}", quoted)
+}
+
+// shellQuote single-quotes s for sh, which takes everything between single
+// quotes literally; a ' inside s is closed, escaped and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runPostHook runs post_hook with sh for one exported file, logging what it
+// prints. A hook that fails only gets a warning; the song is still exported.
+func runPostHook(hook, file string, logf func(format string, args ...any)) {
+	cmd := execCommand("sh", "-c", hookCommand(hook, file))
+	output, err := cmd.CombinedOutput()
+	if out := strings.TrimSpace(string(output)); out != "" {
+		logf("post_hook output for '%s':\n%s", file, out)
+	}
+	if err != nil {
+		logf("Warning: post_hook failed for '%s': %v", file, err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestHookCommand(t *testing.T) {
+	testCases := []struct {
+		hook, file, want string
+	}{
+		{"tag-song {}", "/out/01 - Reba.mp4", `tag-song '/out/01 - Reba.mp4'`},
+		{"cp {} /backup/ && notify-send done", "/out/Song_01.mp4", `cp '/out/Song_01.mp4' /backup/ && notify-send done`},
+		{"tag-song", "/out/Song_01.mp4", `tag-song '/out/Song_01.mp4'`},
+		{"tag {} {}", "/out/a.mp4", `tag '/out/a.mp4' '/out/a.mp4'`},
+		{"tag-song {}", "/out/Bob's $(rm -rf ~).mp4", `tag-song '/out/Bob'\''s $(rm -rf ~).mp4'`},
+	}
+	for _, tc := range testCases {
+		if got := hookCommand(tc.hook, tc.file); got != tc.want {
+			t.Errorf("hookCommand(%q, %q): expected %s, got %s", tc.hook, tc.file, tc.want, got)
+		}
+	}
+}
+
+func TestPostHookRunsPerExport(t *testing.T) {
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(orig) })
+
+	var hooks []string
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		switch {
+		case call.name != "sh":
+			return fakeResult{}
+		case strings.Contains(call.args[1], "Song_02"):
+			hooks = append(hooks, call.args[1])
+			return fakeResult{stderr: "tagger: no such file\n", exitCode: 3}
+		}
+		hooks = append(hooks, call.args[1])
+		return fakeResult{stdout: "tagged\n"}
+	})
+	dir := t.TempDir()
+	cfg := Config{InputFile: "practice.mp4", OutputDir: dir, OutputPrefix: "Song", PostHook: "tag-song {}", Jobs: 1}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 0, end: 200}, {start: 210, end: 400}}, exportOptions{})
+
+	want := []string{fmt.Sprintf("tag-song '%s/Song_01.mp4'", dir), fmt.Sprintf("tag-song '%s/Song_02.mp4'", dir)}
+	if strings.Join(hooks, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected hooks %q, got %q", want, hooks)
+	}
+	if results[1].Status != statusExported {
+		t.Errorf("Expected a failing hook not to fail the export, got %+v", results[1])
+	}
+	logged := buf.String()
+	if !strings.Contains(logged, "tagged") || !strings.Contains(logged, "Warning: post_hook failed") || !strings.Contains(logged, "tagger: no such file") {
+		t.Errorf("Expected the hooks' output and a warning in the log, got:\n%s", logged)
+	}
+}
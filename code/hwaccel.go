@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// HWAccel values: which hardware H.264 encoder re-encodes use.
+const (
+	hwAccelNone         = "none"
+	hwAccelNVENC        = "nvenc"        // NVIDIA
+	hwAccelVideoToolbox = "videotoolbox" // macOS
+	hwAccelQSV          = "qsv"          // Intel Quick Sync
+)
+
+// hwAccel describes one hardware accelerator: its H.264 encoder and the
+// -hwaccel method that decodes the input on the same hardware.
+type hwAccel struct {
+	encoder string
+	decode  string
+}
+
+var hwAccels = map[string]hwAccel{
+	hwAccelNVENC:        {encoder: "h264_nvenc", decode: "cuda"},
+	hwAccelVideoToolbox: {encoder: "h264_videotoolbox", decode: "videotoolbox"},
+	hwAccelQSV:          {encoder: "h264_qsv", decode: "qsv"},
+}
+
+// videoToolboxQuality is the -q:v (1-100, higher is better) videotoolbox
+// encodes with when no video_bitrate is set, since it has no CRF.
+const videoToolboxQuality = 65
+
+// hwEncoder returns the hardware encoder to use in place of a software video
+// encoder, or video itself when hw_accel is off or video isn't H.264.
+func hwEncoder(cfg Config, video string) string {
+	accel, ok := hwAccels[cfg.HWAccel]
+	if !ok || video != "libx264" {
+		return video
+	}
+	return accel.encoder
+}
+
+// hwAccelActive reports whether exports re-encode video on the hardware
+// hw_accel picks.
+func hwAccelActive(cfg Config) bool {
+	video, _ := exportCodecs(cfg)
+	return reencoding(cfg) && !audioOnlyOutput(cfg) && hwEncoder(cfg, video) != video
+}
+
+// hwAccelInputArgs returns the -hwaccel option that decodes the input on the
+// same hardware as the encoder. Frames still come back to system memory, so
+// the software filters keep working.
+func hwAccelInputArgs(cfg Config) []string {
+	if !hwAccelActive(cfg) {
+		return nil
+	}
+	return []string{"-hwaccel", hwAccels[cfg.HWAccel].decode}
+}
+
+// hwQualityArgs translates video_crf or video_bitrate for a hardware
+// encoder, none of which take -crf: NVENC's constant quality is -cq and Quick
+// Sync's is -global_quality, on roughly the same scale; videotoolbox only
+// has its own -q:v.
+func hwQualityArgs(cfg Config) []string {
+	if cfg.VideoCRF <= 0 && cfg.VideoBitrate != "" {
+		return []string{"-b:v", cfg.VideoBitrate}
+	}
+	crf := cfg.VideoCRF
+	if crf <= 0 {
+		crf = defaultVideoCRF
+	}
+	switch cfg.HWAccel {
+	case hwAccelNVENC:
+		return []string{"-cq", strconv.Itoa(crf)}
+	case hwAccelQSV:
+		return []string{"-global_quality", strconv.Itoa(crf)}
+	default:
+		return []string{"-q:v", strconv.Itoa(videoToolboxQuality)}
+	}
+}
+
+// encoderAvailable reports whether this ffmpeg build lists encoder in
+// `ffmpeg -encoders`. A listed hardware encoder can still fail at run time
+// without the hardware or its driver; the export then fails with an error
+// log like any other.
+func encoderAvailable(encoder string) bool {
+	output, err := execCommand("ffmpeg", "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		// " V....D h264_nvenc   NVIDIA NVENC H.264 encoder"
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[1] == encoder {
+			return true
+		}
+	}
+	return false
+}
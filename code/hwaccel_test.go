@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHWEncoderSelection(t *testing.T) {
+	testCases := []struct {
+		accel, container, want string
+	}{
+		{hwAccelNone, "", "libx264"},
+		{"", "", "libx264"},
+		{hwAccelNVENC, "", "h264_nvenc"},
+		{hwAccelVideoToolbox, "mov", "h264_videotoolbox"},
+		{hwAccelQSV, "mkv", "h264_qsv"},
+	}
+	for _, tc := range testCases {
+		cfg := Config{InputFile: "practice.mp4", Reencode: true, HWAccel: tc.accel, OutputContainer: tc.container}
+		video, _ := exportCodecs(cfg)
+		if got := hwEncoder(cfg, video); got != tc.want {
+			t.Errorf("hw_accel %q into %q: expected %s, got %s", tc.accel, tc.container, tc.want, got)
+		}
+	}
+}
+
+func TestHWAccelExportArgs(t *testing.T) {
+	cfg := Config{InputFile: "practice.mp4", Reencode: true, HWAccel: hwAccelNVENC, VideoCRF: 20}
+
+	args := buildExportArgs(cfg, segment{start: 60, end: 300}, "Song_01.mp4", nil)
+
+	joined := strings.Join(args, " ")
+	if !strings.Contains(joined, "-hwaccel cuda") || slices.Index(args, "-hwaccel") > slices.Index(args, "-i") {
+		t.Errorf("Expected -hwaccel cuda before the input, got %q", args)
+	}
+	if !strings.Contains(joined, "-c:v h264_nvenc -cq 20") || slices.Contains(args, "-crf") {
+		t.Errorf("Expected NVENC with constant quality 20, got %q", args)
+	}
+
+	cfg.Reencode = false
+	if args := buildExportArgs(cfg, segment{start: 60, end: 300}, "Song_01.mp4", nil); slices.Contains(args, "-hwaccel") {
+		t.Errorf("Expected no hardware flags for a stream copy, got %q", args)
+	}
+}
+
+func TestHWQualityArgs(t *testing.T) {
+	testCases := []struct {
+		cfg  Config
+		want []string
+	}{
+		{Config{HWAccel: hwAccelQSV}, []string{"-global_quality", "20"}},
+		{Config{HWAccel: hwAccelNVENC, VideoBitrate: "8M"}, []string{"-b:v", "8M"}},
+		{Config{HWAccel: hwAccelVideoToolbox, VideoCRF: 18}, []string{"-q:v", "65"}},
+	}
+	for _, tc := range testCases {
+		if got := hwQualityArgs(tc.cfg); !slices.Equal(got, tc.want) {
+			t.Errorf("%+v: expected %q, got %q", tc.cfg, tc.want, got)
+		}
+	}
+}
+
+func TestEncoderAvailable(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stdout: "Encoders:\n ------\n V....D libx264              libx264 H.264 / AVC (codec h264)\n V....D h264_videotoolbox    VideoToolbox H.264 Encoder (codec h264)\n"}
+	})
+	if !encoderAvailable("h264_videotoolbox") {
+		t.Error("Expected h264_videotoolbox to be found")
+	}
+	if encoderAvailable("h264_nvenc") {
+		t.Error("Expected h264_nvenc to be missing")
+	}
+}
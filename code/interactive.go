@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errEditAborted is returned when the user quits the interactive editor.
+var errEditAborted = errors.New("export aborted from the interactive editor")
+
+const editorHelp = `Commands (song numbers as listed):
+  list                     show the songs again
+  merge N                  join song N with song N+1
+  split N TIME             split song N in two at TIME
+  delete N                 drop song N
+  adjust N START END       move song N's boundaries
+  done (or accept)         export the songs as listed
+  quit                     stop without exporting
+TIME can be seconds (95.5), M:SS or H:MM:SS.`
+
+// editSegmentsInteractive lets the user review and edit the song list before
+// export. Reaching the end of input is treated like "done".
+func editSegmentsInteractive(in io.Reader, out io.Writer, segments []segment) ([]segment, error) {
+	segments = append([]segment(nil), segments...)
+	fmt.Fprintln(out, editorHelp)
+	printSegments(out, segments)
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return segments, scanner.Err()
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch fields[0] {
+		case "list", "l":
+			printSegments(out, segments)
+			continue
+		case "help", "h", "?":
+			fmt.Fprintln(out, editorHelp)
+			continue
+		case "done", "d", "accept":
+			return segments, nil
+		case "quit", "q":
+			return nil, errEditAborted
+		case "merge", "m":
+			err = withSongNumber(fields, 2, func(i int, _ []float64) (e error) {
+				segments, e = mergeSegments(segments, i)
+				return e
+			})
+		case "split", "s":
+			err = withSongNumber(fields, 3, func(i int, times []float64) (e error) {
+				segments, e = splitSegment(segments, i, times[0])
+				return e
+			})
+		case "delete", "del":
+			err = withSongNumber(fields, 2, func(i int, _ []float64) (e error) {
+				segments, e = deleteSegment(segments, i)
+				return e
+			})
+		case "adjust", "a":
+			err = withSongNumber(fields, 4, func(i int, times []float64) (e error) {
+				segments, e = adjustSegment(segments, i, times[0], times[1])
+				return e
+			})
+		default:
+			err = fmt.Errorf("unknown command '%s' (type 'help')", fields[0])
+		}
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+			continue
+		}
+		printSegments(out, segments)
+	}
+}
+
+// withSongNumber checks a command's argument count, converts its 1-based
+// song number to an index and parses any trailing timestamps before calling fn.
+func withSongNumber(fields []string, wantFields int, fn func(i int, times []float64) error) error {
+	if len(fields) != wantFields {
+		return fmt.Errorf("'%s' takes %d argument(s) (type 'help')", fields[0], wantFields-1)
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return fmt.Errorf("invalid song number '%s'", fields[1])
+	}
+	var times []float64
+	for _, f := range fields[2:] {
+		t, err := parseTimestamp(f)
+		if err != nil {
+			return err
+		}
+		times = append(times, t)
+	}
+	return fn(n-1, times)
+}
+
+// printSegments lists the songs with their 1-based numbers.
+func printSegments(out io.Writer, segments []segment) {
+	if len(segments) == 0 {
+		fmt.Fprintln(out, "(no songs)")
+		return
+	}
+	for i, seg := range segments {
+		fmt.Fprintf(out, "%3d. %9.2fs - %9.2fs  (%.1fs)\n", i+1, seg.start, seg.end, seg.end-seg.start)
+	}
+}
+
+// mergeSegments joins segments i and i+1 into one spanning both.
+func mergeSegments(segments []segment, i int) ([]segment, error) {
+	if i < 0 || i+1 >= len(segments) {
+		return segments, fmt.Errorf("song %d has no following song to merge with", i+1)
+	}
+	merged := append([]segment(nil), segments[:i]...)
+	merged = append(merged, segment{start: segments[i].start, end: segments[i+1].end})
+	return append(merged, segments[i+2:]...), nil
+}
+
+// splitSegment cuts segment i in two at time at.
+func splitSegment(segments []segment, i int, at float64) ([]segment, error) {
+	if i < 0 || i >= len(segments) {
+		return segments, fmt.Errorf("no song %d", i+1)
+	}
+	seg := segments[i]
+	if at <= seg.start || at >= seg.end {
+		return segments, fmt.Errorf("split time %.2fs is outside song %d (%.2fs - %.2fs)", at, i+1, seg.start, seg.end)
+	}
+	split := append([]segment(nil), segments[:i]...)
+	split = append(split, segment{start: seg.start, end: at}, segment{start: at, end: seg.end})
+	return append(split, segments[i+1:]...), nil
+}
+
+// deleteSegment removes segment i.
+func deleteSegment(segments []segment, i int) ([]segment, error) {
+	if i < 0 || i >= len(segments) {
+		return segments, fmt.Errorf("no song %d", i+1)
+	}
+	kept := append([]segment(nil), segments[:i]...)
+	return append(kept, segments[i+1:]...), nil
+}
+
+// adjustSegment moves segment i's boundaries, keeping it from overlapping
+// its neighbours.
+func adjustSegment(segments []segment, i int, start, end float64) ([]segment, error) {
+	if i < 0 || i >= len(segments) {
+		return segments, fmt.Errorf("no song %d", i+1)
+	}
+	if start < 0 || end <= start {
+		return segments, fmt.Errorf("invalid range %.2fs - %.2fs", start, end)
+	}
+	if i > 0 && start < segments[i-1].end {
+		return segments, fmt.Errorf("start %.2fs overlaps song %d, which ends at %.2fs", start, i, segments[i-1].end)
+	}
+	if i+1 < len(segments) && end > segments[i+1].start {
+		return segments, fmt.Errorf("end %.2fs overlaps song %d, which starts at %.2fs", end, i+2, segments[i+1].start)
+	}
+	adjusted := append([]segment(nil), segments...)
+	adjusted[i] = segment{start: start, end: end}
+	return adjusted, nil
+}
+
+// parseTimestamp parses seconds ("95.5"), "M:SS" or "H:MM:SS" into seconds.
+func parseTimestamp(s string) (float64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time '%s'", s)
+	}
+	total := 0.0
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid time '%s'", s)
+		}
+		total = total*60 + v
+	}
+	return total, nil
+}
+
+// promptOutput is where the editor writes its prompts: stdout, or stderr
+// with -detect-only, which keeps stdout for the boundaries.
+func promptOutput(cfg Config) *os.File {
+	if cfg.DetectOnly {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe
+// or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEditSegmentsInteractive(t *testing.T) {
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}, {start: 210, end: 400}, {start: 410, end: 500}}
+	input := strings.Join([]string{
+		"merge 1",        // songs 1+2 -> 0-200
+		"split 2 5:00",   // 210-400 -> 210-300, 300-400
+		"delete 4",       // drop 410-500
+		"adjust 1 2 195", // tighten the merged song
+		"bogus",          // reported, not fatal
+		"split 9 10",     // reported, not fatal
+		"done",
+		"delete 1", // never reached
+	}, "\n")
+	var out bytes.Buffer
+
+	edited, err := editSegmentsInteractive(strings.NewReader(input), &out, segments)
+	if err != nil {
+		t.Fatalf("editSegmentsInteractive failed: %v", err)
+	}
+
+	expected := []segment{{start: 2, end: 195}, {start: 210, end: 300}, {start: 300, end: 400}}
+	if !reflect.DeepEqual(edited, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, edited)
+	}
+	if !strings.Contains(out.String(), "unknown command 'bogus'") || !strings.Contains(out.String(), "no song 9") {
+		t.Errorf("Expected errors to be reported, got:\n%s", out.String())
+	}
+	if segments[0].end != 100 {
+		t.Error("Expected the input slice to be left untouched")
+	}
+}
+
+func TestEditSegmentsInteractiveQuit(t *testing.T) {
+	_, err := editSegmentsInteractive(strings.NewReader("quit\n"), &bytes.Buffer{}, []segment{{start: 0, end: 10}})
+	if err != errEditAborted {
+		t.Errorf("Expected errEditAborted, got %v", err)
+	}
+}
+
+func TestEditSegmentsInteractiveAccept(t *testing.T) {
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}}
+	edited, err := editSegmentsInteractive(strings.NewReader("merge 1\naccept\ndelete 1\n"), &bytes.Buffer{}, segments)
+	if err != nil {
+		t.Fatalf("editSegmentsInteractive failed: %v", err)
+	}
+	if expected := []segment{{start: 0, end: 200}}; !reflect.DeepEqual(edited, expected) {
+		t.Errorf("Expected 'accept' to finish like 'done', got %+v", edited)
+	}
+}
+
+func TestEditSegmentsInteractiveEOF(t *testing.T) {
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}}
+	edited, err := editSegmentsInteractive(strings.NewReader("merge 1"), &bytes.Buffer{}, segments)
+	if err != nil {
+		t.Fatalf("Expected end of input to act like done, got %v", err)
+	}
+	if expected := []segment{{start: 0, end: 200}}; !reflect.DeepEqual(edited, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, edited)
+	}
+}
+
+func TestMergeSegments(t *testing.T) {
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}, {start: 210, end: 300}}
+	merged, err := mergeSegments(segments, 1)
+	if err != nil {
+		t.Fatalf("mergeSegments failed: %v", err)
+	}
+	if expected := []segment{{start: 0, end: 100}, {start: 110, end: 300}}; !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, merged)
+	}
+	if _, err := mergeSegments(segments, 2); err == nil {
+		t.Error("Expected an error merging the last song")
+	}
+	if segments[1].end != 200 {
+		t.Error("Expected the input slice to be left untouched")
+	}
+}
+
+func TestSplitSegment(t *testing.T) {
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}}
+	split, err := splitSegment(segments, 0, 40)
+	if err != nil {
+		t.Fatalf("splitSegment failed: %v", err)
+	}
+	if expected := []segment{{start: 0, end: 40}, {start: 40, end: 100}, {start: 110, end: 200}}; !reflect.DeepEqual(split, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, split)
+	}
+	for _, at := range []float64{0, 100, 105} {
+		if _, err := splitSegment(segments, 0, at); err == nil {
+			t.Errorf("Expected an error splitting song 1 at %.0fs", at)
+		}
+	}
+	if _, err := splitSegment(segments, 2, 150); err == nil {
+		t.Error("Expected an error for a song that doesn't exist")
+	}
+}
+
+func TestAdjustSegmentRejectsOverlap(t *testing.T) {
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}, {start: 210, end: 300}}
+	if _, err := adjustSegment(segments, 1, 90, 200); err == nil {
+		t.Error("Expected an error when the start overlaps the previous song")
+	}
+	if _, err := adjustSegment(segments, 1, 110, 250); err == nil {
+		t.Error("Expected an error when the end overlaps the next song")
+	}
+	if _, err := adjustSegment(segments, 1, 150, 120); err == nil {
+		t.Error("Expected an error for an inverted range")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected float64
+		wantErr  bool
+	}{
+		{in: "95.5", expected: 95.5},
+		{in: "3:45", expected: 225},
+		{in: "1:02:03", expected: 3723},
+		{in: "0:00", expected: 0},
+		{in: "abc", wantErr: true},
+		{in: "1:2:3:4", wantErr: true},
+		{in: "-5", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := parseTimestamp(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.expected {
+			t.Errorf("parseTimestamp(%q) = %v, %v; expected %v (error %v)", tc.in, got, err, tc.expected, tc.wantErr)
+		}
+	}
+}
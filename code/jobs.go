@@ -0,0 +1,33 @@
+package main
+
+import "runtime"
+
+// copyJobs is how many stream copies run at once by default. A copy barely
+// touches the CPU; it's bound by the disk, which a few readers keep busy
+// without thrashing.
+const copyJobs = 4
+
+// hwAccelJobs caps the default for hw_accel exports. A GPU has only a couple
+// of encoder sessions (consumer NVIDIA cards allow a few at most), so more
+// jobs just fail or queue up behind each other.
+const hwAccelJobs = 2
+
+// exportJobs is how many songs to export at once: Jobs if set, otherwise a
+// default for the kind of export. x264 already spreads one encode over
+// several cores, so video re-encodes get half the CPUs, and at most
+// hwAccelJobs on hw_accel hardware. Audio encoders use one core each, so
+// audio-only exports get one job per CPU. Stream copies get copyJobs.
+func exportJobs(cfg Config) int {
+	switch {
+	case cfg.Jobs > 0:
+		return cfg.Jobs
+	case audioOnlyOutput(cfg):
+		return runtime.NumCPU()
+	case hwAccelActive(cfg):
+		return min(max(runtime.NumCPU()/2, 1), hwAccelJobs)
+	case reencoding(cfg):
+		return max(runtime.NumCPU()/2, 1)
+	default:
+		return copyJobs
+	}
+}
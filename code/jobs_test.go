@@ -0,0 +1,27 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestExportJobs(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  Config
+		want int
+	}{
+		{"StreamCopy", Config{}, copyJobs},
+		{"VideoReEncode", Config{Reencode: true}, max(runtime.NumCPU()/2, 1)},
+		{"ContainerForcesReEncode", Config{InputFile: "in.mov", OutputContainer: "webm"}, max(runtime.NumCPU()/2, 1)},
+		{"HWAccel", Config{Reencode: true, HWAccel: hwAccelNVENC}, min(max(runtime.NumCPU()/2, 1), hwAccelJobs)},
+		{"AudioOnly", Config{OutputContainer: "mp3"}, runtime.NumCPU()},
+		{"Explicit,Copy", Config{Jobs: 2}, 2},
+		{"Explicit,ReEncode", Config{Jobs: 3, Reencode: true}, 3},
+	}
+	for _, tc := range testCases {
+		if got := exportJobs(tc.cfg); got != tc.want {
+			t.Errorf("%s: expected %d jobs, got %d", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// probeKeyframes returns the presentation times of the input's video
+// keyframes, in order. -skip_frame nokey makes ffprobe skip decoding every
+// other frame, so this is much faster than a full -show_frames.
+func probeKeyframes(path string) ([]float64, error) {
+	output, err := runFFprobe("-select_streams", "v:0", "-skip_frame", "nokey", "-show_frames",
+		"-show_entries", "frame=pts_time", "-of", "csv=p=0", path)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyframeTimes(output), nil
+}
+
+// parseKeyframeTimes reads one pts_time per line, skipping frames ffprobe
+// couldn't time ("N/A").
+func parseKeyframeTimes(output string) []float64 {
+	var times []float64
+	for _, line := range strings.Split(output, "\n") {
+		t, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(line, ",")), 64)
+		if err == nil {
+			times = append(times, t)
+		}
+	}
+	sort.Float64s(times)
+	return times
+}
+
+// nearestKeyframe returns the keyframe closest to t. Ties go to the earlier
+// keyframe, which is where a stream copy would cut anyway; times outside the
+// keyframe range snap to the first or last one. With no keyframes, t is
+// returned unchanged.
+func nearestKeyframe(keyframes []float64, t float64) float64 {
+	if len(keyframes) == 0 {
+		return t
+	}
+	i := sort.SearchFloat64s(keyframes, t)
+	if i == 0 {
+		return keyframes[0]
+	}
+	if i == len(keyframes) {
+		return keyframes[len(keyframes)-1]
+	}
+	before, after := keyframes[i-1], keyframes[i]
+	if after-t < t-before {
+		return after
+	}
+	return before
+}
+
+// snapSegmentsToKeyframes applies snap_keyframes, leaving the segments
+// alone when re-encoding (which cuts exactly) or when the keyframes can't
+// be read.
+func snapSegmentsToKeyframes(cfg Config, segments []segment) []segment {
+	if reencoding(cfg) {
+		log.Println("Skipping keyframe snapping, re-encoded songs are cut exactly.")
+		return segments
+	}
+	if !isFFprobeInstalled() {
+		log.Println("Warning: 'ffprobe' not found, can't snap song starts to keyframes.")
+		return segments
+	}
+	keyframes, err := probeKeyframes(cfg.InputFile)
+	if err != nil {
+		log.Printf("Warning: Could not read keyframes from '%s': %v. Keeping the detected boundaries.", cfg.InputFile, err)
+		return segments
+	}
+	if len(keyframes) == 0 {
+		log.Println("Warning: No video keyframes found in the input. Keeping the detected boundaries.")
+		return segments
+	}
+	return snapToKeyframes(segments, keyframes)
+}
+
+// snapToKeyframes moves each song's start to its nearest keyframe, so the
+// times in the report and tracklist are where a stream copy really cuts.
+// Only starts move: a copy can stop on any frame, and the export's -t is
+// worked out from the snapped start, so the end stays put.
+func snapToKeyframes(segments []segment, keyframes []float64) []segment {
+	snapped := make([]segment, 0, len(segments))
+	moved, largest := 0, 0.0
+	for _, seg := range segments {
+		if start := nearestKeyframe(keyframes, seg.start); start < seg.end && start != seg.start {
+			moved++
+			largest = math.Max(largest, math.Abs(start-seg.start))
+			seg.start = start
+		}
+		snapped = append(snapped, seg)
+	}
+	log.Printf("Snapped %d song start(s) to keyframes, the furthest by %.2fs.", moved, largest)
+	return snapped
+}
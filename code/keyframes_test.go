@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNearestKeyframe(t *testing.T) {
+	keyframes := []float64{0, 2, 4, 10}
+	testCases := []struct {
+		name string
+		t    float64
+		want float64
+	}{
+		{"Exact", 4, 4},
+		{"CloserToLater", 3.5, 4},
+		{"CloserToEarlier", 4.9, 4},
+		{"TieGoesEarlier", 7, 4},
+		{"BeforeFirst", -1, 0},
+		{"AfterLast", 12.5, 10},
+	}
+	for _, tc := range testCases {
+		if got := nearestKeyframe(keyframes, tc.t); got != tc.want {
+			t.Errorf("%s: nearestKeyframe(%v) = %v, want %v", tc.name, tc.t, got, tc.want)
+		}
+	}
+	if got := nearestKeyframe(nil, 3.3); got != 3.3 {
+		t.Errorf("Expected no keyframes to leave the time alone, got %v", got)
+	}
+}
+
+func TestSnapSegmentsToKeyframes(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if call.args[0] == "-version" {
+			return fakeResult{}
+		}
+		return fakeResult{stdout: "0.000000\n5.005000\nN/A\n10.010000\n250.250000,\n"}
+	})
+	segs := []segment{{start: 4, end: 200}, {start: 249, end: 400}}
+
+	got := snapSegmentsToKeyframes(Config{InputFile: "in.mp4"}, segs)
+
+	want := []segment{{start: 5.005, end: 200}, {start: 250.25, end: 400}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if n := len(fake.calls); n != 2 {
+		t.Errorf("Expected a version check and one keyframe probe, got %d calls", n)
+	}
+
+	fake.calls = nil
+	if got := snapSegmentsToKeyframes(Config{InputFile: "in.mp4", Reencode: true}, segs); !reflect.DeepEqual(got, segs) || len(fake.calls) != 0 {
+		t.Errorf("Expected re-encoding to skip snapping, got %v after %d calls", got, len(fake.calls))
+	}
+}
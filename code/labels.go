@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// audacityLabelsFile is the label track written into OutputDir.
+const audacityLabelsFile = "labels.txt"
+
+// writeAudacityLabels writes one Audacity label per song: start and end in
+// seconds and a label, tab-separated (File > Import > Labels in Audacity).
+// Songs are labelled from the setlist where it has a title, otherwise as
+// "<prefix> <n>".
+func writeAudacityLabels(path string, segments []segment, titles []string, prefix string) error {
+	var b strings.Builder
+	for i, seg := range segments {
+		fmt.Fprintf(&b, "%.6f\t%.6f\t%s\n", seg.start, seg.end, songLabel(i, titles, prefix))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// writeTracklist writes each song's start time and title, one per line, in
+// the "3:45 Song Two" form video sites turn into chapter links. Times are
+// from the start of the recording, except that the first song is listed at
+// 0:00: YouTube only links a tracklist that starts there. With absolute set
+// (preserve_absolute_timing) the first song keeps its real start too, to
+// match the songs' own timestamps.
+func writeTracklist(path string, segments []segment, titles []string, absolute bool) error {
+	var b strings.Builder
+	for i, seg := range segments {
+		start := seg.start
+		if i == 0 && !absolute {
+			start = 0
+		}
+		fmt.Fprintf(&b, "%s %s\n", formatTrackTime(start), songLabel(i, titles, "Song"))
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// formatTrackTime formats whole seconds as M:SS, or H:MM:SS from an hour in.
+func formatTrackTime(seconds float64) string {
+	total := int(seconds)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// songLabel names song i for a label track. Tabs and line breaks would
+// split the label's line, so they become spaces.
+func songLabel(i int, titles []string, prefix string) string {
+	label := fmt.Sprintf("%s %d", prefix, i+1)
+	if i < len(titles) {
+		label = titles[i]
+	}
+	return strings.Join(strings.Fields(label), " ")
+}
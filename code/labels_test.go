@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAudacityLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", audacityLabelsFile)
+	segs := []segment{{start: 12.5, end: 250}, {start: 260.25, end: 480.125}, {start: 500, end: 700}}
+
+	if err := writeAudacityLabels(path, segs, []string{"Opener", "Slow\tOne"}, "Song"); err != nil {
+		t.Fatalf("writeAudacityLabels failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "12.500000\t250.000000\tOpener\n" +
+		"260.250000\t480.125000\tSlow One\n" +
+		"500.000000\t700.000000\tSong 3\n"
+	if string(data) != want {
+		t.Errorf("Expected labels:\n%q\ngot:\n%q", want, data)
+	}
+}
+
+func TestWriteTracklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tracklist.txt")
+	segs := []segment{{start: 12.5, end: 225}, {start: 225.9, end: 3590}, {start: 3600, end: 3700}, {start: 4000.2, end: 4200}}
+
+	testCases := []struct {
+		absolute bool
+		first    string
+	}{
+		{false, "0:00"},
+		{true, "0:12"},
+	}
+	for _, tc := range testCases {
+		if err := writeTracklist(path, segs, []string{"Song One", "Song Two", "Song Three"}, tc.absolute); err != nil {
+			t.Fatalf("writeTracklist failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := tc.first + " Song One\n" +
+			"3:45 Song Two\n" +
+			"1:00:00 Song Three\n" +
+			"1:06:40 Song 4\n"
+		if string(data) != want {
+			t.Errorf("absolute %v: expected tracklist:\n%q\ngot:\n%q", tc.absolute, want, data)
+		}
+	}
+}
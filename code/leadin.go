@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+)
+
+// songEnergy is a detected segment with its sustained level: the median RMS
+// level of its windows, which a few loud hits in a stretch of noodling
+// don't raise the way they raise the mean or peak.
+type songEnergy struct {
+	segment
+	level float64 // dBFS
+}
+
+// findFirstRealSong returns the index of the first segment that is at least
+// min_song_length long and whose sustained level reaches lead_in_threshold,
+// or -1 if none does.
+func findFirstRealSong(cfg Config, segments []songEnergy) int {
+	threshold, err := parseDecibels(cfg.LeadInThreshold)
+	if err != nil {
+		return -1
+	}
+	for i, s := range segments {
+		if s.end-s.start >= cfg.MinSongLength && s.level >= threshold {
+			return i
+		}
+	}
+	return -1
+}
+
+// sustainedLevel runs an astats pass over seg and returns the median of its
+// RMS windows.
+func sustainedLevel(cfg Config, seg segment) (float64, error) {
+	output, _ := runFFmpeg(analysisLogLevel, append(analysisInput(cfg, &seg), "-af", rmsFilter(cfg), "-f", "null", "-")...)
+	levels := parseRMSLevels(output)
+	if len(levels) == 0 {
+		return 0, fmt.Errorf("no RMS levels measured for %.2fs - %.2fs", seg.start, seg.end)
+	}
+	values := make([]float64, len(levels))
+	for i, l := range levels {
+		values[i] = l.level
+	}
+	slices.Sort(values)
+	return values[len(values)/2], nil
+}
+
+// skipLeadIn drops the songs before the first one with sustained energy,
+// for skip_lead_in: the soundcheck and noodling a rehearsal starts with.
+// Songs are measured in order until one qualifies, so only the lead-in and
+// the first real song get the extra pass. It returns the songs to keep and
+// the ones dropped; if no song qualifies, or a level can't be measured,
+// nothing is dropped.
+func skipLeadIn(cfg Config, songs []segment) ([]segment, []segment) {
+	var measured []songEnergy
+	first := -1
+	for _, seg := range songs {
+		level, err := sustainedLevel(cfg, seg)
+		if err != nil {
+			log.Printf("Warning: Could not measure the lead-in, keeping every song: %v", err)
+			return songs, nil
+		}
+		measured = append(measured, songEnergy{segment: seg, level: level})
+		if first = findFirstRealSong(cfg, measured); first >= 0 {
+			break
+		}
+	}
+	switch {
+	case first < 0:
+		log.Printf("Warning: No song's sustained level reaches lead_in_threshold %s; keeping every song.", cfg.LeadInThreshold)
+		return songs, nil
+	case first > 0:
+		log.Printf("Skipping %d lead-in segment(s) before %.2fs, the first with a sustained level of %.1f dB.", first, songs[first].start, measured[first].level)
+	}
+	return songs[first:], songs[:first]
+}
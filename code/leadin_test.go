@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindFirstRealSong(t *testing.T) {
+	cfg := Config{MinSongLength: 120, LeadInThreshold: "-30dB"}
+	segments := []songEnergy{
+		{segment: segment{start: 0, end: 300}, level: -42},   // long soundcheck, too quiet
+		{segment: segment{start: 310, end: 360}, level: -18}, // loud but short
+		{segment: segment{start: 370, end: 600}, level: -22}, // the first song
+		{segment: segment{start: 610, end: 900}, level: -20},
+	}
+
+	if got := findFirstRealSong(cfg, segments); got != 2 {
+		t.Errorf("Expected segment 2, got %d", got)
+	}
+
+	cfg.LeadInThreshold = "-15dB"
+	if got := findFirstRealSong(cfg, segments); got != -1 {
+		t.Errorf("Expected no real song above -15dB, got %d", got)
+	}
+}
+
+// rmsOutput is ametadata's output for windows all at level.
+func rmsOutput(level float64, windows int) string {
+	var b strings.Builder
+	for i := 0; i < windows; i++ {
+		fmt.Fprintf(&b, "frame:%d pts:%d pts_time:%g\nlavfi.astats.Overall.RMS_level=%g\n", i, i*4000, float64(i)*rmsWindow, level)
+	}
+	return b.String()
+}
+
+func TestSkipLeadIn(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		level := -20.0
+		if i := slices.Index(call.args, "-ss"); i >= 0 && call.args[i+1] == "0.000" {
+			level = -45 // the soundcheck
+		}
+		return fakeResult{stderr: rmsOutput(level, 5)}
+	})
+	cfg := Config{InputFile: "practice.mp4", MinSongLength: 120, LeadInThreshold: "-30dB"}
+	songs := []segment{{start: 0, end: 240}, {start: 250, end: 500}, {start: 510, end: 800}}
+
+	kept, dropped := skipLeadIn(cfg, songs)
+
+	if want := songs[1:]; !reflect.DeepEqual(kept, want) {
+		t.Errorf("Expected %v kept, got %v", want, kept)
+	}
+	if want := songs[:1]; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("Expected %v dropped, got %v", want, dropped)
+	}
+	if len(fake.calls) != 2 {
+		t.Errorf("Expected measuring to stop at the first real song, got %d ffmpeg calls", len(fake.calls))
+	}
+}
+
+func TestSkipLeadInKeepsEverythingWithoutARealSong(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: rmsOutput(-50, 5)}
+	})
+	cfg := Config{InputFile: "practice.mp4", MinSongLength: 120, LeadInThreshold: "-30dB"}
+	songs := []segment{{start: 0, end: 240}, {start: 250, end: 500}}
+
+	if kept, dropped := skipLeadIn(cfg, songs); !reflect.DeepEqual(kept, songs) || dropped != nil {
+		t.Errorf("Expected every song kept, got %v (dropped %v)", kept, dropped)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lengthBucket is the width in seconds of each summarizeSegmentLengths
+// histogram bar.
+const lengthBucket = 60.0
+
+// candidateSegments returns every stretch between silences, before the
+// min_song_length filter. Nothing outside analysis_windows is a candidate.
+func candidateSegments(silences []segment, totalDuration float64, cfg Config) []segment {
+	allCfg := cfg
+	allCfg.MinSongLength = 0
+	return calculateNonSilentSegments(withUnanalyzed(cfg, boundarySilences(cfg, silences), totalDuration), totalDuration, allCfg)
+}
+
+// summarizeSegmentLengths describes how long the candidate segments are and
+// how many minLen would keep, to help pick min_song_length for a new kind of
+// recording. The histogram has one row per minute of length.
+func summarizeSegmentLengths(segments []segment, minLen float64) string {
+	if len(segments) == 0 {
+		return "No candidate segments."
+	}
+	lengths := make([]float64, len(segments))
+	kept := 0
+	for i, seg := range segments {
+		lengths[i] = seg.end - seg.start
+		if lengths[i] >= minLen {
+			kept++
+		}
+	}
+	sort.Float64s(lengths)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Candidate segments: %d (min %.1fs, median %.1fs, max %.1fs); %d kept, %d filtered at min_song_length %.1fs\n",
+		len(lengths), lengths[0], median(lengths), lengths[len(lengths)-1], kept, len(lengths)-kept, minLen)
+	counts := make([]int, int(lengths[len(lengths)-1]/lengthBucket)+1)
+	for _, l := range lengths {
+		counts[int(l/lengthBucket)]++
+	}
+	for i, n := range counts {
+		fmt.Fprintf(&b, "  %5s %3d %s\n", fmt.Sprintf("%d-%dm", i, i+1), n, strings.Repeat("#", n))
+	}
+	return b.String()
+}
+
+// median returns the middle of sorted values.
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
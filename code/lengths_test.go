@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestSummarizeSegmentLengths(t *testing.T) {
+	segs := []segment{
+		{start: 0, end: 20},    // 20s
+		{start: 30, end: 230},  // 200s
+		{start: 240, end: 275}, // 35s
+		{start: 300, end: 450}, // 150s
+	}
+
+	got := summarizeSegmentLengths(segs, 120)
+
+	want := "Candidate segments: 4 (min 20.0s, median 92.5s, max 200.0s); 2 kept, 2 filtered at min_song_length 120.0s\n" +
+		"   0-1m   2 ##\n" +
+		"   1-2m   0 \n" +
+		"   2-3m   1 #\n" +
+		"   3-4m   1 #\n"
+	if got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+	if got := summarizeSegmentLengths(nil, 120); got != "No candidate segments." {
+		t.Errorf("Unexpected summary for no segments: %q", got)
+	}
+}
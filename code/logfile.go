@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// logFilePath resolves LogFile. A bare file name ("run.log") goes in
+// OutputDir so the log travels with the songs when they're uploaded; with a
+// {count} OutputDir, which isn't named until detection has run, it goes in
+// the folder above. Paths with a folder ("./run.log") are used as given.
+func logFilePath(cfg Config) string {
+	if cfg.LogFile == "" || filepath.Base(cfg.LogFile) != cfg.LogFile {
+		return cfg.LogFile
+	}
+	dir := cfg.OutputDir
+	if strings.Contains(dir, "{count}") {
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Join(dir, cfg.LogFile)
+}
+
+// openLogFile tees the standard logger to path as well as stderr, appending
+// so reruns keep the earlier sessions. The returned func puts the logger
+// back and closes the file. Log writes aren't buffered, so an os.Exit before
+// it runs loses nothing.
+func openLogFile(path string) (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("cannot create log folder: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open log file: %v", err)
+	}
+	previous := log.Writer()
+	log.SetOutput(io.MultiWriter(previous, f))
+	return func() error {
+		log.SetOutput(previous)
+		return f.Close()
+	}, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestOpenLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "run.log")
+	flags := log.Flags()
+	log.SetFlags(log.LstdFlags)
+	t.Cleanup(func() { log.SetFlags(flags) })
+
+	closeLog, err := openLogFile(path)
+	if err != nil {
+		t.Fatalf("openLogFile failed: %v", err)
+	}
+	log.Printf("Exporting segment %d", 1)
+	newProgressReporter(1).logf("Exporting segment %d", 2)
+	if err := closeLog(); err != nil {
+		t.Fatalf("closing the log failed: %v", err)
+	}
+	log.Print("after close") // back on stderr only
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	timestamped := regexp.MustCompile(`^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d Exporting segment \d$`)
+	if len(lines) != 2 || !timestamped.MatchString(lines[0]) || !timestamped.MatchString(lines[1]) {
+		t.Errorf("Expected two timestamped log lines, got %q", data)
+	}
+}
+
+func TestLogFilePath(t *testing.T) {
+	testCases := []struct {
+		logFile, outputDir, expected string
+	}{
+		{"", "output", ""},
+		{"run.log", "output", filepath.Join("output", "run.log")},
+		{"run.log", "shows/{count}_songs", filepath.Join("shows", "run.log")},
+		{"./run.log", "output", "./run.log"},
+		{"/var/log/splitter.log", "output", "/var/log/splitter.log"},
+	}
+	for _, tc := range testCases {
+		if got := logFilePath(Config{LogFile: tc.logFile, OutputDir: tc.outputDir}); got != tc.expected {
+			t.Errorf("logFilePath(%q, %q): expected %q, got %q", tc.logFile, tc.outputDir, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// loudness is the summary of an ebur128 pass over one file.
+type loudness struct {
+	integrated float64 // LUFS
+	truePeak   float64 // dBFS
+}
+
+var (
+	integratedRe = regexp.MustCompile(`I:\s+(-?\d+\.?\d*) LUFS`)
+	truePeakRe   = regexp.MustCompile(`Peak:\s+(-?inf|-?\d+\.?\d*) dBFS`)
+)
+
+// measureLoudness runs ebur128 over a file. framelog=verbose keeps the
+// per-frame readings out of the info-level output, leaving the summary.
+func measureLoudness(file string) (loudness, error) {
+	output, err := runFFmpeg(analysisLogLevel, "-nostats", "-i", file, "-af", "ebur128=peak=true:framelog=verbose", "-f", "null", "-")
+	if err != nil {
+		return loudness{}, fmt.Errorf("%v: %s", err, output)
+	}
+	return parseLoudnessSummary(output)
+}
+
+// parseLoudnessSummary reads the integrated loudness and true peak from the
+// Summary block ebur128 prints at the end.
+func parseLoudnessSummary(output string) (loudness, error) {
+	i := strings.LastIndex(output, "Summary:")
+	if i < 0 {
+		return loudness{}, fmt.Errorf("no ebur128 summary in ffmpeg output")
+	}
+	summary := output[i:]
+	m := integratedRe.FindStringSubmatch(summary)
+	p := truePeakRe.FindStringSubmatch(summary)
+	if m == nil || p == nil {
+		return loudness{}, fmt.Errorf("could not parse ebur128 summary: %s", summary)
+	}
+	var l loudness
+	l.integrated, _ = strconv.ParseFloat(m[1], 64)
+	l.truePeak, _ = strconv.ParseFloat(p[1], 64)
+	return l, nil
+}
+
+// measureSegmentLoudness fills in the loudness of every exported segment.
+func measureSegmentLoudness(results []segmentResult) {
+	log.Println("Measuring loudness... This runs one extra pass per song.")
+	for i := range results {
+		if results[i].Status != statusExported {
+			continue
+		}
+		l, err := measureLoudness(results[i].File)
+		if err != nil {
+			log.Printf("Warning: Could not measure loudness of '%s': %v", results[i].File, err)
+			continue
+		}
+		results[i].LoudnessLUFS = &l.integrated
+		results[i].TruePeakDBFS = &l.truePeak
+	}
+}
+
+// loudnessTable formats the measured songs as a small aligned table, with
+// a gain column when ReplayGain tags were written.
+func loudnessTable(results []segmentResult) string {
+	gains := false
+	for _, r := range results {
+		gains = gains || r.ReplayGainDB != nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-4s  %10s  %10s  ", "Song", "Loudness", "True peak")
+	if gains {
+		fmt.Fprintf(&b, "%9s  ", "Gain")
+	}
+	b.WriteString("File\n")
+	for _, r := range results {
+		if r.LoudnessLUFS == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%-4d  %5.1f LUFS  %5.1f dBFS  ", r.Index, *r.LoudnessLUFS, *r.TruePeakDBFS)
+		if gains && r.ReplayGainDB != nil {
+			fmt.Fprintf(&b, "%+6.2f dB  ", *r.ReplayGainDB)
+		} else if gains {
+			fmt.Fprintf(&b, "%9s  ", "-")
+		}
+		b.WriteString(filepath.Base(r.File) + "\n")
+	}
+	return b.String()
+}
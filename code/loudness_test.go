@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleEBUR128 = `[Parsed_ebur128_0 @ 0x5581] Summary:
+
+  Integrated loudness:
+    I:         -19.5 LUFS
+    Threshold: -29.8 LUFS
+
+  Loudness range:
+    LRA:         5.3 LU
+    Threshold: -39.9 LUFS
+    LRA low:   -23.1 LUFS
+    LRA high:  -17.8 LUFS
+
+  True peak:
+    Peak:       -0.4 dBFS
+`
+
+func TestParseLoudnessSummary(t *testing.T) {
+	l, err := parseLoudnessSummary("t: 1.0 M: -20.1 S:-120.7 I: -30.0 LUFS\n" + sampleEBUR128)
+	if err != nil {
+		t.Fatalf("parseLoudnessSummary failed: %v", err)
+	}
+	if l.integrated != -19.5 || l.truePeak != -0.4 {
+		t.Errorf("Expected -19.5 LUFS / -0.4 dBFS, got %+v", l)
+	}
+	if _, err := parseLoudnessSummary("no summary here"); err == nil {
+		t.Error("Expected an error without a summary")
+	}
+}
+
+func TestMeasureSegmentLoudness(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: sampleEBUR128}
+	})
+	results := []segmentResult{
+		{Index: 1, File: "out/01 - Opener.mp4", Status: statusExported},
+		{Index: 2, File: "out/Song_02.mp4", Status: statusFailed},
+	}
+
+	measureSegmentLoudness(results)
+
+	if len(fake.calls) != 1 || !strings.Contains(strings.Join(fake.calls[0].args, " "), "-i out/01 - Opener.mp4 -af ebur128=peak=true") {
+		t.Fatalf("Expected one ebur128 pass over the exported song, got %+v", fake.calls)
+	}
+	if results[0].LoudnessLUFS == nil || *results[0].LoudnessLUFS != -19.5 || results[1].LoudnessLUFS != nil {
+		t.Errorf("Unexpected loudness results %+v", results)
+	}
+	if table := loudnessTable(results); !strings.Contains(table, "1     -19.5 LUFS   -0.4 dBFS  01 - Opener.mp4") {
+		t.Errorf("Unexpected loudness table:\n%s", table)
+	}
+}
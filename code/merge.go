@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseMergePair parses -merge's two 1-based song numbers ("3,4").
+func parseMergePair(s string) (int, int, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("-merge needs two song numbers, e.g. -merge 3,4")
+	}
+	var pair [2]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid song number '%s' for -merge", strings.TrimSpace(field))
+		}
+		pair[i] = n
+	}
+	return pair[0], pair[1], nil
+}
+
+// mergeAdjacent joins songs a and b, by 1-based number, into one song from
+// a's start to b's end, for a song detection split at a long quiet bridge.
+// b must come right after a; the songs after it move up one number.
+func mergeAdjacent(segments []segment, a, b int) ([]segment, error) {
+	for _, n := range []int{a, b} {
+		if n < 1 || n > len(segments) {
+			return segments, fmt.Errorf("-merge: no song %d (songs are numbered 1-%d)", n, len(segments))
+		}
+	}
+	if b != a+1 {
+		return segments, fmt.Errorf("-merge: songs %d and %d aren't adjacent; give a song and the one right after it", a, b)
+	}
+	return mergeSegments(segments, a-1)
+}
+
+// removeSupersededSongs deletes the song files an earlier run wrote to dir,
+// before -merge exports every song again under its new number. Left alone,
+// the last one would linger as a stale extra song and the rest would push
+// the new files to "(2)" names when renamed from the setlist. Files outside
+// dir are never touched.
+func removeSupersededSongs(dir string, files []string) {
+	removed := 0
+	for _, file := range files {
+		if !containsFile(dir, file) || containsFile(file, dir) {
+			continue
+		}
+		if err := os.Remove(file); err == nil {
+			removed++
+		} else if !os.IsNotExist(err) {
+			log.Printf("Warning: Could not remove superseded song '%s': %v", file, err)
+		}
+	}
+	if removed > 0 {
+		log.Printf("Removed %d song file(s) from the earlier run that -merge replaces.", removed)
+	}
+}
+
+// mergeTitles drops song b's title after mergeAdjacent, so the titles still
+// line up with the songs. The merged song keeps a's title, or b's if a had
+// none.
+func mergeTitles(titles []string, a, b int) []string {
+	if b > len(titles) {
+		return titles
+	}
+	merged := append([]string(nil), titles[:b-1]...)
+	if strings.TrimSpace(merged[a-1]) == "" {
+		merged[a-1] = titles[b-1]
+	}
+	return append(merged, titles[b:]...)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeAdjacent(t *testing.T) {
+	segments := []segment{{0, 200}, {210, 400}, {410, 530}, {545, 700}, {710, 900}}
+
+	merged, err := mergeAdjacent(segments, 3, 4)
+
+	if err != nil {
+		t.Fatalf("mergeAdjacent failed: %v", err)
+	}
+	want := []segment{{0, 200}, {210, 400}, {410, 700}, {710, 900}}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("Expected %v, got %v", want, merged)
+	}
+	if len(segments) != 5 {
+		t.Errorf("Expected the input to be left alone, got %v", segments)
+	}
+}
+
+func TestMergeAdjacentRejects(t *testing.T) {
+	segments := []segment{{0, 200}, {210, 400}, {410, 530}, {545, 700}}
+	for _, pair := range [][2]int{{3, 5}, {4, 3}, {2, 2}, {0, 1}, {4, 5}} {
+		got, err := mergeAdjacent(segments, pair[0], pair[1])
+		if err == nil {
+			t.Errorf("-merge %d,%d: expected an error, got %v", pair[0], pair[1], got)
+		}
+		if !reflect.DeepEqual(got, segments) {
+			t.Errorf("-merge %d,%d: expected the songs unchanged, got %v", pair[0], pair[1], got)
+		}
+	}
+}
+
+func TestParseMergePair(t *testing.T) {
+	if a, b, err := parseMergePair(" 3, 4"); err != nil || a != 3 || b != 4 {
+		t.Errorf("Expected 3 and 4, got %d, %d (err %v)", a, b, err)
+	}
+	for _, bad := range []string{"3", "3,4,5", "three,4", ""} {
+		if _, _, err := parseMergePair(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestMergeTitles(t *testing.T) {
+	testCases := []struct {
+		titles []string
+		want   []string
+	}{
+		{[]string{"Reba", "Sabotage", "Sabotage (reprise)", "Kid Charlemagne"}, []string{"Reba", "Sabotage", "Kid Charlemagne"}},
+		{[]string{"Reba", "", "Sabotage", "Kid Charlemagne"}, []string{"Reba", "Sabotage", "Kid Charlemagne"}},
+		{[]string{"Reba", "Sabotage"}, []string{"Reba", "Sabotage"}},
+		{nil, nil},
+	}
+	for _, tc := range testCases {
+		if got := mergeTitles(tc.titles, 2, 3); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("mergeTitles(%q, 2, 3): expected %q, got %q", tc.titles, tc.want, got)
+		}
+	}
+}
+
+func TestMergeReplacesManifestSongs(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "practice.mp4")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// An earlier run split one song at its quiet bridge.
+	earlier := &runReport{}
+	for i, seg := range []segment{{0, 100}, {110, 200}, {205, 300}} {
+		file := filepath.Join(out, fmt.Sprintf("Song_%02d.mp4", i+1))
+		if err := os.WriteFile(file, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		earlier.Segments = append(earlier.Segments, segmentResult{Index: i + 1, Start: seg.start, End: seg.end, File: file, Status: statusExported})
+	}
+	manifest := filepath.Join(dir, "run.json")
+	if err := writeReport(manifest, earlier); err != nil {
+		t.Fatal(err)
+	}
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "-t "):
+			os.WriteFile(call.args[len(call.args)-1], []byte("new"), 0644)
+		case strings.HasSuffix(args, "-i "+input):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{InputFile: input, OutputDir: out, OutputPrefix: "Song"}
+	cfg.ManifestPath, cfg.MergeSongs = manifest, "2,3"
+
+	if err := NewSplitter(cfg).Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"Song_01.mp4", "Song_02.mp4"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected only the merged run's songs %q, got %q", want, names)
+	}
+}
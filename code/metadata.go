@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// metadataFields are the values a metadata template can use, e.g.
+// {"album": "{{.Album}} {{.Date}}", "track": "{{.Track}}/{{.Tracks}}"}.
+type metadataFields struct {
+	Title  string // setlist title, or "Song 3" without one
+	Track  int    // 1-based song number
+	Tracks int    // songs in the recording
+	Artist string // artist from the config
+	Album  string // album from the config
+	Date   string // recording date (the input's modification date), YYYY-MM-DD
+	Input  string // input file name without its extension
+}
+
+// parseMetadataTemplates parses each tag's template and renders it once with
+// sample values, so a typo such as {{.Abum}} is caught before any export.
+func parseMetadataTemplates(templates map[string]string) (map[string]*template.Template, error) {
+	parsed := make(map[string]*template.Template, len(templates))
+	for tag, text := range templates {
+		if strings.TrimSpace(tag) == "" || strings.ContainsAny(tag, "= ") {
+			return nil, fmt.Errorf("invalid metadata_templates tag name '%s'", tag)
+		}
+		tmpl, err := template.New(tag).Option("missingkey=error").Parse(text)
+		if err == nil {
+			err = tmpl.Execute(&strings.Builder{}, metadataFields{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata_templates entry '%s': %v", tag, err)
+		}
+		parsed[tag] = tmpl
+	}
+	return parsed, nil
+}
+
+// songMetadata returns the template values for song i of total.
+func songMetadata(cfg Config, i, total int, titles []string) metadataFields {
+	fields := metadataFields{
+		Title:  songLabel(i, titles, cfg.OutputPrefix),
+		Track:  i + 1,
+		Tracks: total,
+		Artist: cfg.Artist,
+		Album:  cfg.Album,
+		Input:  strings.TrimSuffix(filepath.Base(cfg.InputFile), filepath.Ext(cfg.InputFile)),
+	}
+	if info, err := os.Stat(cfg.InputFile); err == nil {
+		fields.Date = info.ModTime().Format("2006-01-02")
+	}
+	return fields
+}
+
+// metadataArgs renders each template for one song into ffmpeg -metadata
+// args, in tag order so the args (and -cache fingerprints) are stable.
+func metadataArgs(templates map[string]*template.Template, fields metadataFields) []string {
+	var args []string
+	for _, tag := range slices.Sorted(maps.Keys(templates)) {
+		var value strings.Builder
+		if err := templates[tag].Execute(&value, fields); err != nil {
+			continue // parseMetadataTemplates already rendered it once
+		}
+		args = append(args, "-metadata", tag+"="+value.String())
+	}
+	return args
+}
+
+// titleMetadataArgs tags song i with its setlist title for
+// embed_title_metadata. Songs without a title get no tag, and a "title" in
+// metadata_templates takes precedence.
+func titleMetadataArgs(cfg Config, i int, titles []string, templates map[string]*template.Template) []string {
+	if !cfg.EmbedTitleMetadata || i >= len(titles) || strings.TrimSpace(titles[i]) == "" {
+		return nil
+	}
+	if _, ok := templates["title"]; ok {
+		return nil
+	}
+	return []string{"-metadata", "title=" + strings.TrimSpace(titles[i])}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetadataArgs(t *testing.T) {
+	templates, err := parseMetadataTemplates(map[string]string{
+		"album":   "{{.Album}} {{.Date}}",
+		"artist":  "{{.Artist}}",
+		"comment": "Rehearsal {{.Date}} ({{.Input}})",
+		"title":   "{{.Title}}",
+		"track":   "{{.Track}}/{{.Tracks}}",
+	})
+	if err != nil {
+		t.Fatalf("parseMetadataTemplates failed: %v", err)
+	}
+	input := filepath.Join(t.TempDir(), "tuesday.mp4")
+	if err := os.WriteFile(input, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	recorded := time.Date(2026, 3, 14, 20, 0, 0, 0, time.Local)
+	if err := os.Chtimes(input, recorded, recorded); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{InputFile: input, OutputPrefix: "Song", Artist: "The Knees", Album: "Rehearsals"}
+
+	got := metadataArgs(templates, songMetadata(cfg, 1, 3, []string{"Reba", "Tom; Jerry"}))
+	want := []string{
+		"-metadata", "album=Rehearsals 2026-03-14",
+		"-metadata", "artist=The Knees",
+		"-metadata", "comment=Rehearsal 2026-03-14 (tuesday)",
+		"-metadata", "title=Tom; Jerry",
+		"-metadata", "track=2/3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Past the setlist, the title falls back to the song's label.
+	got = metadataArgs(templates, songMetadata(cfg, 2, 3, []string{"Reba"}))
+	if !reflect.DeepEqual(got[6:8], []string{"-metadata", "title=Song 3"}) {
+		t.Errorf("Expected the label as title, got %q", got)
+	}
+}
+
+func TestParseMetadataTemplatesRejectsBadTemplates(t *testing.T) {
+	for _, templates := range []map[string]string{
+		{"album": "{{.Album"},    // syntax error
+		{"album": "{{.Abum}}"},   // unknown field
+		{"": "{{.Title}}"},       // no tag name
+		{"my tag": "{{.Title}}"}, // breaks -metadata key=value
+	} {
+		if _, err := parseMetadataTemplates(templates); err == nil {
+			t.Errorf("Expected an error for %q", templates)
+		}
+	}
+}
+
+func TestConfigLoadingRejectsBadMetadataTemplates(t *testing.T) {
+	resetFlags()
+	defineFlags()
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"metadata_templates": {"album": "{{.Abum}}"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := flag.CommandLine.Parse([]string{"-config=" + configFile}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	if _, _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "metadata_templates entry 'album'") {
+		t.Errorf("Expected a metadata_templates error, got %v", err)
+	}
+}
+
+func TestExportPassesMetadataArgs(t *testing.T) {
+	var exports [][]string
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		exports = append(exports, call.args)
+		return fakeResult{}
+	})
+	cfg := Config{
+		InputFile: "practice.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", Artist: "The Knees",
+		MetadataTemplates: map[string]string{"artist": "{{.Artist}}", "title": "{{.Track}}. {{.Title}}"},
+	}
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}}
+	opts := exportOptions{titles: []string{"Reba"}, extraArgs: map[int][]string{1: {"-ac", "1"}}}
+
+	splitVideoIntoSegments(cfg, segments, opts)
+
+	if len(exports) != 2 {
+		t.Fatalf("Expected 2 exports, got %d", len(exports))
+	}
+	for i, want := range [][]string{
+		{"-metadata", "artist=The Knees", "-metadata", "title=1. Reba"},
+		{"-metadata", "artist=The Knees", "-metadata", "title=2. Song 2", "-ac", "1"},
+	} {
+		args := exports[i]
+		tail := args[len(args)-len(want)-1 : len(args)-1] // just before the output file
+		if !reflect.DeepEqual(tail, want) {
+			t.Errorf("Segment %d: expected %q before the output, got %q", i+1, want, args)
+		}
+	}
+}
+
+func TestExportEmbedsTitleMetadata(t *testing.T) {
+	var exports [][]string
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		exports = append(exports, call.args)
+		return fakeResult{}
+	})
+	cfg := Config{InputFile: "practice.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", EmbedTitleMetadata: true}
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}}
+
+	splitVideoIntoSegments(cfg, segments, exportOptions{titles: []string{"Reba"}})
+
+	if len(exports) != 2 {
+		t.Fatalf("Expected 2 exports, got %d", len(exports))
+	}
+	if args := strings.Join(exports[0], " "); !strings.Contains(args, "-metadata title=Reba ") {
+		t.Errorf("Expected the title tag for segment 1, got %q", args)
+	}
+	if slices.Contains(exports[1], "-metadata") {
+		t.Errorf("Expected no title tag for a song without a title, got %q", exports[1])
+	}
+
+	templates, _ := parseMetadataTemplates(map[string]string{"title": "{{.Track}}. {{.Title}}"})
+	if args := titleMetadataArgs(cfg, 0, []string{"Reba"}, templates); args != nil {
+		t.Errorf("Expected a title template to take precedence, got %q", args)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+// defaultCandidateThresholds are the thresholds multi_threshold tries when
+// candidate_thresholds isn't set.
+var defaultCandidateThresholds = []string{"-30dB", "-24dB", "-18dB", "-12dB"}
+
+// candidateThresholds returns the thresholds multi_threshold tries.
+func candidateThresholds(cfg Config) []string {
+	if len(cfg.CandidateThresholds) > 0 {
+		return cfg.CandidateThresholds
+	}
+	return defaultCandidateThresholds
+}
+
+// scoreSegmentation rates how plausible a set of songs is, from 0 (no
+// songs) to 1. Two things count, multiplied together: the song count, which
+// should be in the expected range (or, with nothing to aim for, more than
+// one song), losing score the further out it is; and how even the song
+// lengths are, since a threshold that's too high chops songs into fragments
+// of every size. Evenness is 1/(1+cv), cv being the coefficient of
+// variation of the lengths.
+func scoreSegmentation(segments []segment, cfg Config) float64 {
+	if len(segments) == 0 {
+		return 0
+	}
+	count := len(segments)
+	lo, hi, ok := expectedSongRange(cfg)
+	if !ok {
+		lo, hi = 2, count
+	}
+	countScore := 1 / float64(1+max(lo-count, count-hi, 0))
+
+	mean := segmentsLength(segments) / float64(count)
+	var variance float64
+	for _, s := range segments {
+		d := s.end - s.start - mean
+		variance += d * d
+	}
+	cv := math.Sqrt(variance/float64(count)) / mean
+	return countScore / (1 + cv)
+}
+
+// multiThreshold runs detection at each candidate threshold and returns the
+// threshold whose songs score best, along with its silences. Ties go to the
+// earlier candidate.
+func multiThreshold(cfg Config, totalDuration float64) (string, []segment, error) {
+	return pickThreshold(cfg, totalDuration, detectSilence)
+}
+
+// pickThreshold is multiThreshold with the detection pass passed in.
+func pickThreshold(cfg Config, totalDuration float64, detect func(Config) []segment) (string, []segment, error) {
+	bestThreshold, bestScore := cfg.SilenceThreshold, -1.0
+	var bestSilences []segment
+	for _, threshold := range candidateThresholds(cfg) {
+		try := cfg
+		try.SilenceThreshold = threshold
+		silences := detect(try)
+		songs := songsFromSilences(try, silences, totalDuration)
+		score := scoreSegmentation(songs, try)
+		log.Printf("Multi-threshold: %s gives %d song(s), score %.2f.", threshold, len(songs), score)
+		if score > bestScore {
+			bestThreshold, bestSilences, bestScore = threshold, silences, score
+		}
+	}
+	if bestScore <= 0 {
+		return bestThreshold, bestSilences, fmt.Errorf("multi_threshold found no songs at any candidate threshold; using %s", bestThreshold)
+	}
+	return bestThreshold, bestSilences, nil
+}
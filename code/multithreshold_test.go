@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestScoreSegmentation(t *testing.T) {
+	even := []segment{{0, 240}, {250, 480}, {490, 730}, {740, 970}}
+	fragmented := []segment{{0, 240}, {250, 280}, {290, 480}, {490, 510}, {520, 730}, {740, 760}, {770, 970}}
+	oneFile := []segment{{0, 970}}
+	cfg := Config{ExpectedSongs: 4}
+
+	if got := scoreSegmentation(nil, cfg); got != 0 {
+		t.Errorf("Expected no songs to score 0, got %.2f", got)
+	}
+	if got := scoreSegmentation(even, cfg); got < 0.9 {
+		t.Errorf("Expected even songs in range to score near 1, got %.2f", got)
+	}
+	for name, cfg := range map[string]Config{"expected_songs": cfg, "nothing to aim for": {}} {
+		evenScore := scoreSegmentation(even, cfg)
+		if got := scoreSegmentation(fragmented, cfg); got >= evenScore {
+			t.Errorf("%s: expected fragmented songs (%.2f) to score below even ones (%.2f)", name, got, evenScore)
+		}
+		if got := scoreSegmentation(oneFile, cfg); got >= evenScore {
+			t.Errorf("%s: expected one whole file (%.2f) to score below even songs (%.2f)", name, got, evenScore)
+		}
+	}
+}
+
+func TestPickThreshold(t *testing.T) {
+	// Quiet candidates find no gaps, the loudest chops the songs up.
+	silencesAt := map[string][]segment{
+		"-30dB": nil,
+		"-24dB": {{240, 250}, {480, 490}, {730, 740}},
+		"-18dB": {{240, 250}, {280, 290}, {480, 490}, {510, 520}, {730, 740}, {760, 770}},
+	}
+	var tried []string
+	detect := func(cfg Config) []segment {
+		tried = append(tried, cfg.SilenceThreshold)
+		return silencesAt[cfg.SilenceThreshold]
+	}
+	cfg := Config{SilenceThreshold: "-30dB", MinSongLength: 10, CandidateThresholds: []string{"-30dB", "-24dB", "-18dB"}}
+
+	threshold, silences, err := pickThreshold(cfg, 970, detect)
+
+	if err != nil {
+		t.Fatalf("pickThreshold failed: %v", err)
+	}
+	if len(tried) != 3 {
+		t.Errorf("Expected every candidate tried, got %q", tried)
+	}
+	if threshold != "-24dB" || len(silences) != 3 {
+		t.Errorf("Expected -24dB and its 3 silences, got %s and %v", threshold, silences)
+	}
+}
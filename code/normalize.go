@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// mediaExtensions are the files -normalize-filenames renames; anything
+// else in the folder is left alone.
+var mediaExtensions = map[string]bool{
+	".mp4": true, ".mkv": true, ".mov": true, ".avi": true, ".webm": true,
+	".m4a": true, ".mp3": true, ".wav": true, ".flac": true, ".aac": true, ".ogg": true,
+}
+
+// Orderings for -by.
+const (
+	orderByName  = "name"
+	orderByMtime = "mtime"
+)
+
+// normalizeFilenames renames the media files in dir to "NN - Title" from a
+// setlist, as if this tool had exported them with the given session name.
+// Files are matched to titles in order of name or modification time.
+func normalizeFilenames(dir, setlistPath, by, session string) error {
+	if setlistPath == "" {
+		return errors.New("-normalize-filenames needs a setlist (-setlist or setlist_file)")
+	}
+	files, err := listMediaFiles(dir, by)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no media files found in '%s'", dir)
+	}
+	songList, err := loadSetlist(setlistPath)
+	if err != nil {
+		return fmt.Errorf("could not read setlist file '%s': %v", setlistPath, err)
+	}
+	renameFilesFromSetlist(files, songList.titles, session)
+	return nil
+}
+
+// listMediaFiles returns the media files directly inside dir, sorted by name
+// or by modification time (oldest first, ties broken by name).
+func listMediaFiles(dir, by string) ([]string, error) {
+	if by != orderByName && by != orderByMtime {
+		return nil, fmt.Errorf("unknown ordering '%s', want '%s' or '%s'", by, orderByName, orderByMtime)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type mediaFile struct {
+		path    string
+		modTime int64
+	}
+	var media []mediaFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !mediaExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, mediaFile{path: filepath.Join(dir, e.Name()), modTime: info.ModTime().UnixNano()})
+	}
+	sort.SliceStable(media, func(i, j int) bool {
+		if by == orderByMtime && media[i].modTime != media[j].modTime {
+			return media[i].modTime < media[j].modTime
+		}
+		return media[i].path < media[j].path
+	})
+	paths := make([]string, len(media))
+	for i, m := range media {
+		paths[i] = m.path
+	}
+	return paths, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeFilenames(t *testing.T) {
+	dir := t.TempDir()
+	// Name order is a, b; modification order is b, a.
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"b_take.mov", "a_take.mov", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		stamp := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setlistPath := filepath.Join(t.TempDir(), "setlist.txt")
+	if err := os.WriteFile(setlistPath, []byte("Opener\nCloser\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := normalizeFilenames(dir, setlistPath, orderByMtime, ""); err != nil {
+		t.Fatalf("normalizeFilenames failed: %v", err)
+	}
+
+	for name, content := range map[string]string{
+		"01 - Opener.mov": "b_take.mov",
+		"02 - Closer.mov": "a_take.mov",
+		"notes.txt":       "notes.txt",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != content {
+			t.Errorf("Expected %s to hold %s, got %q (%v)", name, content, data, err)
+		}
+	}
+}
+
+func TestListMediaFilesByName(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"c.MP4", "a.wav", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := listMediaFiles(dir, orderByName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "a.wav"), filepath.Join(dir, "c.MP4")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if _, err := listMediaFiles(dir, "size"); err == nil {
+		t.Error("Expected an unknown ordering to be rejected")
+	}
+}
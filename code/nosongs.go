@@ -0,0 +1,38 @@
+package main
+
+import "log"
+
+// OnNoSongs values: what to export when no candidate is min_song_length long.
+const (
+	onNoSongsSkip    = "skip"    // export nothing
+	onNoSongsLongest = "longest" // export the longest candidate
+	onNoSongsAll     = "all"     // export every candidate
+)
+
+// noSongsFallback applies on_no_songs when detection found candidates but
+// none long enough to be a song, returning the songs to export instead. A
+// recording with no silence at all is one candidate.
+func noSongsFallback(cfg Config, songs, silences []segment, totalDuration float64) []segment {
+	if len(songs) > 0 || cfg.OnNoSongs == onNoSongsSkip || cfg.OnNoSongs == "" {
+		return songs
+	}
+	candidates := candidateSegments(silences, totalDuration, cfg)
+	if len(candidates) == 0 && len(silences) == 0 && totalDuration > 0 {
+		candidates = []segment{{start: 0, end: totalDuration}}
+	}
+	if len(candidates) == 0 {
+		return songs
+	}
+	if cfg.OnNoSongs == onNoSongsAll {
+		log.Printf("Warning: No segment is at least %.1fs long; exporting all %d candidate(s) anyway (on_no_songs: all).", cfg.MinSongLength, len(candidates))
+		return candidates
+	}
+	longest := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.end-c.start > longest.end-longest.start {
+			longest = c
+		}
+	}
+	log.Printf("Warning: No segment is at least %.1fs long; exporting the longest, %.1fs at %s (on_no_songs: longest).", cfg.MinSongLength, longest.end-longest.start, formatTrackTime(longest.start))
+	return []segment{longest}
+}
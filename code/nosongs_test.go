@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNoSongsFallback(t *testing.T) {
+	// Candidates of 50s, 80s and 30s, all under min_song_length.
+	silences := []segment{{50, 60}, {140, 150}}
+	testCases := []struct {
+		mode string
+		want []segment
+	}{
+		{onNoSongsSkip, []segment{}},
+		{onNoSongsLongest, []segment{{60, 140}}},
+		{onNoSongsAll, []segment{{0, 50}, {60, 140}, {150, 180}}},
+	}
+	for _, tc := range testCases {
+		cfg := defaultConfig
+		cfg.MinSongLength = 120
+		cfg.OnNoSongs = tc.mode
+		songs := songsFromSilences(cfg, silences, 180)
+		if got := noSongsFallback(cfg, songs, silences, 180); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.mode, tc.want, got)
+		}
+	}
+}
+
+func TestNoSongsFallbackKeepsSongs(t *testing.T) {
+	cfg := defaultConfig
+	cfg.OnNoSongs = onNoSongsAll
+	songs := []segment{{0, 300}}
+	if got := noSongsFallback(cfg, songs, []segment{{300, 310}, {320, 330}}, 330); !reflect.DeepEqual(got, songs) {
+		t.Errorf("Expected the detected songs to be kept, got %v", got)
+	}
+}
+
+func TestNoSongsFallbackNoSilence(t *testing.T) {
+	cfg := defaultConfig
+	cfg.MinSongLength = 120
+	cfg.OnNoSongs = onNoSongsLongest
+	if got := noSongsFallback(cfg, nil, nil, 45); !reflect.DeepEqual(got, []segment{{0, 45}}) {
+		t.Errorf("Expected the whole short recording, got %v", got)
+	}
+}
+
+func TestRecordSkippedLeavesOutFallbackSongs(t *testing.T) {
+	cfg := defaultConfig
+	cfg.MinSongLength = 120
+	rep := &runReport{}
+
+	rep.recordSkipped([]segment{{50, 60}, {140, 150}}, []segment{{60, 140}}, 180, cfg)
+
+	if len(rep.Skipped) != 2 || rep.Skipped[0].End != 50 || rep.Skipped[1].Start != 150 {
+		t.Errorf("Expected the two candidates not exported to be skipped, got %+v", rep.Skipped)
+	}
+}
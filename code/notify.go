@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// notifyTimeout bounds the webhook call so a dead endpoint can't hang the
+// end of a run.
+const notifyTimeout = 10 * time.Second
+
+// notification summarizes a finished run for NotifyWebhook.
+type notification struct {
+	Input           string  `json:"input"`
+	Segments        int     `json:"segments"`
+	Success         bool    `json:"success"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// newNotification builds the notification for a finished run.
+func newNotification(rep *runReport) notification {
+	n := notification{
+		Input:           filepath.Base(rep.Config.InputFile),
+		Segments:        len(exportedPaths(rep.Segments)),
+		Success:         rep.Success,
+		DurationSeconds: rep.TotalSeconds,
+		Error:           rep.Error,
+	}
+	if n.Error == "" && rep.FailedSegments > 0 {
+		n.Error = fmt.Sprintf("%d segment(s) failed to export", rep.FailedSegments)
+	}
+	return n
+}
+
+// buildNotifyPayload renders n as the JSON body for the given format:
+// "slack" for a Slack-compatible {"text": ...} message, anything else for
+// the generic notification object.
+func buildNotifyPayload(format string, n notification) ([]byte, error) {
+	if format != "slack" {
+		return json.Marshal(n)
+	}
+	text := fmt.Sprintf("Split of '%s' finished: %d song(s) exported in %.0fs.", n.Input, n.Segments, n.DurationSeconds)
+	if !n.Success {
+		text = fmt.Sprintf("Split of '%s' failed after %.0fs: %s", n.Input, n.DurationSeconds, n.Error)
+	}
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// sendNotification POSTs the run summary to url.
+func sendNotification(url, format string, n notification) error {
+	payload, err := buildNotifyPayload(format, n)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendNotification(t *testing.T) {
+	var body []byte
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	n := notification{Input: "practice.mp4", Segments: 12, Success: true, DurationSeconds: 93.5}
+
+	t.Run("JSON", func(t *testing.T) {
+		if err := sendNotification(server.URL, "json", n); err != nil {
+			t.Fatalf("sendNotification failed: %v", err)
+		}
+		if contentType != "application/json" {
+			t.Errorf("Expected application/json, got %q", contentType)
+		}
+		var got notification
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("Payload is not valid JSON: %v (%s)", err, body)
+		}
+		if got != n {
+			t.Errorf("Expected payload %+v, got %+v", n, got)
+		}
+	})
+
+	t.Run("Slack", func(t *testing.T) {
+		if err := sendNotification(server.URL, "slack", n); err != nil {
+			t.Fatalf("sendNotification failed: %v", err)
+		}
+		var got map[string]string
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("Payload is not valid JSON: %v (%s)", err, body)
+		}
+		if !strings.Contains(got["text"], "'practice.mp4'") || !strings.Contains(got["text"], "12 song(s)") {
+			t.Errorf("Expected Slack text to mention the input and song count, got %q", got["text"])
+		}
+	})
+}
+
+func TestSendNotificationFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := sendNotification(server.URL, "json", notification{}); err == nil {
+		t.Error("Expected an error for a non-2xx webhook response")
+	}
+}
+
+func TestNewNotification(t *testing.T) {
+	rep := newRunReport(Config{InputFile: "/videos/practice.mp4"})
+	rep.Segments = []segmentResult{{Status: statusExported}, {Status: statusFailed}, {Status: statusExported}}
+	rep.finish(nil)
+
+	n := newNotification(rep)
+	if n.Input != "practice.mp4" || n.Segments != 2 || n.Success || n.Error != "1 segment(s) failed to export" {
+		t.Errorf("Expected practice.mp4 with 2 exported songs and 1 failure, got %+v", n)
+	}
+}
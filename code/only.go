@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// parseSegmentIndices parses -only's comma-separated, 1-based song numbers
+// ("3,7").
+func parseSegmentIndices(s string) ([]int, error) {
+	var indices []int
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid song number '%s' for -only", field)
+		}
+		indices = append(indices, n)
+	}
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("-only needs at least one song number, e.g. -only 3,7")
+	}
+	return indices, nil
+}
+
+// checkSegmentIndices reports every song number that isn't one of the count
+// songs found.
+func checkSegmentIndices(indices []int, count int) error {
+	var unknown []string
+	for _, n := range indices {
+		if n < 1 || n > count {
+			unknown = append(unknown, strconv.Itoa(n))
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("-only: no song %s (songs are numbered 1-%d)", strings.Join(unknown, ", "), count)
+	}
+	return nil
+}
+
+// filterSegmentsByIndex returns the segments picked by their 1-based
+// numbers, each once and in recording order. Numbers out of range are left
+// out; checkSegmentIndices reports them. No indices means every segment.
+func filterSegmentsByIndex(segments []segment, indices []int) []segment {
+	if indices == nil {
+		return segments
+	}
+	var picked []segment
+	for i, seg := range segments {
+		if slices.Contains(indices, i+1) {
+			picked = append(picked, seg)
+		}
+	}
+	return picked
+}
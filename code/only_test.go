@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterSegmentsByIndex(t *testing.T) {
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}, {start: 210, end: 300}, {start: 310, end: 400}}
+	testCases := []struct {
+		name     string
+		indices  []int
+		expected []segment
+	}{
+		{"Valid", []int{3, 1}, []segment{{start: 0, end: 100}, {start: 210, end: 300}}},
+		{"OutOfRange", []int{0, 2, 9}, []segment{{start: 110, end: 200}}},
+		{"Duplicates", []int{4, 4, 2, 4}, []segment{{start: 110, end: 200}, {start: 310, end: 400}}},
+		{"All", nil, segments},
+	}
+	for _, tc := range testCases {
+		if got := filterSegmentsByIndex(segments, tc.indices); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestParseSegmentIndices(t *testing.T) {
+	got, err := parseSegmentIndices(" 3, 7,")
+	if err != nil || !reflect.DeepEqual(got, []int{3, 7}) {
+		t.Errorf("Expected [3 7], got %v (%v)", got, err)
+	}
+	for _, bad := range []string{"", ",", "3,seven", "3-5"} {
+		if _, err := parseSegmentIndices(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestCheckSegmentIndices(t *testing.T) {
+	if err := checkSegmentIndices([]int{1, 8, 8}, 8); err != nil {
+		t.Errorf("Expected songs 1 and 8 of 8 to be fine, got %v", err)
+	}
+	err := checkSegmentIndices([]int{0, 3, 9}, 8)
+	if err == nil || !strings.Contains(err.Error(), "no song 0, 9 (songs are numbered 1-8)") {
+		t.Errorf("Expected the unknown songs to be listed, got %v", err)
+	}
+}
+
+func TestExportOnlyKeepsSongNumbers(t *testing.T) {
+	var outputs []string
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		out := call.args[len(call.args)-1]
+		outputs = append(outputs, out)
+		os.WriteFile(out, nil, 0644)
+		return fakeResult{}
+	})
+	dir := t.TempDir()
+	cfg := Config{InputFile: "practice.mp4", OutputDir: dir, OutputPrefix: "Song"}
+	segments := []segment{{start: 0, end: 100}, {start: 110, end: 200}, {start: 210, end: 300}, {start: 310, end: 400}}
+	titles := []string{"Reba", "Sabotage", "Kid Charlemagne", "Aja"}
+
+	results := splitVideoIntoSegments(cfg, segments, exportOptions{only: []int{3, 2}, titles: titles})
+
+	expected := []string{filepath.Join(dir, "Song_02.mp4"), filepath.Join(dir, "Song_03.mp4")}
+	if !reflect.DeepEqual(outputs, expected) {
+		t.Fatalf("Expected exports %q, got %q", expected, outputs)
+	}
+	numbers := exportedNumbers(results)
+	if !reflect.DeepEqual(numbers, []int{2, 3}) {
+		t.Fatalf("Expected song numbers [2 3], got %v", numbers)
+	}
+	renamed := renameSongFiles(exportedPaths(results), numbers, titles, "")
+	want := []string{filepath.Join(dir, "02 - Sabotage.mp4"), filepath.Join(dir, "03 - Kid_Charlemagne.mp4")}
+	if !reflect.DeepEqual(renamed, want) {
+		t.Errorf("Expected %q, got %q", want, renamed)
+	}
+	if got := titlesForNumbers(numbers, titles); !reflect.DeepEqual(got, []string{"Sabotage", "Kid Charlemagne"}) {
+		t.Errorf("Expected the picked songs' titles, got %q", got)
+	}
+}
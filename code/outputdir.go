@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// outputDirContents lists the entries already in dir, leaving out keep (the
+// log file, which this run has open by now) and any folder holding one of
+// them. A missing dir has none.
+func outputDirContents(dir string, keep ...string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if !slices.ContainsFunc(keep, func(k string) bool { return k != "" && containsFile(path, k) }) {
+			names = append(names, path)
+		}
+	}
+	return names, nil
+}
+
+// containsFile reports whether path is dir itself or lies inside it,
+// comparing them as absolute paths.
+func containsFile(dir, path string) bool {
+	absDir, errDir := filepath.Abs(dir)
+	absPath, errPath := filepath.Abs(path)
+	return errDir == nil && errPath == nil && withinDir(absDir, absPath)
+}
+
+// prepareRunOutputDir runs prepareOutputDir for a run of cfg on inputs,
+// keeping the files the run reads: the log, setlist and config files.
+// Before deleting anything it refuses a folder that holds an input, or that
+// is (or holds) the current or home directory, where -clean-output would do
+// far more than clear out old songs.
+func prepareRunOutputDir(cfg Config, inputs []string) error {
+	if cfg.CleanOutput {
+		if err := checkCleanTarget(cfg.OutputDir, inputs); err != nil {
+			return withExitCode(exitConfig, err)
+		}
+	}
+	keep := append([]string{logFilePath(cfg), cfg.SetlistFile}, cfg.ConfigFiles...)
+	confirm := func(dir string, n int) bool {
+		if !isTerminal(os.Stdin) {
+			log.Println("-clean-output needs a terminal to confirm on, and stdin isn't one.")
+			return false
+		}
+		return confirmClean(os.Stdin, os.Stderr, dir, n)
+	}
+	return prepareOutputDir(cfg.OutputDir, cfg.CleanOutput, cfg.FailIfNotEmpty, keep, confirm)
+}
+
+// checkCleanTarget returns an error if -clean-output mustn't empty dir.
+func checkCleanTarget(dir string, inputs []string) error {
+	for _, input := range inputs {
+		if input != "" && input != stdinPath && containsFile(dir, input) {
+			return fmt.Errorf("-clean-output won't empty '%s', it holds the input '%s'", dir, input)
+		}
+	}
+	cwd, _ := os.Getwd()
+	home, _ := os.UserHomeDir()
+	for _, protected := range []string{cwd, home} {
+		if protected != "" && containsFile(dir, protected) {
+			return fmt.Errorf("-clean-output won't empty '%s', it is or holds '%s'", dir, protected)
+		}
+	}
+	return nil
+}
+
+// prepareOutputDir deals with files left in dir by an earlier run before
+// anything is exported. With clean they're deleted once confirm agrees; with
+// failIfNotEmpty the run stops instead. Otherwise new files are added
+// alongside the old ones, as before.
+func prepareOutputDir(dir string, clean, failIfNotEmpty bool, keep []string, confirm func(dir string, n int) bool) error {
+	if !clean && !failIfNotEmpty {
+		return nil
+	}
+	existing, err := outputDirContents(dir, keep...)
+	if err != nil {
+		return fmt.Errorf("could not read output directory '%s': %w", dir, err)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	if failIfNotEmpty {
+		return withExitCode(exitAborted, fmt.Errorf("output directory '%s' already holds %d file(s) (-fail-if-not-empty)", dir, len(existing)))
+	}
+	if !confirm(dir, len(existing)) {
+		return withExitCode(exitAborted, fmt.Errorf("not cleaning output directory '%s'", dir))
+	}
+	for _, path := range existing {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("could not clean output directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// confirmClean asks on out whether to delete n entries from dir, reading the
+// answer from in. Only "y" or "yes" agrees.
+func confirmClean(in io.Reader, out io.Writer, dir string, n int) bool {
+	fmt.Fprintf(out, "Delete %d file(s) and folder(s) in '%s' before exporting? [y/N] ", n, dir)
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fillOutputDir leaves an earlier run's files in a fresh output folder.
+func fillOutputDir(t *testing.T) string {
+	dir := t.TempDir()
+	for _, name := range []string{"Song_01.mp4", "splitter.log", "chatter/Chatter_01.mp4"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPrepareOutputDirClean(t *testing.T) {
+	dir := fillOutputDir(t)
+	logFile := filepath.Join(dir, "splitter.log")
+	asked := 0
+	confirm := func(_ string, n int) bool {
+		asked = n
+		return true
+	}
+
+	if err := prepareOutputDir(dir, true, false, []string{logFile}, confirm); err != nil {
+		t.Fatalf("prepareOutputDir failed: %v", err)
+	}
+	if asked != 2 {
+		t.Errorf("Expected to confirm deleting 2 entries, asked about %d", asked)
+	}
+	left, err := outputDirContents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0] != logFile {
+		t.Errorf("Expected only the open log file to be kept, got %v", left)
+	}
+}
+
+func TestPrepareOutputDirCleanDeclined(t *testing.T) {
+	dir := fillOutputDir(t)
+
+	err := prepareOutputDir(dir, true, false, nil, func(string, int) bool { return false })
+
+	if code := exitCodeFor(err); code != exitAborted {
+		t.Errorf("Expected exit code %d when declined, got %d (%v)", exitAborted, code, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Song_01.mp4")); err != nil {
+		t.Errorf("Expected the old files to be left alone, got %v", err)
+	}
+}
+
+func TestPrepareOutputDirFailIfNotEmpty(t *testing.T) {
+	confirm := func(string, int) bool {
+		t.Error("Expected no confirmation with -fail-if-not-empty")
+		return true
+	}
+	dir := fillOutputDir(t)
+
+	err := prepareOutputDir(dir, false, true, nil, confirm)
+
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) || exitErr.code != exitAborted {
+		t.Errorf("Expected the run to stop on a non-empty folder, got %v", err)
+	}
+	if err := prepareOutputDir(filepath.Join(dir, "new"), false, true, nil, confirm); err != nil {
+		t.Errorf("Expected a missing folder to count as empty, got %v", err)
+	}
+	if err := prepareOutputDir(dir, false, false, nil, confirm); err != nil {
+		t.Errorf("Expected the default to add to the folder, got %v", err)
+	}
+}
+
+func TestConfirmClean(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, " YES \n": true, "n\n": false, "\n": false, "": false, "yep\n": false} {
+		var out bytes.Buffer
+		if got := confirmClean(strings.NewReader(answer), &out, "output", 3); got != want {
+			t.Errorf("Answer %q: expected %v, got %v", answer, want, got)
+		}
+		if !strings.Contains(out.String(), "Delete 3 file(s)") {
+			t.Errorf("Expected a prompt, got %q", out.String())
+		}
+	}
+}
+
+func TestCleanOutputConflictsWithFailIfNotEmpty(t *testing.T) {
+	resetFlags()
+	defineFlags()
+	flag.CommandLine.Parse([]string{"-input", "in.mp4", "-clean-output", "-fail-if-not-empty"})
+	if _, _, err := loadConfig(); err == nil {
+		t.Error("Expected an error with both -clean-output and -fail-if-not-empty")
+	}
+}
+
+func TestOutputDirContentsKeepsFoldersHoldingKeptFiles(t *testing.T) {
+	dir := fillOutputDir(t)
+	setlist := filepath.Join(dir, "chatter", "setlist.txt")
+
+	got, err := outputDirContents(dir, setlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "Song_01.mp4"), filepath.Join(dir, "splitter.log")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestPrepareRunOutputDirKeepsRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	setlist, config := filepath.Join(dir, "setlist.txt"), filepath.Join(dir, "config.json")
+	for _, path := range []string{setlist, config} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := Config{OutputDir: dir, SetlistFile: setlist}
+	cfg.FailIfNotEmpty = true
+	cfg.ConfigFiles = []string{config}
+
+	if err := prepareRunOutputDir(cfg, []string{"practice.mp4"}); err != nil {
+		t.Errorf("Expected the setlist and config not to count as old files, got %v", err)
+	}
+}
+
+func TestCheckCleanTarget(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(home, "out")
+	testCases := []struct {
+		name    string
+		dir     string
+		input   string
+		wantErr bool
+	}{
+		{"SongsFolder", out, filepath.Join(home, "practice.mp4"), false},
+		{"HoldsInput", out, filepath.Join(out, "practice.mp4"), true},
+		{"Home", home, "/srv/practice.mp4", true},
+		{"AboveHome", filepath.Dir(home), "/srv/practice.mp4", true},
+		{"WorkingDir", ".", "/srv/practice.mp4", true},
+		{"AboveWorkingDir", filepath.Dir(cwd), "/srv/practice.mp4", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkCleanTarget(tc.dir, []string{tc.input}); (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+)
+
+// probeResult is the subset of `ffprobe -show_streams -show_format -of json`
+// output that the tool uses.
+type probeResult struct {
+	Streams []probeStream `json:"streams"`
+	Format  probeFormat   `json:"format"`
+}
+
+type probeStream struct {
+	Index         int               `json:"index"`
+	CodecType     string            `json:"codec_type"`
+	CodecName     string            `json:"codec_name"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	RFrameRate    string            `json:"r_frame_rate"`
+	AvgFrameRate  string            `json:"avg_frame_rate"`
+	SampleRate    string            `json:"sample_rate"`
+	Channels      int               `json:"channels"`
+	ChannelLayout string            `json:"channel_layout"`
+	Duration      string            `json:"duration"`
+	BitRate       string            `json:"bit_rate"`
+	Tags          map[string]string `json:"tags"`
+}
+
+type probeFormat struct {
+	FormatName string `json:"format_name"`
+	Duration   string `json:"duration"`
+	Size       string `json:"size"`
+	BitRate    string `json:"bit_rate"`
+}
+
+// runFFprobe runs ffprobe quietly and returns its stdout.
+func runFFprobe(args ...string) (string, error) {
+	cmd := execCommand("ffprobe", append([]string{"-v", "error"}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("ffprobe failed: %v: %s", err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// isFFprobeInstalled checks for ffprobe, which ships alongside ffmpeg
+func isFFprobeInstalled() bool {
+	cmd := execCommand("ffprobe", "-version")
+	if err := cmd.Run(); err != nil {
+		return false
+	}
+	return true
+}
+
+// probeMedia describes the streams and container of a media file.
+func probeMedia(path string) (probeResult, error) {
+	output, err := runFFprobe("-show_streams", "-show_format", "-of", "json", path)
+	if err != nil {
+		return probeResult{}, err
+	}
+	return parseProbeJSON(output)
+}
+
+// checkInputStreams probes the input's streams once for the checks that
+// need them: whether they can be copied into a different output_container,
+// variable frame rate (handle_vfr) and, with keep_subtitles, subtitle codecs
+// the output container can't hold. Without ffprobe the checks are skipped,
+// and a change of container re-encodes. It runs per input, so each input of
+// a batch decides for itself.
+func checkInputStreams(cfg Config) Config {
+	changesContainer := containerForcesReencode(cfg) && !cfg.Reencode
+	needed := cfg.HandleVFR != vfrIgnore || cfg.KeepSubtitles || changesContainer
+	if !needed {
+		return cfg
+	}
+	unchecked := func() Config {
+		if changesContainer {
+			log.Printf("Warning: The input's streams may not fit a %s container; re-encoding them.", cfg.OutputContainer)
+		}
+		return cfg
+	}
+	if !isFFprobeInstalled() {
+		return unchecked()
+	}
+	output, err := runFFprobe("-show_streams", "-of", "json", cfg.InputFile)
+	if err != nil {
+		log.Printf("Warning: Could not check the input's streams: %v", err)
+		return unchecked()
+	}
+	cfg = checkCopyCompatibility(cfg, output)
+	cfg = checkVFR(cfg, output)
+	if cfg.KeepSubtitles {
+		warnUnsupportedSubtitles(cfg, output)
+	}
+	return cfg
+}
+
+// parseProbeJSON decodes ffprobe's JSON output.
+func parseProbeJSON(output string) (probeResult, error) {
+	var result probeResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return result, fmt.Errorf("could not parse ffprobe output: %v", err)
+	}
+	return result, nil
+}
+
+// printProbeInfo writes a readable summary of a probe result.
+func printProbeInfo(w io.Writer, path string, info probeResult) {
+	fmt.Fprintf(w, "File:      %s\n", path)
+	fmt.Fprintf(w, "Container: %s\n", info.Format.FormatName)
+	if d, err := strconv.ParseFloat(info.Format.Duration, 64); err == nil {
+		fmt.Fprintf(w, "Duration:  %.2fs\n", d)
+	}
+	if n, err := strconv.ParseInt(info.Format.Size, 10, 64); err == nil {
+		fmt.Fprintf(w, "Size:      %s\n", humanizeBytes(n))
+	}
+	if b, err := strconv.ParseInt(info.Format.BitRate, 10, 64); err == nil {
+		fmt.Fprintf(w, "Bitrate:   %d kb/s\n", b/1000)
+	}
+	for _, s := range info.Streams {
+		switch s.CodecType {
+		case "video":
+			rate := formatFrameRate(s.AvgFrameRate) + " fps"
+			if streamIsVFR(s) {
+				rate += fmt.Sprintf(" average, variable frame rate (r_frame_rate %s); stream copies may drift, see -handle-vfr", formatFrameRate(s.RFrameRate))
+			}
+			fmt.Fprintf(w, "Stream #%d: video %s, %dx%d, %s\n", s.Index, s.CodecName, s.Width, s.Height, rate)
+		case "audio":
+			layout := s.ChannelLayout
+			if layout == "" {
+				layout = fmt.Sprintf("%d channel(s)", s.Channels)
+			}
+			fmt.Fprintf(w, "Stream #%d: audio %s, %s Hz, %s%s\n", s.Index, s.CodecName, s.SampleRate, layout, languageSuffix(s))
+		default:
+			fmt.Fprintf(w, "Stream #%d: %s %s%s\n", s.Index, s.CodecType, s.CodecName, languageSuffix(s))
+		}
+	}
+}
+
+// languageSuffix returns a stream's language tag as " [eng]", if it has one.
+func languageSuffix(s probeStream) string {
+	if lang := s.Tags["language"]; lang != "" {
+		return " [" + lang + "]"
+	}
+	return ""
+}
+
+// probeChapter is one chapter from `ffprobe -show_chapters -of json`.
+type probeChapter struct {
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// probeChapters returns the chapter markers stored in a media file, with
+// their titles ("" where a chapter has none).
+func probeChapters(path string) ([]segment, []string, error) {
+	output, err := runFFprobe("-show_chapters", "-of", "json", path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var result struct {
+		Chapters []probeChapter `json:"chapters"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, nil, fmt.Errorf("could not parse ffprobe output: %v", err)
+	}
+	var segments []segment
+	var titles []string
+	for _, c := range result.Chapters {
+		start, err1 := strconv.ParseFloat(c.StartTime, 64)
+		end, err2 := strconv.ParseFloat(c.EndTime, 64)
+		if err1 != nil || err2 != nil || end <= start {
+			continue
+		}
+		segments = append(segments, segment{start: start, end: end})
+		titles = append(titles, c.Tags["title"])
+	}
+	return segments, titles, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleProbeJSON = `{
+  "streams": [
+    {"index": 0, "codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080,
+     "r_frame_rate": "30/1", "avg_frame_rate": "30/1"},
+    {"index": 1, "codec_type": "audio", "codec_name": "aac", "sample_rate": "48000",
+     "channels": 2, "channel_layout": "stereo", "tags": {"language": "eng"}},
+    {"index": 2, "codec_type": "audio", "codec_name": "pcm_s16le", "sample_rate": "44100", "channels": 1}
+  ],
+  "format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "7265.120000", "size": "2254857830", "bit_rate": "2483000"}
+}`
+
+func TestProbeMedia(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stdout: sampleProbeJSON}
+	})
+
+	info, err := probeMedia("practice.mp4")
+	if err != nil {
+		t.Fatalf("probeMedia failed: %v", err)
+	}
+
+	expectedArgs := []string{"-v", "error", "-show_streams", "-show_format", "-of", "json", "practice.mp4"}
+	if fake.calls[0].name != "ffprobe" || !reflect.DeepEqual(fake.calls[0].args, expectedArgs) {
+		t.Errorf("Expected ffprobe %v, got %s %v", expectedArgs, fake.calls[0].name, fake.calls[0].args)
+	}
+	if len(info.Streams) != 3 || info.Streams[1].Channels != 2 || info.Format.Duration != "7265.120000" {
+		t.Errorf("Unexpected probe result: %+v", info)
+	}
+}
+
+func TestProbeMediaFailure(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: "practice.mp4: No such file or directory", exitCode: 1}
+	})
+	if _, err := probeMedia("practice.mp4"); err == nil || !strings.Contains(err.Error(), "No such file") {
+		t.Errorf("Expected ffprobe's error to be surfaced, got %v", err)
+	}
+}
+
+func TestPrintProbeInfo(t *testing.T) {
+	info, err := parseProbeJSON(sampleProbeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printProbeInfo(&buf, "practice.mp4", info)
+	out := buf.String()
+	for _, want := range []string{
+		"Duration:  7265.12s",
+		"Size:      2.1 GB",
+		"Bitrate:   2483 kb/s",
+		"Stream #0: video h264, 1920x1080, 30 fps\n",
+		"Stream #1: audio aac, 48000 Hz, stereo [eng]",
+		"Stream #2: audio pcm_s16le, 44100 Hz, 1 channel(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestProbeChapters(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stdout: `{"chapters": [
+  {"id": 0, "time_base": "1/1000", "start_time": "0.000000", "end_time": "241.500000", "tags": {"title": "Opener"}},
+  {"id": 1, "time_base": "1/1000", "start_time": "241.500000", "end_time": "480.000000"}
+]}`}
+	})
+
+	segments, titles, err := probeChapters("practice.mp4")
+	if err != nil {
+		t.Fatalf("probeChapters failed: %v", err)
+	}
+	expectedArgs := []string{"-v", "error", "-show_chapters", "-of", "json", "practice.mp4"}
+	if !reflect.DeepEqual(fake.calls[0].args, expectedArgs) {
+		t.Errorf("Expected ffprobe %v, got %v", expectedArgs, fake.calls[0].args)
+	}
+	if want := []segment{{start: 0, end: 241.5}, {start: 241.5, end: 480}}; !reflect.DeepEqual(segments, want) {
+		t.Errorf("Expected segments %v, got %v", want, segments)
+	}
+	if want := []string{"Opener", ""}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("Expected titles %q, got %q", want, titles)
+	}
+}
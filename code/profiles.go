@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// loadProfiles reads the "profiles" object of a config file: named sets of
+// config keys that -profile lays over the file's top-level settings, e.g.
+// {"profiles": {"loud-room": {"silence_threshold": "-24dB"}}}.
+func loadProfiles(path string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Profiles map[string]json.RawMessage `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Profiles, nil
+}
+
+// hasProfile reports whether the config file at path defines the named
+// profile.
+func hasProfile(path, name string) bool {
+	profiles, err := loadProfiles(path)
+	_, ok := profiles[name]
+	return err == nil && ok
+}
+
+// applyProfile lays the named profile from the config file at path over
+// fileConfig, the file's top-level settings. Keys the profile doesn't set
+// keep their top-level values.
+func applyProfile(path string, fileConfig Config, name string) (Config, error) {
+	profiles, err := loadProfiles(path)
+	if err != nil {
+		return fileConfig, fmt.Errorf("-profile %s: could not read config file '%s': %v", name, path, err)
+	}
+	raw, ok := profiles[name]
+	if !ok {
+		if len(profiles) == 0 {
+			return fileConfig, fmt.Errorf("-profile %s: config file '%s' has no profiles", name, path)
+		}
+		return fileConfig, fmt.Errorf("-profile %s: no such profile in '%s' (have: %s)", name, path, strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+	if err := json.Unmarshal(raw, &fileConfig); err != nil {
+		return fileConfig, fmt.Errorf("profile '%s': %v", name, err)
+	}
+	return fileConfig, nil
+}
+
+// profileSummary is one row of -list-profiles: the settings a profile ends
+// up with, before CLI flags.
+type profileSummary struct {
+	Name             string
+	SilenceThreshold string
+	MinSongLength    float64
+	Keys             []string // the keys the profile sets
+}
+
+// summarizeProfiles describes each profile in the config file at path, in
+// name order.
+func summarizeProfiles(path string) ([]profileSummary, error) {
+	profiles, err := loadProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+	base, err := loadConfigFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var summaries []profileSummary
+	for _, name := range slices.Sorted(maps.Keys(profiles)) {
+		cfg, err := applyProfile(path, base, name)
+		if err != nil {
+			return nil, err
+		}
+		var keys map[string]json.RawMessage
+		json.Unmarshal(profiles[name], &keys) // parsed by applyProfile
+		s := profileSummary{Name: name, SilenceThreshold: cfg.SilenceThreshold, MinSongLength: cfg.MinSongLength, Keys: slices.Sorted(maps.Keys(keys))}
+		if s.SilenceThreshold == "" {
+			s.SilenceThreshold = defaultConfig.SilenceThreshold
+		}
+		if s.MinSongLength == 0 {
+			s.MinSongLength = defaultConfig.MinSongLength
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, nil
+}
+
+// listConfigProfiles prints the profiles of each config file in paths. Like
+// loadConfig, it skips a missing file in a layered list (loadConfig has
+// already warned about it); a lone -config file has to exist.
+func listConfigProfiles(w io.Writer, paths []string) error {
+	for _, path := range paths {
+		profiles, err := summarizeProfiles(path)
+		if os.IsNotExist(err) && len(paths) > 1 {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not read profiles from '%s': %v", path, err)
+		}
+		printProfiles(w, path, profiles)
+	}
+	return nil
+}
+
+// printProfiles prints -list-profiles' table.
+func printProfiles(w io.Writer, path string, profiles []profileSummary) {
+	if len(profiles) == 0 {
+		fmt.Fprintf(w, "No profiles in %s.\n", path)
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE\tTHRESHOLD\tMIN SONG LENGTH\tSETS")
+	for _, p := range profiles {
+		fmt.Fprintf(tw, "%s\t%s\t%gs\t%s\n", p.Name, p.SilenceThreshold, p.MinSongLength, strings.Join(p.Keys, ", "))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profilesConfigJSON = `{
+  "silence_threshold": "-40dB",
+  "output_prefix": "Practice",
+  "profiles": {
+    "loud-room": {"silence_threshold": "-24dB", "min_song_length": 90},
+    "gig": {"output_prefix": "Live"}
+  }
+}`
+
+func writeProfilesConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(profilesConfigJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigLoadingAppliesProfile(t *testing.T) {
+	t.Cleanup(func() { profileName = "" })
+	path := writeProfilesConfig(t)
+	resetFlags()
+	defineFlags()
+	if err := flag.CommandLine.Parse([]string{"-config=" + path, "-profile=loud-room"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	cfg, _, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if cfg.SilenceThreshold != "-24dB" || cfg.MinSongLength != 90 {
+		t.Errorf("Expected the profile's settings, got threshold %s, min song length %g", cfg.SilenceThreshold, cfg.MinSongLength)
+	}
+	if cfg.OutputPrefix != "Practice" {
+		t.Errorf("Expected top-level settings the profile doesn't set to stay, got prefix %s", cfg.OutputPrefix)
+	}
+}
+
+func TestConfigLoadingAppliesProfileFromLaterFile(t *testing.T) {
+	t.Cleanup(func() { profileName = "" })
+	base, cleanup := createTempConfig(t, Config{SilenceThreshold: "-35dB", OutputPrefix: "Team"})
+	defer cleanup()
+	path := writeProfilesConfig(t)
+	resetFlags()
+	defineFlags()
+	if err := flag.CommandLine.Parse([]string{"-config=" + base + "," + path, "-profile=gig"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	cfg, _, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if cfg.OutputPrefix != "Live" || cfg.SilenceThreshold != "-40dB" {
+		t.Errorf("Expected the later file and its profile to win, got prefix %s, threshold %s", cfg.OutputPrefix, cfg.SilenceThreshold)
+	}
+}
+
+func TestConfigLoadingRejectsUnknownProfile(t *testing.T) {
+	t.Cleanup(func() { profileName = "" })
+	path := writeProfilesConfig(t)
+	resetFlags()
+	defineFlags()
+	if err := flag.CommandLine.Parse([]string{"-config=" + path, "-profile=studio"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	if _, _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "have: gig, loud-room") {
+		t.Errorf("Expected an error listing the profiles, got %v", err)
+	}
+}
+
+func TestPrintProfiles(t *testing.T) {
+	path := writeProfilesConfig(t)
+	profiles, err := summarizeProfiles(path)
+	if err != nil {
+		t.Fatalf("summarizeProfiles failed: %v", err)
+	}
+	var buf bytes.Buffer
+	printProfiles(&buf, path, profiles)
+
+	want := "PROFILE    THRESHOLD  MIN SONG LENGTH  SETS\n" +
+		"gig        -40dB      200s             output_prefix\n" +
+		"loud-room  -24dB      90s              min_song_length, silence_threshold\n"
+	if buf.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+func TestListConfigProfilesSkipsMissingLayer(t *testing.T) {
+	path := writeProfilesConfig(t)
+	missing := filepath.Join(t.TempDir(), "local.json")
+	var buf bytes.Buffer
+
+	if err := listConfigProfiles(&buf, []string{path, missing}); err != nil {
+		t.Fatalf("Expected a missing layered config to be skipped, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "loud-room") {
+		t.Errorf("Expected the existing file's profiles, got\n%s", buf.String())
+	}
+	if err := listConfigProfiles(&buf, []string{missing}); err == nil {
+		t.Error("Expected an error for a lone missing config file")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressReporter serializes status output from export jobs so lines from
+// concurrent ffmpeg runs never interleave. On a terminal it also keeps one
+// aggregate line ("4/12 segments done, 2 in progress") redrawn below the
+// log; otherwise it logs plain lines only.
+//
+// Jobs can also report how much media they covered, giving a job-level ETA
+// from the export speed so far.
+//
+// With ordered set, lines a job logs through jobLogf (and anything else it
+// reports through jobDo) are held until every earlier job has finished, so a
+// parallel export logs in job order.
+type progressReporter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	logger *log.Logger
+	tty    bool
+	total  int
+	done   int
+	active int
+	shown  bool // whether the aggregate line is on screen
+
+	media     float64 // seconds of media across all jobs
+	mediaDone float64 // seconds of media exported so far
+	began     time.Time
+	now       func() time.Time
+
+	ordered  bool
+	next     int              // lowest job that hasn't finished
+	held     map[int][]func() // output waiting for earlier jobs, by job
+	finished map[int]bool     // jobs done while an earlier one still runs
+}
+
+// newProgressReporter reports on total jobs to stderr, logging through the
+// standard logger's writer so -log-file gets the lines too.
+func newProgressReporter(total int) *progressReporter {
+	p := newProgressReporterTo(os.Stderr, isTerminal(os.Stderr), total)
+	p.logger.SetOutput(log.Writer())
+	return p
+}
+
+// newProgressReporterTo reports to out, drawing the aggregate line if tty.
+func newProgressReporterTo(out io.Writer, tty bool, total int) *progressReporter {
+	return &progressReporter{
+		out:      out,
+		logger:   log.New(out, log.Prefix(), log.Flags()),
+		tty:      tty,
+		total:    total,
+		began:    time.Now(),
+		now:      time.Now,
+		held:     map[int][]func(){},
+		finished: map[int]bool{},
+	}
+}
+
+// trackMedia sets how many seconds of media the jobs cover in total.
+func (p *progressReporter) trackMedia(seconds float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.media = seconds
+	p.began = p.now()
+}
+
+// logf logs one line above the aggregate line.
+func (p *progressReporter) logf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLine()
+	p.logger.Printf(format, args...)
+	p.drawLine()
+}
+
+// jobLogf logs one line for job (numbered from 0 in the order jobs were
+// queued). With ordered set, only the lowest unfinished job logs straight
+// away; later jobs' lines wait for finishJob.
+func (p *progressReporter) jobLogf(job int, format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+	p.jobDo(job, func() { p.logger.Print(line) })
+}
+
+// jobDo runs report, which writes job's output some other way (e.g. a
+// ProgressFunc logging "song 3 done"), held in order just like jobLogf's
+// lines. Reports never run concurrently with each other.
+func (p *progressReporter) jobDo(job int, report func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ordered && job != p.next {
+		p.held[job] = append(p.held[job], report)
+		return
+	}
+	p.clearLine()
+	report()
+	p.drawLine()
+}
+
+// start marks a job as running.
+func (p *progressReporter) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active++
+	p.clearLine()
+	p.drawLine()
+}
+
+// finish marks a running job covering seconds of media as done, whether it
+// succeeded or not. A reused job took no time, so it's left out of the
+// speed rather than making the rest look faster than they will be.
+func (p *progressReporter) finish(seconds float64, reused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finishLocked(seconds, reused)
+}
+
+// finishJob is finish for a job that logged through jobLogf, releasing the
+// held lines of the jobs after it once it's their turn.
+func (p *progressReporter) finishJob(job int, seconds float64, reused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finished[job] = true
+	p.clearLine()
+	for p.finished[p.next] {
+		delete(p.finished, p.next)
+		p.next++
+		for _, report := range p.held[p.next] {
+			report()
+		}
+		delete(p.held, p.next)
+	}
+	p.finishLocked(seconds, reused)
+}
+
+func (p *progressReporter) finishLocked(seconds float64, reused bool) {
+	p.active--
+	p.done++
+	if reused {
+		p.media -= seconds
+	} else {
+		p.mediaDone += seconds
+	}
+	p.clearLine()
+	p.drawLine()
+}
+
+// logETA logs how far through the media the jobs are and, once the speed is
+// known, about how long the rest will take.
+func (p *progressReporter) logETA() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.media <= 0 {
+		return
+	}
+	p.clearLine()
+	line := fmt.Sprintf("Progress: %s of %s exported (%.0f%%), %s elapsed",
+		formatTrackTime(p.mediaDone), formatTrackTime(p.media), 100*p.mediaDone/p.media, roundDuration(p.elapsed()))
+	if left, speed, ok := p.eta(); ok {
+		line += fmt.Sprintf(", about %s left at %.1fx realtime", roundDuration(left), speed)
+	}
+	p.logger.Print(line)
+	p.drawLine()
+}
+
+// logSummary logs the total wall time of the jobs and their overall speed.
+func (p *progressReporter) logSummary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLine()
+	line := fmt.Sprintf("Exported %d segment(s) in %s", p.done, roundDuration(p.elapsed()))
+	if elapsed := p.elapsed().Seconds(); p.mediaDone > 0 && elapsed > 0 {
+		line += fmt.Sprintf(" (%s of media, %.1fx realtime)", formatTrackTime(p.mediaDone), p.mediaDone/elapsed)
+	}
+	p.logger.Print(line)
+}
+
+// eta estimates the time left from the average speed so far, in seconds of
+// media per second of wall time.
+func (p *progressReporter) eta() (left time.Duration, speed float64, ok bool) {
+	elapsed := p.elapsed().Seconds()
+	if p.mediaDone <= 0 || elapsed <= 0 {
+		return 0, 0, false
+	}
+	speed = p.mediaDone / elapsed
+	remaining := max(p.media-p.mediaDone, 0)
+	return time.Duration(remaining / speed * float64(time.Second)), speed, true
+}
+
+func (p *progressReporter) elapsed() time.Duration {
+	return p.now().Sub(p.began)
+}
+
+// roundDuration rounds to whole seconds for display ("4m10s").
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(time.Second)
+}
+
+// close removes the aggregate line once all jobs have finished.
+func (p *progressReporter) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLine()
+}
+
+func (p *progressReporter) clearLine() {
+	if p.shown {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.shown = false
+	}
+}
+
+func (p *progressReporter) drawLine() {
+	if !p.tty || p.total == 0 {
+		return
+	}
+	fmt.Fprintf(p.out, "%d/%d segments done, %d in progress", p.done, p.total, p.active)
+	if left, _, ok := p.eta(); ok && p.media > 0 {
+		fmt.Fprintf(p.out, ", about %s left", roundDuration(left))
+	}
+	p.shown = true
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressReporterPlainLines(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressReporterTo(&buf, false, 8)
+	p.logger.SetFlags(0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p.start()
+			p.logf("segment %d exported", i)
+			p.finish(0, false)
+		}(i)
+	}
+	wg.Wait()
+	p.close()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("Expected 8 whole lines, got %q", buf.String())
+	}
+	for _, line := range lines {
+		var n int
+		if _, err := fmt.Sscanf(line, "segment %d exported", &n); err != nil {
+			t.Errorf("Expected an unbroken log line, got %q", line)
+		}
+	}
+}
+
+func TestProgressReporterAggregateLine(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressReporterTo(&buf, true, 3)
+	p.logger.SetFlags(0)
+
+	p.start()
+	p.start()
+	p.finish(0, false)
+	p.logf("segment 1 exported")
+
+	out := buf.String()
+	if !strings.Contains(out, "1/3 segments done, 1 in progress") {
+		t.Errorf("Expected an aggregate line, got %q", out)
+	}
+	if !strings.HasSuffix(out, "\r\033[Ksegment 1 exported\n1/3 segments done, 1 in progress") {
+		t.Errorf("Expected the log line above a redrawn aggregate line, got %q", out)
+	}
+
+	p.finish(0, false)
+	p.close()
+	if !strings.HasSuffix(buf.String(), "\r\033[K") {
+		t.Errorf("Expected close to clear the aggregate line, got %q", buf.String())
+	}
+}
+
+func TestProgressReporterETA(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressReporterTo(&buf, false, 4)
+	p.logger.SetFlags(0)
+	clock := time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return clock }
+	p.trackMedia(1200) // four 5-minute songs
+
+	p.logETA() // nothing exported yet: no speed to go on
+	p.start()
+	p.finish(300, true) // reused from -cache: no time, and no longer to do
+	clock = clock.Add(time.Minute)
+	p.start()
+	p.finish(300, false) // 5 minutes of media in 1 minute: 5x
+	p.logETA()
+	clock = clock.Add(time.Minute)
+	p.start()
+	p.finish(300, false)
+	p.logSummary()
+
+	expected := "Progress: 0:00 of 20:00 exported (0%), 0s elapsed\n" +
+		"Progress: 5:00 of 15:00 exported (33%), 1m0s elapsed, about 2m0s left at 5.0x realtime\n" +
+		"Exported 3 segment(s) in 2m0s (10:00 of media, 5.0x realtime)\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestProgressReporterOrderedLines(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressReporterTo(&buf, false, 3)
+	p.logger.SetFlags(0)
+	p.ordered = true
+
+	p.jobLogf(0, "song 1 started")
+	p.jobLogf(2, "song 3 started")
+	p.jobLogf(1, "song 2 started")
+	p.jobLogf(2, "song 3 failed")
+	p.finishJob(2, 0, false)
+	if got := buf.String(); got != "song 1 started\n" {
+		t.Fatalf("Expected later songs held back, got %q", got)
+	}
+	p.finishJob(0, 0, false)
+	p.jobLogf(1, "song 2 exported")
+	p.finishJob(1, 0, false)
+
+	want := "song 1 started\nsong 2 started\nsong 2 exported\nsong 3 started\nsong 3 failed\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Expected lines in song order %q, got %q", want, got)
+	}
+}
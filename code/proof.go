@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Proof copies are small H.264/AAC files for a quick listen on a phone.
+const (
+	proofSuffix       = "_proof.mp4"
+	proofVideoBitrate = "400k"
+	proofAudioBitrate = "96k"
+)
+
+// audioOnlyExtensions are outputs with no picture to make a proof of.
+var audioOnlyExtensions = map[string]bool{
+	".m4a": true, ".mp3": true, ".wav": true, ".flac": true, ".aac": true, ".ogg": true, ".opus": true,
+}
+
+// proofPath returns where the proof of an exported song goes:
+// "output/Song_01.mkv" gets "output/Song_01_proof.mp4".
+func proofPath(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file)) + proofSuffix
+}
+
+// buildProofArgs assembles the ffmpeg arguments that re-encode an exported
+// song at ProofScale (any scale filter size, e.g. "480:-2") and a low bitrate.
+func buildProofArgs(cfg Config, file string) []string {
+	return ffmpegArgs(cfg.FFmpegLogLevel, "-y", "-i", file,
+		"-vf", "scale="+cfg.ProofScale,
+		"-c:v", "libx264", "-preset", "veryfast", "-b:v", proofVideoBitrate,
+		"-c:a", "aac", "-b:a", proofAudioBitrate,
+		"-movflags", "+faststart", proofPath(file))
+}
+
+// exportProofs writes a proof next to each exported song, skipping
+// audio-only files and proofs already newer than their song. Failures are
+// logged; the full-quality songs are what matter.
+func exportProofs(cfg Config, files []string) {
+	log.Printf("--- Making %s proof copies ---", cfg.ProofScale)
+	for _, file := range files {
+		if audioOnlyExtensions[strings.ToLower(filepath.Ext(file))] {
+			continue
+		}
+		proof := proofPath(file)
+		if newerThan(proof, file) {
+			log.Printf("Proof '%s' is up to date.", proof)
+			continue
+		}
+		cmd := execCommand("ffmpeg", buildProofArgs(cfg, file)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			log.Printf("Warning: Could not make proof of '%s': %v\n%s", file, err, output)
+			continue
+		}
+		log.Printf("Wrote proof '%s'", proof)
+	}
+}
+
+// newerThan reports whether path exists and was modified after other.
+func newerThan(path, other string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	otherInfo, err := os.Stat(other)
+	return err == nil && info.ModTime().After(otherInfo.ModTime())
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildProofArgs(t *testing.T) {
+	cfg := Config{ProofScale: "480:-2"}
+	args := buildProofArgs(cfg, "output/01 - Opener.mkv")
+
+	if got := args[len(args)-1]; got != "output/01 - Opener_proof.mp4" {
+		t.Errorf("Expected the proof beside the song as an mp4, got %q", got)
+	}
+	if !strings.Contains(strings.Join(args, " "), "-i output/01 - Opener.mkv -vf scale=480:-2 -c:v libx264") {
+		t.Errorf("Expected a scaled H.264 re-encode of the song, got %v", args)
+	}
+}
+
+func TestExportProofs(t *testing.T) {
+	dir := t.TempDir()
+	song := filepath.Join(dir, "Song_01.mp4")
+	fresh := filepath.Join(dir, "Song_02.mp4")
+	for _, f := range []string{song, fresh, filepath.Join(dir, "Song_03.m4a")} {
+		if err := os.WriteFile(f, []byte("song"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.WriteFile(proofPath(fresh), []byte("proof"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(proofPath(fresh), future, future); err != nil {
+		t.Fatal(err)
+	}
+	fake := installFakeExec(t, nil)
+
+	exportProofs(Config{ProofScale: "480:-2"}, []string{song, fresh, filepath.Join(dir, "Song_03.m4a")})
+
+	var made []string
+	for _, call := range fake.calls {
+		made = append(made, call.args[len(call.args)-1])
+	}
+	if want := []string{proofPath(song)}; !reflect.DeepEqual(made, want) {
+		t.Errorf("Expected only %q to be made (audio and up-to-date proofs skipped), got %q", want, made)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// replayGainReference is the ReplayGain 2.0 target loudness.
+const replayGainReference = -18.0 // LUFS
+
+// taggableExtensions are the formats whose tags players read ReplayGain from.
+var taggableExtensions = map[string]bool{".flac": true, ".mp3": true, ".ogg": true, ".opus": true}
+
+// replayGainTags returns the REPLAYGAIN_TRACK_GAIN and _PEAK values for a
+// measured song: the gain to bring it to replayGainReference, and its true
+// peak as a linear amplitude.
+func replayGainTags(l loudness) (gain float64, gainTag, peakTag string) {
+	gain = replayGainReference - l.integrated
+	return gain, fmt.Sprintf("%.2f dB", gain), fmt.Sprintf("%.6f", math.Pow(10, l.truePeak/20))
+}
+
+// replayGainArgs assembles the ffmpeg arguments that copy file to tmp with
+// the ReplayGain tags added, leaving the audio untouched.
+func replayGainArgs(file, tmp, gainTag, peakTag string) []string {
+	return ffmpegArgs("", "-y", "-i", file, "-map", "0", "-c", "copy",
+		"-metadata", "REPLAYGAIN_TRACK_GAIN="+gainTag,
+		"-metadata", "REPLAYGAIN_TRACK_PEAK="+peakTag, tmp)
+}
+
+// applyReplayGain measures each exported song (unless -loudness-report
+// already did) and writes its ReplayGain tags. Formats that can't carry the
+// tags are skipped with one warning.
+func applyReplayGain(results []segmentResult) {
+	log.Println("Writing ReplayGain tags...")
+	var skipped []string
+	for i := range results {
+		r := &results[i]
+		if r.Status != statusExported {
+			continue
+		}
+		if !taggableExtensions[strings.ToLower(filepath.Ext(r.File))] {
+			skipped = append(skipped, filepath.Base(r.File))
+			continue
+		}
+		if r.LoudnessLUFS == nil {
+			l, err := measureLoudness(r.File)
+			if err != nil {
+				log.Printf("Warning: Could not measure loudness of '%s': %v", r.File, err)
+				continue
+			}
+			r.LoudnessLUFS, r.TruePeakDBFS = &l.integrated, &l.truePeak
+		}
+		gain, gainTag, peakTag := replayGainTags(loudness{integrated: *r.LoudnessLUFS, truePeak: *r.TruePeakDBFS})
+		if err := writeReplayGainTags(r.File, gainTag, peakTag); err != nil {
+			log.Printf("Warning: Could not tag '%s': %v", r.File, err)
+			continue
+		}
+		r.ReplayGainDB = &gain
+	}
+	if len(skipped) > 0 {
+		log.Printf("Warning: ReplayGain tags need flac, mp3, ogg or opus files; skipped %d song(s): %s", len(skipped), strings.Join(skipped, ", "))
+	}
+}
+
+// writeReplayGainTags rewrites file with the tags added. ffmpeg can't edit
+// in place, so it writes a hidden copy beside the song and swaps it in.
+func writeReplayGainTags(file, gainTag, peakTag string) error {
+	tmp := filepath.Join(filepath.Dir(file), ".replaygain-"+filepath.Base(file))
+	cmd := execCommand("ffmpeg", replayGainArgs(file, tmp, gainTag, peakTag)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%v: %s", err, output)
+	}
+	return os.Rename(tmp, file)
+}
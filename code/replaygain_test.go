@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplayGainTags(t *testing.T) {
+	gain, gainTag, peakTag := replayGainTags(loudness{integrated: -21.5, truePeak: -1})
+	if gain != 3.5 || gainTag != "3.50 dB" || peakTag != "0.891251" {
+		t.Errorf("Expected +3.5 dB (\"3.50 dB\", \"0.891251\"), got %v (%q, %q)", gain, gainTag, peakTag)
+	}
+	if _, gainTag, _ := replayGainTags(loudness{integrated: -9.25, truePeak: 0}); gainTag != "-8.75 dB" {
+		t.Errorf("Expected a loud song to get negative gain, got %q", gainTag)
+	}
+}
+
+func TestApplyReplayGain(t *testing.T) {
+	dir := t.TempDir()
+	flac := filepath.Join(dir, "01 - Opener.flac")
+	mp4 := filepath.Join(dir, "02 - Closer.mp4")
+	for _, f := range []string{flac, mp4} {
+		if err := os.WriteFile(f, []byte("song"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var tagged []string
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		tmp := call.args[len(call.args)-1]
+		tagged = append(tagged, strings.Join(call.args, " "))
+		os.WriteFile(tmp, []byte("tagged"), 0644)
+		return fakeResult{}
+	})
+	lufs, peak := -21.5, -1.0
+	results := []segmentResult{
+		{Index: 1, File: flac, Status: statusExported, LoudnessLUFS: &lufs, TruePeakDBFS: &peak},
+		{Index: 2, File: mp4, Status: statusExported, LoudnessLUFS: &lufs, TruePeakDBFS: &peak},
+	}
+
+	applyReplayGain(results)
+
+	if len(tagged) != 1 || !strings.Contains(tagged[0], "-c copy -metadata REPLAYGAIN_TRACK_GAIN=3.50 dB -metadata REPLAYGAIN_TRACK_PEAK=0.891251") {
+		t.Fatalf("Expected one stream-copy tagging pass for the flac, got %q", tagged)
+	}
+	if data, _ := os.ReadFile(flac); string(data) != "tagged" {
+		t.Errorf("Expected the tagged copy to replace the song, got %q", data)
+	}
+	if results[0].ReplayGainDB == nil || *results[0].ReplayGainDB != 3.5 || results[1].ReplayGainDB != nil {
+		t.Errorf("Expected a gain for the flac only, got %v and %v", results[0].ReplayGainDB, results[1].ReplayGainDB)
+	}
+	if table := loudnessTable(results); !strings.Contains(table, "+3.50 dB  01 - Opener.flac") || !strings.Contains(table, "-  02 - Closer.mp4") {
+		t.Errorf("Expected the gain in the summary table, got:\n%s", table)
+	}
+}
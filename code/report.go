@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// version is the tool version recorded in run reports. Release builds set
+// it with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// statusUploaded marks a successful upload in uploadResult.
+const statusUploaded = "uploaded"
+
+// Report timestamp modes, recorded in runReport.Timestamps.
+const (
+	timestampsSource = "source" // seconds into the input recording
+	timestampsFile   = "file"   // seconds into each song's own file
+)
+
+// runReport is the machine-readable outcome of a run, written by -report-json.
+type runReport struct {
+	Version          string          `json:"version"`
+	Config           Config          `json:"config"`
+	StartedAt        time.Time       `json:"started_at"`
+	TotalSeconds     float64         `json:"total_seconds"`
+	Success          bool            `json:"success"`
+	FailedSegments   int             `json:"failed_segments"`
+	Timestamps       string          `json:"timestamps"`
+	Error            string          `json:"error,omitempty"`
+	Segments         []segmentResult `json:"segments"`
+	Skipped          []segmentResult `json:"skipped"`
+	Chatter          []segmentResult `json:"chatter,omitempty"`
+	GapsFile         string          `json:"gaps_file,omitempty"`
+	FullSetFile      string          `json:"full_set_file,omitempty"`
+	Archive          string          `json:"archive,omitempty"`
+	IntervalFallback bool            `json:"interval_fallback,omitempty"`
+	Uploads          []uploadResult  `json:"uploads,omitempty"`
+	Stages           []stageTiming   `json:"stages"`
+}
+
+// uploadResult is the outcome of uploading to one destination.
+type uploadResult struct {
+	Destination string `json:"destination"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// stageTiming is the wall-clock time one pipeline stage took.
+type stageTiming struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// redacted replaces a secret config value in a report.
+const redacted = "<redacted>"
+
+// redactSecrets blanks the config values that shouldn't be written out with
+// a report, either to -report-json's file or in -serve's response: the
+// webhook URL, which is often the only credential a Slack or Discord hook
+// has; the post hook and rclone flags, which can carry tokens and config
+// paths; and where the songs are uploaded to.
+func redactSecrets(cfg Config) Config {
+	if cfg.NotifyWebhook != "" {
+		cfg.NotifyWebhook = redacted
+	}
+	if cfg.PostHook != "" {
+		cfg.PostHook = redacted
+	}
+	if len(cfg.RcloneGlobalFlags) > 0 {
+		cfg.RcloneGlobalFlags = []string{redacted}
+	}
+	if cfg.RcloneRemote != "" {
+		cfg.RcloneRemote = redacted
+	}
+	if cfg.DriveSubfolder != "" {
+		cfg.DriveSubfolder = redacted
+	}
+	if len(cfg.UploadDestinations) > 0 {
+		dests := make([]UploadDestination, len(cfg.UploadDestinations))
+		for i := range dests {
+			dests[i] = UploadDestination{Remote: redacted, Subfolder: redacted}
+		}
+		cfg.UploadDestinations = dests
+	}
+	return cfg
+}
+
+func newRunReport(cfg Config) *runReport {
+	return &runReport{
+		Version:    version,
+		Config:     redactSecrets(cfg),
+		StartedAt:  time.Now(),
+		Timestamps: timestampsSource,
+		Segments:   []segmentResult{},
+		Skipped:    []segmentResult{},
+		Stages:     []stageTiming{},
+	}
+}
+
+// startStage starts timing a stage. Call the returned func when it ends.
+func (r *runReport) startStage(name string) func() {
+	start := time.Now()
+	return func() {
+		r.Stages = append(r.Stages, stageTiming{Name: name, Seconds: time.Since(start).Seconds()})
+	}
+}
+
+// recordSkipped records the candidate segments that were too short to count
+// as songs, other than any on_no_songs exports anyway.
+func (r *runReport) recordSkipped(silences, songs []segment, totalDuration float64, cfg Config) {
+	if cfg.LosslessBoundaries {
+		return // short pieces are merged into a neighbour, never skipped
+	}
+	for _, seg := range candidateSegments(silences, totalDuration, cfg) {
+		if seg.end-seg.start < cfg.MinSongLength && !slices.Contains(songs, seg) {
+			r.Skipped = append(r.Skipped, segmentResult{Start: seg.start, End: seg.end, Status: statusSkipped})
+		}
+	}
+}
+
+// updateExportedPaths replaces the file names of exported segments, in order,
+// e.g. after the setlist rename.
+func (r *runReport) updateExportedPaths(paths []string) {
+	i := 0
+	for j := range r.Segments {
+		if r.Segments[j].Status == statusExported && i < len(paths) {
+			r.Segments[j].File = paths[i]
+			i++
+		}
+	}
+}
+
+// recordTitles attaches the setlist titles to the exported segments, in the
+// same order renameFilesFromSetlist applies them.
+func (r *runReport) recordTitles(titles []string) {
+	i := 0
+	for j := range r.Segments {
+		if r.Segments[j].Status == statusExported && i < len(titles) {
+			r.Segments[j].Title = titles[i]
+			i++
+		}
+	}
+}
+
+// finish stamps the overall result and total run time. A run with any
+// failed segment isn't a success, even if the rest went through.
+func (r *runReport) finish(runErr error) {
+	r.TotalSeconds = time.Since(r.StartedAt).Seconds()
+	r.FailedSegments = 0
+	for _, seg := range r.Segments {
+		if seg.Status == statusFailed {
+			r.FailedSegments++
+		}
+	}
+	r.Success = runErr == nil && r.FailedSegments == 0
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+}
+
+// withFileTimes returns a copy of the report for -relative-timestamps, with
+// each segment's times relative to its own file: every start is 0 and every
+// end is the segment's length.
+func (r *runReport) withFileTimes() *runReport {
+	c := *r
+	c.Timestamps = timestampsFile
+	c.Segments = fileTimes(r.Segments)
+	c.Skipped = fileTimes(r.Skipped)
+	c.Chatter = fileTimes(r.Chatter)
+	return &c
+}
+
+func fileTimes(results []segmentResult) []segmentResult {
+	if results == nil {
+		return nil
+	}
+	shifted := make([]segmentResult, len(results))
+	for i, res := range results {
+		res.Start, res.End = 0, res.End-res.Start
+		shifted[i] = res
+	}
+	return shifted
+}
+
+// writeReport writes the report as indented JSON, creating parent folders.
+func writeReport(path string, r *runReport) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// loadManifest reads the songs back out of a report written by a previous
+// run, so a run can be repeated without detection. Failed segments are
+// included, since re-cutting them is often the point. The titles are nil
+// unless the earlier run had a setlist.
+func loadManifest(path string) ([]segment, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var r runReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, nil, err
+	}
+	if len(r.Segments) == 0 {
+		return nil, nil, errors.New("manifest has no segments")
+	}
+	if r.Timestamps == timestampsFile {
+		return nil, nil, errors.New("manifest was written with -relative-timestamps, so it doesn't say where the songs are in the recording")
+	}
+	segments := make([]segment, len(r.Segments))
+	titles := make([]string, len(r.Segments))
+	hasTitles := false
+	for i, s := range r.Segments {
+		// Songs are numbered by position when exported again, so a report of
+		// some songs only (from -only) would renumber them and overwrite the
+		// wrong files. Reports from before Index was recorded have none.
+		if s.Index != 0 && s.Index != i+1 {
+			return nil, nil, fmt.Errorf("manifest lists song %d as number %d, so it only covers some songs (written with -only?); use the report of a run that exported them all", i+1, s.Index)
+		}
+		segments[i] = segment{start: s.Start, end: s.End}
+		titles[i] = s.Title
+		hasTitles = hasTitles || s.Title != ""
+	}
+	if !hasTitles {
+		titles = nil
+	}
+	return segments, titles, nil
+}
+
+// manifestFiles lists the song files a -report-json manifest's run wrote.
+func manifestFiles(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var r runReport
+	if json.Unmarshal(data, &r) != nil {
+		return nil
+	}
+	var files []string
+	for _, s := range r.Segments {
+		if s.File != "" {
+			files = append(files, s.File)
+		}
+	}
+	return files
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunReportOnPartialFailure(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "practice.mp4")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 20\nsilence_end: 30\nsilence_start: 150\nsilence_end: 160\n"}
+		case strings.HasSuffix(args, "-i "+input):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		case strings.HasSuffix(args, "Song_02.mp4"):
+			return fakeResult{stderr: "Invalid data found when processing input", exitCode: 1}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{
+		InputFile:        input,
+		OutputDir:        filepath.Join(dir, "out"),
+		OutputPrefix:     "Song",
+		SilenceThreshold: "-20dB",
+		MinSilenceDur:    5,
+		MinSongLength:    60,
+	}
+	splitter := NewSplitter(cfg)
+	err := splitter.Run()
+	rep := splitter.Report
+	reportPath := filepath.Join(dir, "reports", "run.json")
+	if err := writeReport(reportPath, rep); err != nil {
+		t.Fatalf("writeReport failed: %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Expected report file to be written: %v", err)
+	}
+	var got runReport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	if got.Success || got.FailedSegments != 1 || got.Version != version {
+		t.Errorf("Expected an unsuccessful run with 1 failed segment stamped with version %q, got success=%v failed=%d version=%q", version, got.Success, got.FailedSegments, got.Version)
+	}
+	if len(got.Segments) != 2 {
+		t.Fatalf("Expected 2 song segments, got %+v", got.Segments)
+	}
+	if got.Segments[0].Status != statusExported || got.Segments[1].Status != statusFailed || got.Segments[1].Error == "" {
+		t.Errorf("Expected exported then failed segments, got %+v", got.Segments)
+	}
+	if len(got.Skipped) != 1 || got.Skipped[0].Start != 0 || got.Skipped[0].End != 20 {
+		t.Errorf("Expected the 20s opener to be reported as skipped, got %+v", got.Skipped)
+	}
+	stages := make(map[string]bool)
+	for _, s := range got.Stages {
+		stages[s.Name] = true
+	}
+	for _, name := range []string{"duration", "detection", "export"} {
+		if !stages[name] {
+			t.Errorf("Expected a timing for stage %q, got %+v", name, got.Stages)
+		}
+	}
+}
+
+func TestRunReportOnFatalError(t *testing.T) {
+	installFakeExec(t, nil)
+	cfg := Config{InputFile: filepath.Join(t.TempDir(), "missing.mp4")}
+	splitter := NewSplitter(cfg)
+	err := splitter.Run()
+	rep := splitter.Report
+
+	if err == nil || rep.Success || !strings.Contains(rep.Error, "not found") {
+		t.Errorf("Expected a failed report for a missing input, got success=%v error=%q", rep.Success, rep.Error)
+	}
+}
+
+func TestRunReportRedactsWebhook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	rep := newRunReport(Config{InputFile: "practice.mp4", NotifyWebhook: "https://hooks.slack.com/services/T000/B000/secret"})
+	if err := writeReport(path, rep); err != nil {
+		t.Fatalf("writeReport failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got runReport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	if strings.Contains(string(data), "secret") || got.Config.NotifyWebhook != redacted {
+		t.Errorf("Expected the webhook URL redacted from the report, got %s", data)
+	}
+}
+
+func TestRunReportRedactsRcloneAndHook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	cfg := Config{
+		InputFile:          "practice.mp4",
+		PostHook:           "upload-to-nas --password hunter2",
+		RcloneGlobalFlags:  []string{"--drive-token={\"access_token\":\"hunter2\"}", "--config=/home/band/.rclone.conf"},
+		RcloneRemote:       "private-drive:",
+		DriveSubfolder:     "Band/Private",
+		UploadDestinations: []UploadDestination{{Remote: "nas:", Subfolder: "band"}},
+	}
+	if err := writeReport(path, newRunReport(cfg)); err != nil {
+		t.Fatalf("writeReport failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", ".rclone.conf", "private-drive:", "Band/Private", "nas:"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q redacted from the report, got %s", secret, data)
+		}
+	}
+	if cfg.PostHook == redacted || cfg.UploadDestinations[0].Remote == redacted {
+		t.Error("Expected the run's own config to be left alone")
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	rep := newRunReport(Config{InputFile: "practice.mp4"})
+	rep.Segments = []segmentResult{
+		{Index: 1, Start: 12.5, End: 250, File: "output/Song_01.mp4", Status: statusExported},
+		{Index: 2, Start: 260, End: 480.125, File: "output/Song_02.mp4", Status: statusFailed, Error: "exit status 1"},
+		{Index: 3, Start: 500, End: 700, File: "output/Song_03.mp4", Status: statusExported},
+	}
+	rep.recordTitles([]string{"Opener", "Closer"})
+	if err := writeReport(path, rep); err != nil {
+		t.Fatal(err)
+	}
+
+	segments, titles, err := loadManifest(path)
+	if err != nil {
+		t.Fatalf("loadManifest failed: %v", err)
+	}
+	wantSegments := []segment{{start: 12.5, end: 250}, {start: 260, end: 480.125}, {start: 500, end: 700}}
+	if !reflect.DeepEqual(segments, wantSegments) {
+		t.Errorf("Expected segments %v, got %v", wantSegments, segments)
+	}
+	if wantTitles := []string{"Opener", "", "Closer"}; !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("Expected titles %q, got %q", wantTitles, titles)
+	}
+
+	// A -only 1,3 run's report has gaps in its song numbers.
+	rep.Segments = []segmentResult{rep.Segments[0], rep.Segments[2]}
+	if err := writeReport(path, rep); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadManifest(path); err == nil || !strings.Contains(err.Error(), "only covers some songs") {
+		t.Errorf("Expected a partial manifest to be refused, got %v", err)
+	}
+}
+
+func TestLoadManifestWithoutSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := writeReport(path, newRunReport(Config{})); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadManifest(path); err == nil {
+		t.Error("Expected an error for a manifest with no segments")
+	}
+}
+
+func TestReportTimestamps(t *testing.T) {
+	rep := newRunReport(Config{InputFile: "practice.mp4"})
+	rep.Segments = []segmentResult{
+		{Index: 1, Start: 12.5, End: 250, File: "output/Song_01.mp4", Status: statusExported},
+		{Index: 2, Start: 260, End: 480.125, File: "output/Song_02.mp4", Status: statusExported},
+	}
+	rep.Skipped = []segmentResult{{Start: 250, End: 260, Status: statusSkipped}}
+
+	t.Run("Source", func(t *testing.T) {
+		if rep.Timestamps != timestampsSource || rep.Segments[1].Start != 260 {
+			t.Errorf("Expected source-absolute times by default, got %q %+v", rep.Timestamps, rep.Segments)
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		relative := rep.withFileTimes()
+
+		want := []segmentResult{
+			{Index: 1, Start: 0, End: 237.5, File: "output/Song_01.mp4", Status: statusExported},
+			{Index: 2, Start: 0, End: 220.125, File: "output/Song_02.mp4", Status: statusExported},
+		}
+		if relative.Timestamps != timestampsFile || !reflect.DeepEqual(relative.Segments, want) {
+			t.Errorf("Expected %+v, got %q %+v", want, relative.Timestamps, relative.Segments)
+		}
+		if relative.Skipped[0].End != 10 {
+			t.Errorf("Expected skipped segments shifted too, got %+v", relative.Skipped)
+		}
+		if rep.Segments[0].Start != 12.5 {
+			t.Error("Expected the original report to keep its source times")
+		}
+
+		path := filepath.Join(t.TempDir(), "run.json")
+		if err := writeReport(path, relative); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := loadManifest(path); err == nil || !strings.Contains(err.Error(), "-relative-timestamps") {
+			t.Errorf("Expected -from-manifest to refuse file-relative times, got %v", err)
+		}
+	})
+}
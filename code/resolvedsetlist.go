@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// resolvedSetlistFile is the file in OutputDir recording how the setlist was
+// applied.
+const resolvedSetlistFile = "setlist_resolved.txt"
+
+// renameRecord is one line of the resolved setlist: a song, the title it
+// got and the file it ended up in.
+type renameRecord struct {
+	Number int    // 1-based song number; 0 for a title with no song
+	Title  string // "" if the song got no title
+	File   string // final path; "" if the song wasn't exported
+}
+
+// resolvedSetlist pairs each export result with the title and file it ended
+// up with, followed by any setlist titles past the songs songs found.
+func resolvedSetlist(results []segmentResult, titles []string, songs int) []renameRecord {
+	var mapping []renameRecord
+	for i, r := range results {
+		rec := renameRecord{Number: r.Index, Title: r.Title}
+		if rec.Number == 0 {
+			rec.Number = i + 1
+		}
+		if r.Status == statusExported {
+			rec.File = r.File
+		}
+		mapping = append(mapping, rec)
+	}
+	for i := songs; i < len(titles); i++ {
+		mapping = append(mapping, renameRecord{Title: titles[i]})
+	}
+	return mapping
+}
+
+// writeResolvedSetlist writes mapping into dir as setlist_resolved.txt, so
+// the folder records which song got which title and file, mismatches
+// included.
+func writeResolvedSetlist(dir string, mapping []renameRecord) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, "# Setlist as applied: song number, title, file")
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	untitled, unmatched := 0, 0
+	for _, rec := range mapping {
+		number, title, file := "--", rec.Title, "(no song)"
+		if rec.Number > 0 {
+			number = fmt.Sprintf("%02d", rec.Number)
+			file = "(not exported)"
+			if rec.File != "" {
+				file = filepath.Base(rec.File)
+			}
+		} else {
+			unmatched++
+		}
+		if strings.TrimSpace(title) == "" {
+			title = "(no title)"
+			untitled++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", number, title, file)
+	}
+	tw.Flush()
+	if untitled > 0 || unmatched > 0 {
+		fmt.Fprintf(&b, "# Mismatch: %d song(s) without a title, %d title(s) without a song.\n", untitled, unmatched)
+	}
+	return os.WriteFile(filepath.Join(dir, resolvedSetlistFile), []byte(b.String()), 0644)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolvedSetlist(t *testing.T) {
+	results := []segmentResult{
+		{Index: 1, File: "/out/01 - Reba.mp4", Title: "Reba", Status: statusExported},
+		{Index: 2, Status: statusFailed},
+	}
+
+	got := resolvedSetlist(results, []string{"Reba", "Sabotage", "Kid Charlemagne"}, 2)
+
+	want := []renameRecord{
+		{Number: 1, Title: "Reba", File: "/out/01 - Reba.mp4"},
+		{Number: 2},
+		{Title: "Kid Charlemagne"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestWriteResolvedSetlist(t *testing.T) {
+	dir := t.TempDir()
+	mapping := []renameRecord{
+		{Number: 1, Title: "Reba", File: filepath.Join(dir, "01 - Reba.mp4")},
+		{Number: 2, Title: "Kid Charlemagne", File: filepath.Join(dir, "02 - Kid_Charlemagne.mp4")},
+		{Number: 3, File: filepath.Join(dir, "Song_03.mp4")},
+		{Number: 4, Title: "Sabotage"},
+		{Title: "Wilson"},
+	}
+
+	if err := writeResolvedSetlist(dir, mapping); err != nil {
+		t.Fatalf("writeResolvedSetlist failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, resolvedSetlistFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Setlist as applied: song number, title, file\n" +
+		"01  Reba             01 - Reba.mp4\n" +
+		"02  Kid Charlemagne  02 - Kid_Charlemagne.mp4\n" +
+		"03  (no title)       Song_03.mp4\n" +
+		"04  Sabotage         (not exported)\n" +
+		"--  Wilson           (no song)\n" +
+		"# Mismatch: 1 song(s) without a title, 1 title(s) without a song.\n"
+	if string(data) != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, data)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"math"
+)
+
+// TimestampRounding values: what song boundaries are rounded to.
+const (
+	roundNone   = "none"
+	roundSecond = "second" // whole seconds
+	roundFrame  = "frame"  // the nearest video frame
+)
+
+// roundTimestamp rounds t for mode. Frame rounding needs the frame rate;
+// without one (fps <= 0) t is returned as is.
+func roundTimestamp(t, fps float64, mode string) float64 {
+	switch mode {
+	case roundSecond:
+		return math.Round(t)
+	case roundFrame:
+		if fps <= 0 {
+			return t
+		}
+		return math.Round(t*fps) / fps
+	default:
+		return t
+	}
+}
+
+// roundSegments rounds each segment's start and end. A segment that would
+// round to nothing keeps its original times.
+func roundSegments(segments []segment, fps float64, mode string) []segment {
+	rounded := make([]segment, len(segments))
+	for i, seg := range segments {
+		r := seg
+		r.start, r.end = roundTimestamp(seg.start, fps, mode), roundTimestamp(seg.end, fps, mode)
+		if r.end <= r.start {
+			r = seg
+		}
+		rounded[i] = r
+	}
+	return rounded
+}
+
+// inputFrameRate returns the frame rate of the input's first video stream,
+// or 0 if ffprobe can't tell.
+func inputFrameRate(path string) float64 {
+	if !isFFprobeInstalled() {
+		return 0
+	}
+	info, err := probeMedia(path)
+	if err != nil {
+		return 0
+	}
+	for _, s := range info.Streams {
+		if s.CodecType != "video" {
+			continue
+		}
+		if fps, ok := parseFrameRate(s.AvgFrameRate); ok {
+			return fps
+		}
+		if fps, ok := parseFrameRate(s.RFrameRate); ok {
+			return fps
+		}
+		return 0
+	}
+	return 0
+}
+
+// applyTimestampRounding rounds the song boundaries for timestamp_rounding,
+// so the exports and every file listing their times (labels, tracklist,
+// report) agree on the rounded values. Frame rounding without a known frame
+// rate falls back to whole seconds.
+func applyTimestampRounding(cfg Config, segments []segment) []segment {
+	mode := cfg.TimestampRounding
+	if mode == "" || mode == roundNone {
+		return segments
+	}
+	var fps float64
+	if mode == roundFrame {
+		if fps = inputFrameRate(cfg.InputFile); fps <= 0 {
+			log.Println("Warning: Could not read the input's frame rate; rounding timestamps to whole seconds instead.")
+			mode = roundSecond
+		}
+	}
+	return roundSegments(segments, fps, mode)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTimestamp(t *testing.T) {
+	testCases := []struct {
+		mode string
+		t    float64
+		fps  float64
+		want float64
+	}{
+		{roundNone, 12.3456, 30, 12.3456},
+		{"", 12.3456, 30, 12.3456},
+		{roundSecond, 12.3456, 0, 12},
+		{roundSecond, 12.5, 0, 13},
+		{roundFrame, 12.3456, 30, 12.3333},
+		{roundFrame, 10.01, 30000.0 / 1001, 10.01},
+		{roundFrame, 12.3456, 0, 12.3456},
+	}
+	for _, tc := range testCases {
+		if got := roundTimestamp(tc.t, tc.fps, tc.mode); math.Abs(got-tc.want) > 0.0001 {
+			t.Errorf("roundTimestamp(%v, %v, %q): expected %v, got %v", tc.t, tc.fps, tc.mode, tc.want, got)
+		}
+	}
+}
+
+func TestRoundSegments(t *testing.T) {
+	got := roundSegments([]segment{{start: 12.4, end: 250.6}, {start: 300.2, end: 300.4}}, 0, roundSecond)
+
+	want := []segment{{start: 12, end: 251}, {start: 300.2, end: 300.4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestApplyTimestampRoundingToFrames(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		if call.name == "ffprobe" && len(call.args) > 0 && call.args[0] == "-version" {
+			return fakeResult{}
+		}
+		return fakeResult{stdout: `{"streams": [{"codec_type": "audio"}, {"codec_type": "video", "avg_frame_rate": "25/1"}]}`}
+	})
+	cfg := Config{InputFile: "practice.mp4", TimestampRounding: roundFrame}
+
+	got := applyTimestampRounding(cfg, []segment{{start: 12.33, end: 250.61}})
+
+	if want := []segment{{start: 12.32, end: 250.6}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestApplyTimestampRoundingWithoutFrameRate(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stdout: `{"streams": [{"codec_type": "audio"}]}`}
+	})
+	cfg := Config{InputFile: "practice.m4a", TimestampRounding: roundFrame}
+
+	got := applyTimestampRounding(cfg, []segment{{start: 12.33, end: 250.61}})
+
+	if want := []segment{{start: 12, end: 251}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected a fallback to whole seconds %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// serveTokenEnv holds -serve's bearer token when -serve-token isn't given,
+// keeping it out of the process list.
+const serveTokenEnv = "SPLITTER_SERVE_TOKEN"
+
+// runSplit runs one split for -serve and returns its report. Tests replace
+// it to serve canned reports without ffmpeg.
+var runSplit = func(cfg Config) (*runReport, error) {
+	splitter := NewSplitter(cfg)
+	splitter.ProgressFunc = logProgress
+	err := splitter.Run()
+	return splitter.Report, err
+}
+
+// splitRequest is the body of a POST /split. Only these settings can come
+// from a request; everything else (hooks, rclone flags, upload targets, the
+// output folder itself) stays as the server was started with, since anyone
+// who can reach the server could otherwise run commands on it.
+type splitRequest struct {
+	InputFile string `json:"input_file"`
+	// SetlistFile must be a relative path inside the server's working
+	// directory. Its extra-args directives are dropped, since they go
+	// straight to ffmpeg.
+	SetlistFile      string  `json:"setlist_file"`
+	SilenceThreshold string  `json:"silence_threshold"`
+	MinSilenceDur    float64 `json:"min_silence_duration"`
+	MinSongLength    float64 `json:"min_song_length"`
+	// OutputSubfolder puts the songs in this folder under the server's
+	// output_dir instead of straight in it.
+	OutputSubfolder string `json:"output_subfolder"`
+}
+
+// config lays the request over base.
+func (req splitRequest) config(base Config) (Config, error) {
+	if req.InputFile == "" || req.InputFile == stdinPath {
+		return base, errors.New("input_file must name the recording to split")
+	}
+	if req.SetlistFile == stdinPath {
+		return base, errors.New("setlist_file can't be stdin")
+	}
+	if req.SetlistFile != "" && !localPath(req.SetlistFile) {
+		return base, fmt.Errorf("setlist_file '%s' must be a relative path inside the server's working directory", req.SetlistFile)
+	}
+	if req.OutputSubfolder != "" && !localPath(req.OutputSubfolder) {
+		return base, fmt.Errorf("output_subfolder '%s' must be a relative path inside the output folder", req.OutputSubfolder)
+	}
+	cfg := mergeFileConfig(base, Config{
+		InputFile:        req.InputFile,
+		SetlistFile:      req.SetlistFile,
+		SilenceThreshold: req.SilenceThreshold,
+		MinSilenceDur:    req.MinSilenceDur,
+		MinSongLength:    req.MinSongLength,
+	})
+	if req.SetlistFile != "" {
+		cfg.IgnoreSetlistArgs = true
+	}
+	if req.OutputSubfolder != "" {
+		cfg.OutputDir = filepath.Join(base.OutputDir, req.OutputSubfolder)
+	}
+	return cfg, nil
+}
+
+// localPath reports whether a path from a request stays below the folder
+// it's resolved against: relative, without "..", and unchanged by the "~"
+// and $VAR expansion checkConfig does.
+func localPath(p string) bool {
+	expanded, err := expandPath(p)
+	return err == nil && expanded == p && filepath.IsLocal(p)
+}
+
+// splitServer handles -serve's HTTP API. Each request's settings are layered
+// over base, the config the server was started with, and must name their own
+// input_file.
+type splitServer struct {
+	base Config
+	// token, if set, must be sent as "Authorization: Bearer <token>".
+	token string
+	// mu runs one split at a time: runs share the log, and would fight over
+	// ffmpeg and the disk anyway.
+	mu sync.Mutex
+}
+
+// newSplitHandler returns the -serve API: POST /split with a splitRequest as
+// its JSON body splits that input and answers with its run report. Requests
+// don't inherit the server's own run flags (-clean-output, -detect-only and
+// the like), and never wait on the server's terminal. With a token, requests
+// without it are refused.
+func newSplitHandler(base Config, token string) http.Handler {
+	base.RunOptions = RunOptions{NonInteractive: true}
+	srv := &splitServer{base: base, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /split", srv.split)
+	return mux
+}
+
+func (srv *splitServer) split(w http.ResponseWriter, r *http.Request) {
+	if !srv.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(w, http.StatusUnauthorized, "missing or wrong bearer token")
+		return
+	}
+	// Browsers can't send application/json cross-site without a CORS
+	// preflight, which this server never answers.
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeJSONError(w, http.StatusUnsupportedMediaType, "the body must be JSON, sent as Content-Type: application/json")
+		return
+	}
+	var body splitRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "could not parse the request: "+err.Error())
+		return
+	}
+	requested, err := body.config(srv.base)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cfg, warnings, err := checkConfig(requested)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, warning := range warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	srv.mu.Lock()
+	rep, runErr := runSplit(cfg)
+	srv.mu.Unlock()
+
+	if cfg.RelativeTimestamps {
+		rep = rep.withFileTimes()
+	}
+	status := http.StatusOK
+	if runErr != nil {
+		log.Printf("Error: %v", runErr)
+		status = http.StatusInternalServerError
+		if exitCodeFor(runErr) == exitConfig {
+			status = http.StatusBadRequest
+		}
+	}
+	writeJSON(w, status, rep) // newRunReport redacted the config
+}
+
+// authorized reports whether r carries the server's bearer token, if it has
+// one.
+func (srv *splitServer) authorized(r *http.Request) bool {
+	if srv.token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(srv.token)) == 1
+}
+
+// writeJSON answers with v as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Warning: Could not write the response: %v", err)
+	}
+}
+
+// writeJSONError answers with {"error": msg}.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// serve answers split requests on addr until interrupted. The first Ctrl+C
+// (or SIGTERM) stops taking new requests and waits for running splits to
+// finish; a second one quits at once.
+func serve(addr string, base Config, token string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if token == "" {
+		log.Printf("Warning: serving without a token; anyone who can reach %s can split files on this machine. Set -serve-token or %s.", addr, serveTokenEnv)
+	}
+	server := &http.Server{
+		Addr:    addr,
+		Handler: newSplitHandler(base, token),
+		// A split can take many minutes, so there's no WriteTimeout; the
+		// read limits only stop a slow or idle client holding a connection.
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- server.ListenAndServe() }()
+	log.Printf("Serving split requests on %s (POST /split).", addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	log.Println("Shutting down after the running split, if any; press Ctrl+C again to quit now.")
+	return server.Shutdown(context.Background())
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// stubRunSplit replaces runSplit for a test, recording the configs it gets.
+func stubRunSplit(t *testing.T, run func(cfg Config) (*runReport, error)) *[]Config {
+	t.Helper()
+	var got []Config
+	original := runSplit
+	runSplit = func(cfg Config) (*runReport, error) {
+		got = append(got, cfg)
+		return run(cfg)
+	}
+	t.Cleanup(func() { runSplit = original })
+	return &got
+}
+
+func postSplit(handler http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/split", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeSplit(t *testing.T) {
+	configs := stubRunSplit(t, func(cfg Config) (*runReport, error) {
+		rep := newRunReport(cfg)
+		rep.Segments = []segmentResult{{Index: 1, Start: 12.5, End: 250, File: "Song_01.mp4", Status: statusExported}}
+		rep.finish(nil)
+		return rep, nil
+	})
+	base := defaultConfig
+	base.OutputPrefix = "Rehearsal"
+
+	rec := postSplit(newSplitHandler(base, ""), `{"input_file": "practice.mp4", "min_song_length": 90}`)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a 200 JSON response, got %d %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	var resp struct {
+		Success  bool `json:"success"`
+		Segments []struct {
+			Start  float64 `json:"start"`
+			End    float64 `json:"end"`
+			File   string  `json:"file"`
+			Status string  `json:"status"`
+		} `json:"segments"`
+		Config Config `json:"config"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Response isn't JSON: %v", err)
+	}
+	if !resp.Success || len(resp.Segments) != 1 || resp.Segments[0].Start != 12.5 || resp.Segments[0].File != "Song_01.mp4" {
+		t.Errorf("Unexpected report %+v", resp)
+	}
+	if len(*configs) != 1 {
+		t.Fatalf("Expected one split, got %d", len(*configs))
+	}
+	if cfg := (*configs)[0]; cfg.InputFile != "practice.mp4" || cfg.MinSongLength != 90 || cfg.OutputPrefix != "Rehearsal" {
+		t.Errorf("Expected the request layered over the server's config, got %+v", cfg)
+	}
+}
+
+func TestServeSplitFailure(t *testing.T) {
+	stubRunSplit(t, func(cfg Config) (*runReport, error) {
+		rep := newRunReport(cfg)
+		err := withExitCode(exitDetectionFailed, errors.New("no songs found"))
+		rep.finish(err)
+		return rep, err
+	})
+
+	rec := postSplit(newSplitHandler(defaultConfig, ""), `{"input_file": "practice.mp4"}`)
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"error":"no songs found"`) {
+		t.Errorf("Expected a 500 with the failed report, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestServeRejectsBadRequests(t *testing.T) {
+	configs := stubRunSplit(t, func(cfg Config) (*runReport, error) { return newRunReport(cfg), nil })
+	handler := newSplitHandler(defaultConfig, "")
+
+	for _, body := range []string{
+		`not json`,
+		`{"output_subfolder": "tue"}`,
+		`{"input_file": "-"}`,
+		`{"input_file": "practice.mp4", "setlist_file": "-"}`,
+		`{"input_file": "practice.mp4", "setlist_file": "/etc/setlist.txt"}`,
+		`{"input_file": "practice.mp4", "setlist_file": "../setlist.txt"}`,
+		`{"input_file": "practice.mp4", "setlist_file": "~/setlist.txt"}`,
+		`{"input_file": "practice.mp4", "setlist_file": "$HOME/setlist.txt"}`,
+		`{"input_file": "practice.mp4", "output_dir": "/"}`,
+		`{"input_file": "practice.mp4", "post_hook": "rm -rf ~"}`,
+		`{"input_file": "practice.mp4", "output_subfolder": "../elsewhere"}`,
+		`{"input_file": "practice.mp4", "output_subfolder": "/tmp"}`,
+	} {
+		if rec := postSplit(handler, body); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"error"`) {
+			t.Errorf("%s: expected a 400 with an error, got %d: %s", body, rec.Code, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/split", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be refused, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/split", strings.NewReader(`{"input_file": "practice.mp4"}`)))
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected a body without Content-Type: application/json to be refused, got %d", rec.Code)
+	}
+	if len(*configs) != 0 {
+		t.Errorf("Expected no splits, got %d", len(*configs))
+	}
+}
+
+func TestServeSplitOutputSubfolder(t *testing.T) {
+	configs := stubRunSplit(t, func(cfg Config) (*runReport, error) { return newRunReport(cfg), nil })
+	base := defaultConfig
+	base.OutputDir = "songs"
+
+	postSplit(newSplitHandler(base, ""), `{"input_file": "practice.mp4", "output_subfolder": "2024/tue"}`)
+
+	if len(*configs) != 1 || (*configs)[0].OutputDir != filepath.Join("songs", "2024", "tue") {
+		t.Errorf("Expected the songs under the server's output folder, got %+v", *configs)
+	}
+}
+
+func TestServeRequiresToken(t *testing.T) {
+	configs := stubRunSplit(t, func(cfg Config) (*runReport, error) { return newRunReport(cfg), nil })
+	handler := newSplitHandler(defaultConfig, "s3cret")
+	testCases := []struct {
+		name, auth string
+		want       int
+	}{
+		{"Missing", "", http.StatusUnauthorized},
+		{"Wrong", "Bearer guess", http.StatusUnauthorized},
+		{"NotBearer", "Basic s3cret", http.StatusUnauthorized},
+		{"Right", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/split", strings.NewReader(`{"input_file": "practice.mp4"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("Expected %d, got %d: %s", tc.want, rec.Code, rec.Body)
+			}
+		})
+	}
+	if len(*configs) != 1 {
+		t.Errorf("Expected only the authorized request to split, got %d", len(*configs))
+	}
+}
+
+func TestServeRedactsServerConfig(t *testing.T) {
+	stubRunSplit(t, func(cfg Config) (*runReport, error) { return newRunReport(cfg), nil })
+	base := defaultConfig
+	base.PostHook = "notify-band --key abc123"
+	base.RcloneGlobalFlags = []string{"--drive-client-secret", "abc123"}
+	base.UploadDestinations = []UploadDestination{{Remote: "gdrive:", Subfolder: "Band"}}
+
+	rec := postSplit(newSplitHandler(base, ""), `{"input_file": "practice.mp4"}`)
+
+	if strings.Contains(rec.Body.String(), "abc123") || strings.Contains(rec.Body.String(), "gdrive:") {
+		t.Errorf("Expected the server's hook, rclone flags and destinations redacted, got %s", rec.Body)
+	}
+}
+
+func TestServeDropsSetlistExtraArgs(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("practice.mp4", []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	evil := filepath.Join(dir, "evil.mp4")
+	if err := os.WriteFile("setlist.txt", []byte("Reba | extra-args: -y "+evil+"\nTom\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 140\nsilence_end: 150\n"}
+		case strings.HasSuffix(args, "-i practice.mp4"):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+	base := defaultConfig
+	base.OutputDir, base.SilenceThreshold, base.MinSilenceDur, base.MinSongLength = "out", "-20dB", 5, 60
+
+	rec := postSplit(newSplitHandler(base, ""), `{"input_file": "practice.mp4", "setlist_file": "setlist.txt"}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the split to run, got %d: %s", rec.Code, rec.Body)
+	}
+	exports := 0
+	for _, call := range fake.calls {
+		if slices.Contains(call.args, evil) {
+			t.Errorf("Expected the request's setlist extra-args to be dropped, got %q", call.args)
+		}
+		if slices.Contains(call.args, "-t") {
+			exports++
+		}
+	}
+	if exports != 2 {
+		t.Errorf("Expected 2 exports, got %d", exports)
+	}
+}
+
+func TestServeFailsUnreachablePrecheck(t *testing.T) {
+	oldAttempts, oldDelay := precheckAttempts, precheckRetryDelay
+	precheckAttempts, precheckRetryDelay = 1, 0
+	t.Cleanup(func() { precheckAttempts, precheckRetryDelay = oldAttempts, oldDelay })
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if call.name == "rclone" && slices.Contains(call.args, "mkdir") {
+			return fakeResult{stderr: "dial tcp: i/o timeout\n", exitCode: 1}
+		}
+		return fakeResult{}
+	})
+	base := defaultConfig
+	base.UploadToDrive, base.RcloneRemote = true, "gdrive:"
+
+	rec := postSplit(newSplitHandler(base, ""), `{"input_file": "practice.mp4"}`)
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "pre-check failed") {
+		t.Errorf("Expected the served run to fail on an unreachable remote, got %d: %s", rec.Code, rec.Body)
+	}
+	for _, call := range fake.calls {
+		if call.name == "ffmpeg" {
+			t.Errorf("Expected nothing exported, got ffmpeg %q", call.args)
+		}
+	}
+}
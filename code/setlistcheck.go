@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printSetlistCheck lists the title each detected song would be renamed to,
+// and returns an error describing any mismatch between the song and title
+// counts.
+func printSetlistCheck(out io.Writer, segments []segment, titles []string) error {
+	for i, seg := range segments {
+		title := "(no title)"
+		if i < len(titles) && strings.TrimSpace(titles[i]) != "" {
+			title = titles[i]
+		}
+		fmt.Fprintf(out, "%3d. %9.2fs - %9.2fs  %s\n", i+1, seg.start, seg.end, title)
+	}
+	for i := len(segments); i < len(titles); i++ {
+		fmt.Fprintf(out, "     %-24s  %s\n", "(no song)", titles[i])
+	}
+
+	err := setlistMismatch(len(segments), len(titles))
+	if err != nil {
+		fmt.Fprintf(out, "Mismatch: %v.\n", err)
+	} else {
+		fmt.Fprintf(out, "Setlist matches: %d song(s).\n", len(segments))
+	}
+	return err
+}
+
+// setlistMismatch returns an error unless there is one title per song.
+func setlistMismatch(songs, titles int) error {
+	if songs == titles {
+		return nil
+	}
+	return fmt.Errorf("setlist has %d title(s) but %d song(s) were found", titles, songs)
+}
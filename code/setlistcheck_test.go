@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintSetlistCheck(t *testing.T) {
+	segs := []segment{{start: 12.5, end: 250}, {start: 260, end: 480}}
+
+	var out strings.Builder
+	if err := printSetlistCheck(&out, segs, []string{"Opener", "Closer"}); err != nil {
+		t.Errorf("Expected matching counts to pass, got %v", err)
+	}
+	if want := "  1.     12.50s -    250.00s  Opener\n"; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("Expected the listing to start with %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := printSetlistCheck(&out, segs, []string{"Opener", "", "Encore"}); err == nil {
+		t.Error("Expected an error for 3 titles and 2 songs")
+	}
+	for _, want := range []string{"(no title)", "(no song)                 Encore", "Mismatch: setlist has 3 title(s) but 2 song(s) were found."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the listing, got %q", want, out.String())
+		}
+	}
+}
+
+func TestCheckSetlistSkipsExport(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "practice.mp4")
+	list := filepath.Join(dir, "setlist.txt")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(list, []byte("Only Song\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 140\nsilence_end: 150\n"}
+		case strings.HasSuffix(args, "-i "+input):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+
+	cfg := Config{InputFile: input, SetlistFile: list, OutputDir: filepath.Join(dir, "out"), OutputPrefix: "Song", SilenceThreshold: "-20dB", MinSilenceDur: 5, MinSongLength: 60, UploadToDrive: true}
+	cfg.CheckSetlist, cfg.StrictSetlist = true, true
+	err := NewSplitter(cfg).Run()
+
+	if code := exitCodeFor(err); code != exitSetlistMismatch {
+		t.Errorf("Expected exit code %d for 1 title and 2 songs, got %d (%v)", exitSetlistMismatch, code, err)
+	}
+	for _, call := range fake.calls {
+		if call.name == "rclone" || strings.Contains(strings.Join(call.args, " "), "Song_") {
+			t.Errorf("Expected no export or upload in a setlist check, got %s %v", call.name, call.args)
+		}
+	}
+}
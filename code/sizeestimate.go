@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Rough rates for size estimates of re-encodes that don't name a bitrate.
+const (
+	estimateAudioRate = 128000 // ffmpeg's AAC and MP3 default
+	estimateFLACRate  = 800000 // typical for a stereo live recording
+	// estimateBitsPerPixel is the x264 rate at estimateCRF, in bits per
+	// pixel per frame; every 6 CRF steps halve or double it.
+	estimateBitsPerPixel = 0.08
+	estimateCRF          = 23
+)
+
+// sizeEstimate is the bitrate exports are expected to come out at.
+type sizeEstimate struct {
+	bitsPerSecond float64
+	crf           bool // the video rate is a CRF guess, so less reliable
+}
+
+// parseBitrate parses an ffmpeg bitrate such as "192k", "4M" or "128000"
+// into bits per second.
+func parseBitrate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		mult, s = 1e3, s[:len(s)-1]
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1e6, s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid bitrate %q", s)
+	}
+	return v * mult, nil
+}
+
+// estimateBitrate works out the output bitrate from the input's probe. A
+// stream copy keeps the source's bit_rate; a re-encode uses video_bitrate
+// and audio_bitrate when given, and otherwise guesses from the resolution
+// and CRF.
+func estimateBitrate(cfg Config, info probeResult) (sizeEstimate, error) {
+	if !reencoding(cfg) && !cfg.TrimSilence {
+		rate, err := parseBitrate(info.Format.BitRate)
+		if err != nil {
+			return sizeEstimate{}, fmt.Errorf("the input has no usable bit_rate")
+		}
+		return sizeEstimate{bitsPerSecond: rate}, nil
+	}
+
+	_, audioCodec := exportCodecs(cfg)
+	audio := float64(estimateAudioRate)
+	if audioCodec == "flac" {
+		audio = estimateFLACRate
+	}
+	if r, err := parseBitrate(cfg.AudioBitrate); err == nil {
+		audio = r
+	}
+	var est sizeEstimate
+	for _, s := range info.Streams {
+		if s.CodecType == "audio" {
+			est.bitsPerSecond += audio
+			if !cfg.MapAllAudio {
+				break
+			}
+		}
+	}
+	if audioOnlyOutput(cfg) {
+		return est, nil
+	}
+
+	for _, s := range info.Streams {
+		if s.CodecType != "video" {
+			continue
+		}
+		if !reencoding(cfg) {
+			// Only the audio is re-encoded (trim_silence); the video is copied.
+			if r, err := parseBitrate(s.BitRate); err == nil {
+				est.bitsPerSecond += r
+			}
+			break
+		}
+		if cfg.VideoCRF <= 0 && cfg.VideoBitrate != "" {
+			if r, err := parseBitrate(cfg.VideoBitrate); err == nil {
+				est.bitsPerSecond += r
+				break
+			}
+		}
+		crf := cfg.VideoCRF
+		if crf <= 0 {
+			crf = defaultVideoCRF
+		}
+		fps, ok := parseFrameRate(s.AvgFrameRate)
+		if !ok {
+			fps = 30
+		}
+		bpp := estimateBitsPerPixel * math.Pow(2, float64(estimateCRF-crf)/6)
+		est.bitsPerSecond += float64(s.Width*s.Height) * fps * bpp
+		est.crf = true
+		break
+	}
+	return est, nil
+}
+
+// printSizeEstimates lists the rough output size of each segment and their
+// total for -check-setlist.
+func printSizeEstimates(out io.Writer, segments []segment, est sizeEstimate) {
+	fmt.Fprintf(out, "Estimated output size (about %d kb/s):\n", int(est.bitsPerSecond/1000))
+	var total int64
+	for i, seg := range segments {
+		size := int64(est.bitsPerSecond / 8 * (seg.end - seg.start))
+		total += size
+		fmt.Fprintf(out, "%3d. ~%s\n", i+1, humanizeBytes(size))
+	}
+	note := "approximate"
+	if est.crf {
+		note = "approximate; CRF re-encodes vary a lot with the content"
+	}
+	fmt.Fprintf(out, "Total: ~%s (%s).\n", humanizeBytes(total), note)
+}
+
+// printSizeEstimate probes the input and prints the size estimates, or says
+// why it can't.
+func printSizeEstimate(out io.Writer, cfg Config, segments []segment) {
+	if !isFFprobeInstalled() {
+		fmt.Fprintln(out, "Output size estimate skipped: ffprobe not found.")
+		return
+	}
+	info, err := probeMedia(cfg.InputFile)
+	if err == nil {
+		var est sizeEstimate
+		if est, err = estimateBitrate(cfg, info); err == nil {
+			printSizeEstimates(out, segments, est)
+			return
+		}
+	}
+	fmt.Fprintf(out, "Output size estimate skipped: %v.\n", err)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseBitrate(t *testing.T) {
+	for in, want := range map[string]float64{"192k": 192000, "4M": 4e6, "128000": 128000, "1.5M": 1.5e6} {
+		if got, err := parseBitrate(in); err != nil || got != want {
+			t.Errorf("parseBitrate(%q): expected %v, got %v (%v)", in, want, got, err)
+		}
+	}
+	for _, in := range []string{"", "fast", "-1k"} {
+		if _, err := parseBitrate(in); err == nil {
+			t.Errorf("Expected an error for %q", in)
+		}
+	}
+}
+
+func TestEstimateBitrate(t *testing.T) {
+	info := probeResult{
+		Streams: []probeStream{
+			{CodecType: "video", Width: 1920, Height: 1080, AvgFrameRate: "30/1", BitRate: "6000000"},
+			{CodecType: "audio", BitRate: "192000"},
+		},
+		Format: probeFormat{BitRate: "6200000"},
+	}
+	testCases := []struct {
+		name string
+		cfg  Config
+		want float64
+		crf  bool
+	}{
+		{"Copy", Config{InputFile: "practice.mp4"}, 6200000, false},
+		{"Bitrates", Config{InputFile: "practice.mp4", Reencode: true, VideoBitrate: "4M", AudioBitrate: "192k"}, 4192000, false},
+		{"CRF", Config{InputFile: "practice.mp4", Reencode: true, VideoCRF: 23}, 1920*1080*30*estimateBitsPerPixel + estimateAudioRate, true},
+		{"AudioOnly", Config{InputFile: "practice.mp4", OutputContainer: "mp3", AudioBitrate: "320k"}, 320000, false},
+		{"TrimSilence", Config{InputFile: "practice.mp4", TrimSilence: true}, 6000000 + estimateAudioRate, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			est, err := estimateBitrate(tc.cfg, info)
+			if err != nil {
+				t.Fatalf("estimateBitrate failed: %v", err)
+			}
+			if math.Abs(est.bitsPerSecond-tc.want) > 1 || est.crf != tc.crf {
+				t.Errorf("Expected %.0f b/s (crf %v), got %.0f (crf %v)", tc.want, tc.crf, est.bitsPerSecond, est.crf)
+			}
+		})
+	}
+
+	if _, err := estimateBitrate(Config{InputFile: "practice.mp4"}, probeResult{}); err == nil {
+		t.Error("Expected an error for a stream copy without a source bit_rate")
+	}
+}
+
+func TestPrintSizeEstimates(t *testing.T) {
+	var out strings.Builder
+	printSizeEstimates(&out, []segment{{start: 0, end: 120}, {start: 130, end: 250}}, sizeEstimate{bitsPerSecond: 2000000, crf: true})
+
+	for _, want := range []string{"about 2000 kb/s", "  1. ~29 MB\n", "  2. ~29 MB\n", "Total: ~57 MB (approximate; CRF"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in\n%s", want, out.String())
+		}
+	}
+}
@@ -4,29 +4,200 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Config holds all our settings.
 type Config struct {
-	InputFile        string  `json:"input_file"`
-	MinSilenceDur    float64 `json:"min_silence_duration"`
-	SilenceThreshold string  `json:"silence_threshold"`
-	MinSongLength    float64 `json:"min_song_length"`
-	OutputPrefix     string  `json:"output_prefix"`
-	OutputDir        string  `json:"output_dir"`
-	UploadToDrive    bool    `json:"upload_to_drive"`
-	RcloneRemote     string  `json:"rclone_remote"`
-	DriveSubfolder   string  `json:"drive_subfolder"`
-	SetlistFile      string  `json:"setlist_file"`
+	InputFile           string  `json:"input_file"`
+	MinSilenceDur       float64 `json:"min_silence_duration"`
+	SilenceThreshold    string  `json:"silence_threshold"`
+	MinSongLength       float64 `json:"min_song_length"`
+	OutputPrefix        string  `json:"output_prefix"`
+	OutputDir           string  `json:"output_dir"`
+	UploadToDrive       bool    `json:"upload_to_drive"`
+	RcloneRemote        string  `json:"rclone_remote"`
+	DriveSubfolder      string  `json:"drive_subfolder"`
+	SetlistFile         string  `json:"setlist_file"`
+	MapAllAudio         bool    `json:"map_all_audio"`
+	MinExpectedSegments int     `json:"min_expected_segments"`
+	MaxExpectedSegments int     `json:"max_expected_segments"`
+	ExportChatter       bool    `json:"export_chatter"`
+	Reencode            bool    `json:"reencode"`
+	VideoCRF            int     `json:"video_crf"`
+	VideoBitrate        string  `json:"video_bitrate"`
+	AudioBitrate        string  `json:"audio_bitrate"`
+	AutoTrim            bool    `json:"auto_trim"`
+	FFmpegLogLevel      string  `json:"ffmpeg_log_level"`
+	MonoDetection       bool    `json:"mono_detection"`
+	NotifyWebhook       string  `json:"notify_webhook"`
+	NotifyFormat        string  `json:"notify_format"`
+	NoSplit             bool    `json:"no_split"`
+	DetectionMode       string  `json:"detection_mode"`
+	SeekMode            string  `json:"seek_mode"`
+	AudacityLabels      bool    `json:"audacity_labels"`
+	OutputMode          string  `json:"output_mode"`
+	LosslessBoundaries  bool    `json:"lossless_boundaries"`
+	SourceChapters      bool    `json:"source_chapters"`
+	TrimSilence         bool    `json:"trim_silence"`
+	Cache               bool    `json:"cache"`
+	UploadMode          string  `json:"upload_mode"`
+	LoudnessReport      bool    `json:"loudness_report"`
+	OutputContainer     string  `json:"output_container"`
+	Tracklist           string  `json:"tracklist"`
+	SnapKeyframes       bool    `json:"snap_keyframes"`
+	AutoTune            bool    `json:"auto_tune"`
+	ExpectedSongs       int     `json:"expected_songs"`
+	BoundariesFile      string  `json:"boundaries_file"`
+	OutputFlat          bool    `json:"output_flat"`
+	ProofScale          string  `json:"proof_scale"`
+	TempDir             string  `json:"temp_dir"`
+	ReplayGain          bool    `json:"replay_gain"`
+	TargetCount         int     `json:"target_count"`
+	Artist              string  `json:"artist"`
+	Album               string  `json:"album"`
+	// MetadataTemplates maps tag names to text/template strings rendered per
+	// song (see metadataFields), e.g. {"album": "{{.Album}} {{.Date}}"}.
+	MetadataTemplates map[string]string `json:"metadata_templates"`
+	HandleVFR         string            `json:"handle_vfr"`
+	KeepSubtitles     bool              `json:"keep_subtitles"`
+	LogFile           string            `json:"log_file"`
+	// RcloneGlobalFlags are passed to every rclone command the tool runs,
+	// e.g. ["--fast-list", "--drive-acknowledge-abuse"].
+	RcloneGlobalFlags []string `json:"rclone_global_flags"`
+	CoverArt          string   `json:"cover_art"`
+	ExportGaps        bool     `json:"export_gaps"`
+	GapsName          string   `json:"gaps_name"`
+	Jobs              int      `json:"jobs"`
+	FallbackInterval  float64  `json:"fallback_interval"`
+	FallbackMinSongs  int      `json:"fallback_min_songs"`
+	MultiThreshold    bool     `json:"multi_threshold"`
+	// CandidateThresholds are the thresholds multi_threshold tries (default
+	// -30, -24, -18 and -12 dB).
+	CandidateThresholds    []string `json:"candidate_thresholds"`
+	Archive                string   `json:"archive"`
+	Checksums              bool     `json:"checksums"`
+	PreserveAbsoluteTiming bool     `json:"preserve_absolute_timing"`
+	VerifyDurations        bool     `json:"verify_durations"`
+	DurationTolerance      float64  `json:"duration_tolerance"`
+	RecutDurations         bool     `json:"recut_durations"`
+	AnalysisWindows        string   `json:"analysis_windows"`
+	SessionName            string   `json:"session_name"`
+	FullSet                bool     `json:"full_set"`
+	PostHook               string   `json:"post_hook"`
+	OnNoSongs              string   `json:"on_no_songs"`
+	ChunkLength            float64  `json:"chunk_length"`
+	HWAccel                string   `json:"hw_accel"`
+	UploadNoTraverse       bool     `json:"upload_no_traverse"`
+	UploadImmutable        bool     `json:"upload_immutable"`
+	TimestampRounding      string   `json:"timestamp_rounding"`
+	SkipLeadIn             bool     `json:"skip_lead_in"`
+	LeadInThreshold        string   `json:"lead_in_threshold"`
+	EmbedTitleMetadata     bool     `json:"embed_title_metadata"`
+	BoundarySilenceDur     float64  `json:"boundary_silence_duration"`
+	HandleDTSWarnings      bool     `json:"handle_dts_warnings"`
+	FixedInterval          float64  `json:"fixed_interval"`
+	// UploadDestinations lists every place to upload to. When empty, the
+	// single RcloneRemote/DriveSubfolder pair is used.
+	UploadDestinations []UploadDestination `json:"upload_destinations"`
+	// RunOptions come from the command line only, never from a config file
+	// or a -serve request.
+	RunOptions `json:"-"`
+
+	// streamsCopyable is set once ffprobe has found that the input's streams
+	// fit OutputContainer as they are, so changing container needn't
+	// re-encode.
+	streamsCopyable bool
+	// genPTS regenerates the input's timestamps (-fflags +genpts), for
+	// re-cutting a song that handle_dts_warnings caught.
+	genPTS bool
+	// audioFiltered is set for an export whose extra args filter the audio,
+	// which then has to be re-encoded.
+	audioFiltered bool
+	// stdinSetlist holds a "-" setlist a batch read once for all its
+	// inputs, since only the first could read stdin itself.
+	stdinSetlist []byte
+	// skipUpload leaves the upload to the batch, which uploads the folder
+	// its -output-flat inputs share once after the last of them.
+	skipUpload bool
+}
+
+// RunOptions are the per-run switches that aren't settings: what to do with
+// this one run rather than how to split. main sets them from flags; a
+// library caller sets them on Config directly.
+type RunOptions struct {
+	Interactive        bool   // -interactive: edit the boundaries before export
+	ManifestPath       string // -from-manifest: take the songs from a report
+	ForceExport        bool   // -force: with Cache, ignore the saved state
+	ConfirmSync        bool   // -confirm-sync: allow upload_mode sync
+	CheckSetlist       bool   // -check-setlist: compare with the setlist and stop
+	StrictSetlist      bool   // -strict-setlist: fail on a setlist mismatch
+	NoDetectCache      bool   // -no-cache: always run detection
+	NoCopyFallback     bool   // -no-copy-fallback: don't retry failed re-encodes with copy
+	OnlySongs          string // -only: song numbers to export
+	OrderedLogs        bool   // -ordered-logs: keep each song's log lines together
+	CleanOutput        bool   // -clean-output: empty OutputDir first
+	FailIfNotEmpty     bool   // -fail-if-not-empty: stop if OutputDir has files
+	DetectOnly         bool   // -detect-only: print the boundaries and stop
+	JSONOutput         bool   // -json: print -detect-only's boundaries as JSON
+	MergeSongs         string // -merge: two adjacent song numbers to join
+	RelativeTimestamps bool   // -relative-timestamps: report times per file
+	// NonInteractive never asks on the terminal, as for -serve's runs: an
+	// unreachable remote fails the pre-check instead of prompting.
+	NonInteractive bool
+	// IgnoreSetlistArgs drops the setlist's extra-args directives, for a
+	// setlist named by a -serve request rather than the operator.
+	IgnoreSetlistArgs bool
+	// ConfigFiles are the -config files the settings were read from, which
+	// CleanOutput never deletes.
+	ConfigFiles []string
+}
+
+// runOptionsFromFlags collects the RunOptions given on the command line.
+func runOptionsFromFlags() RunOptions {
+	return RunOptions{
+		Interactive:        interactiveMode,
+		ManifestPath:       manifestPath,
+		ForceExport:        forceExport,
+		ConfirmSync:        confirmSync,
+		CheckSetlist:       checkSetlist,
+		StrictSetlist:      strictSetlist,
+		NoDetectCache:      noDetectCache,
+		NoCopyFallback:     noCopyFallback,
+		OnlySongs:          onlySongs,
+		OrderedLogs:        orderedLogs,
+		CleanOutput:        cleanOutput,
+		FailIfNotEmpty:     failIfNotEmpty,
+		DetectOnly:         detectOnly,
+		JSONOutput:         jsonOutput,
+		MergeSongs:         mergeSongs,
+		RelativeTimestamps: relativeTimestamps,
+		ConfigFiles:        strings.Split(configFilePath, ","),
+	}
+}
+
+// UploadDestination is one rclone remote and folder to upload to.
+type UploadDestination struct {
+	Remote    string `json:"remote"`
+	Subfolder string `json:"subfolder"`
+}
+
+// path is the destination folder as rclone sees it, e.g. "gdrive:Band/Shows".
+func (d UploadDestination) path() string {
+	return buildRemotePath(d.Remote, d.Subfolder, "")
 }
 
 // segment holds the start and end time of a clip
@@ -35,38 +206,325 @@ type segment struct {
 	end   float64
 }
 
+// segmentResult is the outcome of exporting (or skipping) one segment.
+type segmentResult struct {
+	Index  int     `json:"index,omitempty"` // 1-based song number
+	Start  float64 `json:"start"`
+	End    float64 `json:"end"`
+	File   string  `json:"file,omitempty"`
+	Title  string  `json:"title,omitempty"`
+	Status string  `json:"status"`
+	Error  string  `json:"error,omitempty"`
+	// ErrorLog is the file holding ffmpeg's output for a failed export.
+	ErrorLog string `json:"error_log,omitempty"`
+	// Cached is set when -cache reused the output of an earlier run.
+	Cached bool `json:"cached,omitempty"`
+	// CopyFallback is set when a failed re-encode was exported with stream
+	// copy instead.
+	CopyFallback bool `json:"copy_fallback,omitempty"`
+	// DurationMismatch is how many seconds longer (or, negative, shorter)
+	// than its segment the export came out, when -verify-durations found
+	// it off. Recut is set when it was cut again with an accurate seek.
+	DurationMismatch float64 `json:"duration_mismatch,omitempty"`
+	Recut            bool    `json:"recut,omitempty"`
+	// DTSWarnings is set when ffmpeg warned about non-monotonous DTS while
+	// exporting. DTSFix is how -handle-dts-warnings re-cut the song:
+	// "genpts" or "reencode".
+	DTSWarnings bool   `json:"dts_warnings,omitempty"`
+	DTSFix      string `json:"dts_fix,omitempty"`
+	// Loudness, with -loudness-report or -replaygain.
+	LoudnessLUFS *float64 `json:"loudness_lufs,omitempty"`
+	TruePeakDBFS *float64 `json:"true_peak_dbfs,omitempty"`
+	// ReplayGainDB is the track gain written with -replaygain.
+	ReplayGainDB *float64 `json:"replaygain_db,omitempty"`
+
+	stateKey  string // -cache key: the output path before any rename
+	paramHash string // -cache fingerprint of the source and ffmpeg args
+}
+
+// Segment statuses used in segmentResult.
+const (
+	statusExported = "exported"
+	statusFailed   = "failed"
+	statusSkipped  = "skipped"
+)
+
 // --- 1. SCRIPT DEFAULTS ---
 var defaultConfig = Config{
-	InputFile:        "practice_session.mp4",
-	MinSilenceDur:    2.0,
-	SilenceThreshold: "-12dB",
-	MinSongLength:    200.0,
-	OutputPrefix:     "Song",
-	OutputDir:        "output",
-	UploadToDrive:    false,
-	RcloneRemote:     "gdrive:",
-	DriveSubfolder:   "SplitSongs",
-	SetlistFile:      "",
+	InputFile:              "practice_session.mp4",
+	MinSilenceDur:          2.0,
+	SilenceThreshold:       "-12dB",
+	MinSongLength:          200.0,
+	OutputPrefix:           "Song",
+	OutputDir:              "output",
+	UploadToDrive:          false,
+	RcloneRemote:           "gdrive:",
+	DriveSubfolder:         "SplitSongs",
+	SetlistFile:            "",
+	MapAllAudio:            false,
+	MinExpectedSegments:    0,
+	MaxExpectedSegments:    0,
+	ExportChatter:          false,
+	Reencode:               false,
+	VideoCRF:               0, // 0 = defaultVideoCRF unless VideoBitrate is set
+	VideoBitrate:           "",
+	AudioBitrate:           "",
+	AutoTrim:               false,
+	FFmpegLogLevel:         "warning",
+	MonoDetection:          false,
+	NotifyWebhook:          "",
+	NotifyFormat:           "json",
+	NoSplit:                false,
+	DetectionMode:          detectionPeak,
+	SeekMode:               "",
+	AudacityLabels:         false,
+	OutputMode:             outputSongs,
+	LosslessBoundaries:     false,
+	SourceChapters:         false,
+	TrimSilence:            false,
+	Cache:                  false,
+	UploadMode:             uploadCopy,
+	LoudnessReport:         false,
+	OutputContainer:        "",
+	Tracklist:              "",
+	SnapKeyframes:          false,
+	AutoTune:               false,
+	ExpectedSongs:          0,
+	BoundariesFile:         "",
+	OutputFlat:             false,
+	ProofScale:             "",
+	TempDir:                "",
+	ReplayGain:             false,
+	TargetCount:            0,
+	Artist:                 "",
+	Album:                  "",
+	HandleVFR:              vfrWarn,
+	KeepSubtitles:          false,
+	LogFile:                "",
+	CoverArt:               "",
+	ExportGaps:             false,
+	GapsName:               "Between_Songs",
+	Jobs:                   0,
+	FallbackInterval:       0,
+	FallbackMinSongs:       2,
+	MultiThreshold:         false,
+	Archive:                "",
+	Checksums:              false,
+	PreserveAbsoluteTiming: false,
+	VerifyDurations:        false,
+	DurationTolerance:      1.0,
+	RecutDurations:         false,
+	AnalysisWindows:        "",
+	SessionName:            "",
+	FullSet:                false,
+	PostHook:               "",
+	OnNoSongs:              onNoSongsSkip,
+	ChunkLength:            0,
+	HWAccel:                hwAccelNone,
+	UploadNoTraverse:       false,
+	UploadImmutable:        false,
+	TimestampRounding:      roundNone,
+	SkipLeadIn:             false,
+	LeadInThreshold:        "-30dB",
+	EmbedTitleMetadata:     false,
+	BoundarySilenceDur:     0,
+	HandleDTSWarnings:      false,
+	FixedInterval:          0,
 }
 
+// Auto-trim looks for quiet of at least autoTrimSilenceDur at a song's edges,
+// and never trims a song down below autoTrimMinKeep of its length.
+const (
+	autoTrimSilenceDur = 1.0
+	autoTrimMinKeep    = 0.25
+)
+
+// Detection modes for DetectionMode.
+const (
+	detectionPeak = "peak"
+	detectionRMS  = "rms"
+)
+
+// Seek modes for SeekMode.
+const (
+	seekFast     = "fast"
+	seekAccurate = "accurate"
+)
+
+// Output modes for OutputMode.
+const (
+	outputSongs    = "songs"
+	outputChapters = "chapters"
+)
+
+// Upload modes for UploadMode.
+const (
+	uploadCopy   = "copy"
+	uploadUpdate = "update"
+	uploadSync   = "sync"
+)
+
+// analysisLogLevel is the ffmpeg log level for runs whose output we parse.
+const analysisLogLevel = "info"
+
+// defaultVideoCRF is the x264 quality used when re-encoding without an
+// explicit CRF or bitrate. 20 is visually close to the source.
+const defaultVideoCRF = 20
+
+// stdinPath is the conventional "-" file name meaning standard input.
+const stdinPath = "-"
+
+// chatterDirName is the OutputDir subfolder for exported between-song gaps.
+const chatterDirName = "_chatter"
+
+// execCommand builds every external command the tool runs (ffmpeg, rclone).
+// Tests swap it out to capture arguments and return canned output.
+var execCommand = exec.Command
+
 // --- 2. Flag variables (global) ---
 var (
-	configFilePath   string
-	cliInput         string
-	cliDuration      float64
-	cliThreshold     string
-	cliMinSongLength float64
-	cliPrefix        string
-	cliOutput        string
-	cliUpload        bool
-	cliRemote        string
-	cliSubfolder     string
-	cliSetlistFile   string
+	configFilePath            string
+	cliInput                  string
+	cliDuration               float64
+	cliThreshold              string
+	cliMinSongLength          float64
+	cliPrefix                 string
+	cliOutput                 string
+	cliUpload                 bool
+	cliRemote                 string
+	cliSubfolder              string
+	cliSetlistFile            string
+	cliMapAllAudio            bool
+	cliMinSegments            int
+	cliMaxSegments            int
+	cliExportChatter          bool
+	cliReencode               bool
+	cliVideoCRF               int
+	cliVideoBitrate           string
+	cliAudioBitrate           string
+	cliAutoTrim               bool
+	cliFFmpegLogLevel         string
+	cliMonoDetection          bool
+	cliNotifyWebhook          string
+	cliNotifyFormat           string
+	cliNoSplit                bool
+	cliDetectionMode          string
+	cliSeekMode               string
+	cliAudacityLabels         bool
+	cliOutputMode             string
+	cliLosslessBoundaries     bool
+	cliSourceChapters         bool
+	cliTrimSilence            bool
+	cliCache                  bool
+	cliUploadMode             string
+	cliLoudnessReport         bool
+	cliOutputContainer        string
+	cliTracklist              string
+	cliSnapKeyframes          bool
+	cliAutoTune               bool
+	cliExpectedSongs          int
+	cliBoundariesFile         string
+	cliOutputFlat             bool
+	cliProofScale             string
+	cliTempDir                string
+	cliReplayGain             bool
+	cliTargetCount            int
+	cliArtist                 string
+	cliAlbum                  string
+	cliHandleVFR              string
+	cliKeepSubtitles          bool
+	cliLogFile                string
+	cliCoverArt               string
+	cliExportGaps             bool
+	cliGapsName               string
+	cliJobs                   int
+	cliFallbackInterval       float64
+	cliFallbackMinSongs       int
+	cliMultiThreshold         bool
+	cliArchive                string
+	cliChecksums              bool
+	cliPreserveAbsoluteTiming bool
+	cliVerifyDurations        bool
+	cliDurationTolerance      float64
+	cliRecutDurations         bool
+	cliAnalysisWindows        string
+	cliSessionName            string
+	cliFullSet                bool
+	cliPostHook               string
+	cliOnNoSongs              string
+	cliChunkLength            float64
+	cliHWAccel                string
+	cliUploadNoTraverse       bool
+	cliUploadImmutable        bool
+	cliTimestampRounding      string
+	cliSkipLeadIn             bool
+	cliLeadInThreshold        string
+	cliEmbedTitleMetadata     bool
+	cliBoundarySilence        float64
+	cliHandleDTSWarnings      bool
+	cliFixedInterval          float64
+	doctorMode                bool
+	reportPath                string
+	interactiveMode           bool
+	probeMode                 bool
+	manifestPath              string
+	normalizeDir              string
+	normalizeOrder            string
+	forceExport               bool
+	confirmSync               bool
+	checkSetlist              bool
+	strictSetlist             bool
+	noDetectCache             bool
+	noCopyFallback            bool
+	onlySongs                 string
+	noUpload                  bool
+	orderedLogs               bool
+	profileName               string
+	listProfiles              bool
+	cleanOutput               bool
+	failIfNotEmpty            bool
+	detectOnly                bool
+	jsonOutput                bool
+	mergeSongs                string
+	serveAddr                 string
+	serveToken                string
+	relativeTimestamps        bool
+	colorMode                 string
+	uploadOnlyMode            bool
 )
 
 // defineFlags registers all CLI flags
 func defineFlags() {
 	flag.StringVar(&configFilePath, "config", "config.json", "Path to config JSON file")
+	flag.StringVar(&colorMode, "color", colorAuto, "Color warnings, errors and finished steps in the log: auto (when it goes to a terminal and NO_COLOR is unset), always or never")
+	flag.BoolVar(&doctorMode, "doctor", false, "Check that ffmpeg, rclone, the output folder and config are ready, then exit")
+	flag.StringVar(&reportPath, "report-json", "", "Write a JSON report of the run (segments, uploads, timings) to this path")
+	flag.BoolVar(&relativeTimestamps, "relative-timestamps", false, "Record each song's times in the -report-json report relative to its own file (start 0) instead of the source recording")
+	flag.BoolVar(&interactiveMode, "interactive", false, "Review and edit song boundaries in the terminal before exporting")
+	flag.BoolVar(&probeMode, "probe", false, "Print the input's streams, codecs and duration (via ffprobe), then exit")
+	flag.StringVar(&manifestPath, "from-manifest", "", "Export the songs listed in a previous run's -report-json file instead of detecting them")
+	flag.StringVar(&normalizeDir, "normalize-filenames", "", "Rename the media files in this folder to \"NN - Title\" from the setlist, then exit")
+	flag.BoolVar(&uploadOnlyMode, "upload-only", false, "Upload the existing output folder (rclone pre-check, then upload) without detecting or splitting anything, then exit")
+	flag.StringVar(&normalizeOrder, "by", orderByName, "Order for -normalize-filenames to match files to setlist titles: name or mtime")
+	flag.BoolVar(&forceExport, "force", false, "With -cache, ignore the saved state and export and upload everything again")
+	flag.BoolVar(&confirmSync, "confirm-sync", false, "Allow upload_mode \"sync\", which deletes remote files that aren't in the output folder")
+	flag.BoolVar(&checkSetlist, "check-setlist", false, "Detect the songs and show which setlist title each one gets, then exit without exporting")
+	flag.BoolVar(&checkSetlist, "dryrun", false, "Same as -check-setlist")
+	flag.BoolVar(&strictSetlist, "strict-setlist", false, "Fail before exporting if the setlist doesn't have one title per song")
+	flag.BoolVar(&noDetectCache, "no-cache", false, "Run silence detection even if an earlier run cached results for this input and settings")
+	flag.BoolVar(&noCopyFallback, "no-copy-fallback", false, "With -reencode, fail a song whose encoder is missing instead of retrying it with stream copy")
+	flag.StringVar(&onlySongs, "only", "", "Export only these songs (1-based, comma-separated, e.g. 3,7), keeping their numbers")
+	flag.StringVar(&mergeSongs, "merge", "", "Join two adjacent songs (1-based, e.g. 3,4) into one before exporting, e.g. with -from-manifest after detection split a song")
+	flag.StringVar(&serveAddr, "serve", "", "Run as a server on this address (e.g. localhost:8080), splitting each input POSTed to /split as JSON and answering with its run report")
+	flag.StringVar(&serveToken, "serve-token", "", "With -serve, require this bearer token on every request (default $"+serveTokenEnv+")")
+	flag.BoolVar(&noUpload, "no-upload", false, "Never upload, whatever the config file or -upload say")
+	flag.BoolVar(&orderedLogs, "ordered-logs", false, "With -jobs, hold each song's log lines until the songs before it are done, so the log reads in song order")
+	flag.BoolVar(&cleanOutput, "clean-output", false, "Delete everything already in the output folder, after asking, before exporting")
+	flag.BoolVar(&detectOnly, "detect-only", false, "Print the song boundaries to stdout, one \"START END\" line per song, and exit without writing any files; logs stay on stderr")
+	flag.BoolVar(&jsonOutput, "json", false, "With -detect-only, print the boundaries as a JSON array instead")
+	flag.BoolVar(&failIfNotEmpty, "fail-if-not-empty", false, "Stop before exporting if the output folder already holds files")
+	flag.StringVar(&profileName, "profile", "", "Use this profile from the config file's \"profiles\" on top of its other settings")
+	flag.BoolVar(&listProfiles, "list-profiles", false, "List the profiles in the config file with their main settings, then exit")
 	flag.StringVar(&cliInput, "input", defaultConfig.InputFile, "Input video file")
 	flag.Float64Var(&cliDuration, "duration", defaultConfig.MinSilenceDur, "Minimum silence duration (seconds)")
 	flag.StringVar(&cliThreshold, "threshold", defaultConfig.SilenceThreshold, "Silence threshold (e.g., -30dB)")
@@ -77,49 +535,115 @@ func defineFlags() {
 	flag.StringVar(&cliRemote, "remote", defaultConfig.RcloneRemote, "rclone remote name (e.g., 'gdrive:')")
 	flag.StringVar(&cliSubfolder, "subfolder", defaultConfig.DriveSubfolder, "Google Drive subfolder to upload to")
 	flag.StringVar(&cliSetlistFile, "setlist", defaultConfig.SetlistFile, "Path to a .txt setlist file for renaming")
+	flag.BoolVar(&cliMapAllAudio, "map-all-audio", defaultConfig.MapAllAudio, "Carry every audio track into each split, not just the default one")
+	flag.IntVar(&cliMinSegments, "min-segments", defaultConfig.MinExpectedSegments, "Abort before exporting if fewer songs are found (0 = no check)")
+	flag.IntVar(&cliMaxSegments, "max-segments", defaultConfig.MaxExpectedSegments, "Abort before exporting if more songs are found (0 = no check)")
+	flag.BoolVar(&cliExportChatter, "export-chatter", defaultConfig.ExportChatter, "Also export the between-song gaps into a _chatter subfolder")
+	flag.BoolVar(&cliReencode, "reencode", defaultConfig.Reencode, "Re-encode (H.264/AAC) instead of stream copy, for frame-accurate cuts")
+	flag.IntVar(&cliVideoCRF, "crf", defaultConfig.VideoCRF, "x264 CRF quality when re-encoding (lower is better; default 20)")
+	flag.StringVar(&cliVideoBitrate, "video-bitrate", defaultConfig.VideoBitrate, "Target video bitrate when re-encoding (e.g., 4M); ignored if -crf is set")
+	flag.StringVar(&cliAudioBitrate, "audio-bitrate", defaultConfig.AudioBitrate, "Audio bitrate when re-encoding (e.g., 192k)")
+	flag.BoolVar(&cliAutoTrim, "autotrim", defaultConfig.AutoTrim, "Trim quiet tuning/tails off each song with an extra analysis pass per song")
+	flag.StringVar(&cliFFmpegLogLevel, "loglevel", defaultConfig.FFmpegLogLevel, "ffmpeg log level for exports (quiet, error, warning, info, ...)")
+	flag.BoolVar(&cliMonoDetection, "mono-detection", defaultConfig.MonoDetection, "Detect silence on a mono downmix (exports keep every channel)")
+	flag.StringVar(&cliNotifyWebhook, "notify-webhook", defaultConfig.NotifyWebhook, "URL to POST a summary to when the run finishes")
+	flag.StringVar(&cliNotifyFormat, "notify-format", defaultConfig.NotifyFormat, "Webhook payload format: json or slack")
+	flag.BoolVar(&cliNoSplit, "single", defaultConfig.NoSplit, "Skip detection and treat the whole file as one song")
+	flag.StringVar(&cliDetectionMode, "detection-mode", defaultConfig.DetectionMode, "Silence detection: peak (silencedetect) or rms (windowed astats RMS, ignores brief spikes)")
+	flag.StringVar(&cliSeekMode, "seek-mode", defaultConfig.SeekMode, "Segment seeking: fast (-ss before -i, keyframe cuts) or accurate (-ss after -i); default fast for copy, accurate for re-encode")
+	flag.BoolVar(&cliAudacityLabels, "audacity-labels", defaultConfig.AudacityLabels, "Write the detected songs to labels.txt in the output dir as an Audacity label track")
+	flag.StringVar(&cliOutputMode, "output-mode", defaultConfig.OutputMode, "What to write: songs (one file per song) or chapters (the original file with a chapter per song)")
+	flag.BoolVar(&cliLosslessBoundaries, "lossless-boundaries", defaultConfig.LosslessBoundaries, "Cut at the middle of each silence so every moment of the recording lands in exactly one song")
+	flag.BoolVar(&cliSourceChapters, "source-chapters", defaultConfig.SourceChapters, "Split on chapter markers already in the input (titles from the chapters), falling back to silence detection")
+	flag.BoolVar(&cliTrimSilence, "trim-silence", defaultConfig.TrimSilence, "Strip leading/trailing near-silence (below -threshold) from each song's audio with silenceremove; re-encodes the audio")
+	flag.BoolVar(&cliCache, "cache", defaultConfig.Cache, "Remember exported files' hashes and skip exporting/uploading songs whose source and settings are unchanged")
+	flag.StringVar(&cliUploadMode, "upload-mode", defaultConfig.UploadMode, "rclone upload mode: copy, update (skip files newer on the remote) or sync (mirror, deletes remote extras; needs -confirm-sync)")
+	flag.BoolVar(&cliLoudnessReport, "loudness-report", defaultConfig.LoudnessReport, "Measure each exported song's integrated loudness and true peak (one extra ffmpeg pass per song)")
+	flag.StringVar(&cliOutputContainer, "output-container", defaultConfig.OutputContainer, "Container for exported songs (mp4, mkv, mov, webm); defaults to the input's. Re-encodes when the streams can't be copied into it")
+	flag.StringVar(&cliTracklist, "tracklist", defaultConfig.Tracklist, "Write a timestamped tracklist (\"3:45 Song Two\") for video descriptions to this path")
+	flag.BoolVar(&cliSnapKeyframes, "snap-keyframes", defaultConfig.SnapKeyframes, "In copy mode, move each song start to the nearest video keyframe (via ffprobe) so reported times match the cuts")
+	flag.BoolVar(&cliAutoTune, "auto-tune", defaultConfig.AutoTune, "Re-run detection with a higher or lower silence threshold until the song count is near expected_songs")
+	flag.IntVar(&cliExpectedSongs, "expected-songs", defaultConfig.ExpectedSongs, "Roughly how many songs the recording has, for -auto-tune (0 = use min/max-segments)")
+	flag.StringVar(&cliBoundariesFile, "boundaries", defaultConfig.BoundariesFile, "Read song boundaries (and titles) from a cut list or FFmetadata file instead of detecting silence")
+	flag.BoolVar(&cliOutputFlat, "output-flat", defaultConfig.OutputFlat, "With several inputs, put every song straight in the output dir with the input name as a prefix, instead of one subfolder per input")
+	flag.StringVar(&cliProofScale, "proof-scale", defaultConfig.ProofScale, "Also write a small <song>_proof.mp4 at this scale (e.g. 480:-2) for quick review")
+	flag.StringVar(&cliTempDir, "temp-dir", defaultConfig.TempDir, "Folder for scratch and cache files (default: the system temp dir)")
+	flag.BoolVar(&cliReplayGain, "replaygain", defaultConfig.ReplayGain, "Tag flac/mp3/ogg/opus songs with ReplayGain track gain and peak so players can level them, without re-encoding")
+	flag.IntVar(&cliTargetCount, "target-count", defaultConfig.TargetCount, "Search for the silence threshold that gives exactly this many songs (0 = off)")
+	flag.StringVar(&cliArtist, "artist", defaultConfig.Artist, "Artist for metadata_templates ({{.Artist}})")
+	flag.StringVar(&cliAlbum, "album", defaultConfig.Album, "Album for metadata_templates ({{.Album}})")
+	flag.StringVar(&cliHandleVFR, "handle-vfr", defaultConfig.HandleVFR, "Variable frame rate input: warn, reencode (to constant frame rate) or ignore")
+	flag.BoolVar(&cliKeepSubtitles, "keep-subtitles", defaultConfig.KeepSubtitles, "Copy subtitle streams into each song")
+	flag.StringVar(&cliLogFile, "log-file", defaultConfig.LogFile, "Also write the log to this file; a bare file name goes in the output folder")
+	flag.StringVar(&cliCoverArt, "cover-art", defaultConfig.CoverArt, "JPG/PNG to embed as cover art in mp3, m4a or flac output")
+	flag.BoolVar(&cliExportGaps, "export-gaps", defaultConfig.ExportGaps, "Also join all the between-song gaps into one file (see -gaps-name)")
+	flag.StringVar(&cliGapsName, "gaps-name", defaultConfig.GapsName, "File name (without extension) for -export-gaps")
+	flag.IntVar(&cliJobs, "jobs", defaultConfig.Jobs, "Number of songs to export at once (0 = pick from the export mode and CPU count)")
+	flag.Float64Var(&cliFallbackInterval, "fallback-interval", defaultConfig.FallbackInterval, "If detection finds fewer than -fallback-min-songs songs, split into chunks of this many seconds instead (0 = off)")
+	flag.IntVar(&cliFallbackMinSongs, "fallback-min-songs", defaultConfig.FallbackMinSongs, "Song count below which -fallback-interval kicks in")
+	flag.BoolVar(&cliMultiThreshold, "multi-threshold", defaultConfig.MultiThreshold, "Experimental: detect at several silence thresholds and keep the most plausible songs")
+	flag.StringVar(&cliArchive, "archive", defaultConfig.Archive, "Also pack the output folder into one archive next to it: zip or tgz (uploaded instead of the folder)")
+	flag.BoolVar(&cliChecksums, "checksums", defaultConfig.Checksums, "Write a SHA256SUMS file for the exported songs into the output folder")
+	flag.BoolVar(&cliPreserveAbsoluteTiming, "absolute-timing", defaultConfig.PreserveAbsoluteTiming, "Keep each song's timestamps at its original position in the recording instead of starting at zero")
+	flag.BoolVar(&cliVerifyDurations, "verify-durations", defaultConfig.VerifyDurations, "After exporting, check each song's real length with ffprobe and warn if it's off")
+	flag.Float64Var(&cliDurationTolerance, "duration-tolerance", defaultConfig.DurationTolerance, "How many seconds a song's length may be off before -verify-durations warns")
+	flag.BoolVar(&cliRecutDurations, "recut-durations", defaultConfig.RecutDurations, "With -verify-durations, cut songs whose length is off again with an accurate seek")
+	flag.StringVar(&cliAnalysisWindows, "analyze", defaultConfig.AnalysisWindows, "Only look for silence inside these comma-separated START-END windows (e.g. 0:00-30:00,45:00-60:00); the rest of the recording is never split into songs")
+	flag.StringVar(&cliSessionName, "session", defaultConfig.SessionName, "Session name to start every file name with, e.g. SpringRehearsal gives SpringRehearsal_Song_01 and SpringRehearsal_01 - Title")
+	flag.BoolVar(&cliFullSet, "full-set", defaultConfig.FullSet, "Also join the exported songs, in order, into one Full_Set file without the silence between them")
+	flag.StringVar(&cliPostHook, "post-hook", defaultConfig.PostHook, "Shell command to run after each song is exported, with {} replaced by the file's path (e.g. \"tag-song {}\"); a failing hook is only a warning")
+	flag.StringVar(&cliOnNoSongs, "on-no-songs", defaultConfig.OnNoSongs, "What to do when no segment is as long as -minsonglength: skip (export nothing), longest (export the longest one) or all (export them all)")
+	flag.Float64Var(&cliChunkLength, "chunk", defaultConfig.ChunkLength, "Skip detection and cut the whole recording into chunks of this many seconds (the last one shorter), e.g. 600 for 10-minute uploads (0 = off)")
+	flag.StringVar(&cliHWAccel, "hwaccel", defaultConfig.HWAccel, "Hardware encoder for re-encodes: none, nvenc (NVIDIA), videotoolbox (macOS) or qsv (Intel); falls back to software if ffmpeg lacks it")
+	flag.BoolVar(&cliUploadNoTraverse, "upload-no-traverse", defaultConfig.UploadNoTraverse, "Pass --no-traverse to rclone: don't list the whole destination first (faster when uploading a few files into a big folder)")
+	flag.BoolVar(&cliUploadImmutable, "upload-immutable", defaultConfig.UploadImmutable, "Pass --immutable to rclone: fail instead of overwriting files that already exist and differ on the remote")
+	flag.StringVar(&cliTimestampRounding, "timestamp-rounding", defaultConfig.TimestampRounding, "Round song boundaries before exporting and in every listing of them: none, second (whole seconds) or frame (the nearest video frame, needs ffprobe)")
+	flag.BoolVar(&cliSkipLeadIn, "skip-lead-in", defaultConfig.SkipLeadIn, "Drop the soundcheck/noodling at the start: skip detected songs until one has a sustained level of at least -lead-in-threshold (one astats pass per song checked)")
+	flag.StringVar(&cliLeadInThreshold, "lead-in-threshold", defaultConfig.LeadInThreshold, "Median RMS level a song must reach for -skip-lead-in to count it as the first real song (e.g. -30dB)")
+	flag.BoolVar(&cliEmbedTitleMetadata, "embed-title", defaultConfig.EmbedTitleMetadata, "Tag each exported song with its setlist title (-metadata title=...) so players show it")
+	flag.Float64Var(&cliBoundarySilence, "boundary-silence", defaultConfig.BoundarySilenceDur, "Minimum silence (seconds) that counts as a boundary between songs; shorter silences are treated as rests within a song (0 = every detected silence)")
+	flag.BoolVar(&cliHandleDTSWarnings, "handle-dts-warnings", defaultConfig.HandleDTSWarnings, "Re-cut a song whose export printed ffmpeg's \"Non-monotonous DTS\" warnings: first with -fflags +genpts, then by re-encoding if they persist")
+	flag.Float64Var(&cliFixedInterval, "fixed-interval", defaultConfig.FixedInterval, "Another name for -chunk: skip detection and cut the recording every this many seconds (0 = off)")
 }
 
 // loadConfig manages loading settings from defaults, file, and (parsed) cli flags.
-func loadConfig() (Config, error) {
+// Problems that don't stop the run, such as an unparseable config file, are
+// returned as warnings for the caller to surface.
+func loadConfig() (Config, []string, error) {
 	// 1. Start with the defaults
 	cfg := defaultConfig
+	var warnings []string
 
-	// 2. Load Config File
-	fileConfig, err := loadConfigFromFile(configFilePath)
-	if err == nil {
-		// Merge fileConfig onto defaultConfig
-		if fileConfig.InputFile != "" {
-			cfg.InputFile = fileConfig.InputFile
-		}
-		if fileConfig.MinSilenceDur != 0.0 {
-			cfg.MinSilenceDur = fileConfig.MinSilenceDur
-		}
-		if fileConfig.SilenceThreshold != "" {
-			cfg.SilenceThreshold = fileConfig.SilenceThreshold
-		}
-		if fileConfig.MinSongLength != 0.0 {
-			cfg.MinSongLength = fileConfig.MinSongLength
-		}
-		if fileConfig.OutputPrefix != "" {
-			cfg.OutputPrefix = fileConfig.OutputPrefix
-		}
-		if fileConfig.OutputDir != "" {
-			cfg.OutputDir = fileConfig.OutputDir
-		}
-		if fileConfig.UploadToDrive {
-			cfg.UploadToDrive = fileConfig.UploadToDrive
-		}
-		if fileConfig.RcloneRemote != "" {
-			cfg.RcloneRemote = fileConfig.RcloneRemote
+	// 2. Load the config files, each overriding the ones before it
+	paths, err := configPaths(configFilePath)
+	if err != nil {
+		return cfg, warnings, err
+	}
+	configFilePath = strings.Join(paths, ",")
+	profileFound := false
+	for _, path := range paths {
+		fileConfig, err := loadConfigFromFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				warnings = append(warnings, fmt.Sprintf("Could not parse config file '%s': %v. Skipping it.", path, err))
+			} else if len(paths) > 1 {
+				warnings = append(warnings, fmt.Sprintf("Config file '%s' not found; skipping it.", path))
+			}
+			continue
 		}
-		if fileConfig.DriveSubfolder != "" {
-			cfg.DriveSubfolder = fileConfig.DriveSubfolder
+		if profileName != "" && hasProfile(path, profileName) {
+			if fileConfig, err = applyProfile(path, fileConfig, profileName); err != nil {
+				return cfg, warnings, err
+			}
+			profileFound = true
 		}
-		if fileConfig.SetlistFile != "" {
-			cfg.SetlistFile = fileConfig.SetlistFile
+		cfg = mergeFileConfig(cfg, fileConfig)
+	}
+	if profileName != "" && !profileFound {
+		// No file has it; applyProfile explains why for the last one.
+		if _, err := applyProfile(paths[len(paths)-1], Config{}, profileName); err != nil {
+			return cfg, warnings, err
 		}
-	} else if !os.IsNotExist(err) {
-		log.Printf("Warning: Could not parse config file '%s': %v. Using defaults.", configFilePath, err)
 	}
 
 	// 3. Override with CLI Flags
@@ -143,339 +667,2666 @@ func loadConfig() (Config, error) {
 	if userSetFlags["prefix"] {
 		cfg.OutputPrefix = cliPrefix
 	}
-	if userSetFlags["output"] {
-		cfg.OutputDir = cliOutput
+	if userSetFlags["output"] {
+		cfg.OutputDir = cliOutput
+	}
+	// Only flags given on the command line override, so an explicit
+	// -upload=false wins over "upload_to_drive": true in the file.
+	if userSetFlags["upload"] {
+		cfg.UploadToDrive = cliUpload
+	}
+	if noUpload {
+		cfg.UploadToDrive = false
+	}
+	if userSetFlags["remote"] {
+		cfg.RcloneRemote = cliRemote
+	}
+	if userSetFlags["subfolder"] {
+		cfg.DriveSubfolder = cliSubfolder
+	}
+	if userSetFlags["setlist"] {
+		cfg.SetlistFile = cliSetlistFile
+	}
+	if userSetFlags["map-all-audio"] {
+		cfg.MapAllAudio = cliMapAllAudio
+	}
+	if userSetFlags["min-segments"] {
+		cfg.MinExpectedSegments = cliMinSegments
+	}
+	if userSetFlags["max-segments"] {
+		cfg.MaxExpectedSegments = cliMaxSegments
+	}
+	if userSetFlags["export-chatter"] {
+		cfg.ExportChatter = cliExportChatter
+	}
+	if userSetFlags["reencode"] {
+		cfg.Reencode = cliReencode
+	}
+	if userSetFlags["crf"] {
+		cfg.VideoCRF = cliVideoCRF
+	}
+	if userSetFlags["video-bitrate"] {
+		cfg.VideoBitrate = cliVideoBitrate
+	}
+	if userSetFlags["audio-bitrate"] {
+		cfg.AudioBitrate = cliAudioBitrate
+	}
+	if userSetFlags["autotrim"] {
+		cfg.AutoTrim = cliAutoTrim
+	}
+	if userSetFlags["loglevel"] {
+		cfg.FFmpegLogLevel = cliFFmpegLogLevel
+	}
+	if userSetFlags["mono-detection"] {
+		cfg.MonoDetection = cliMonoDetection
+	}
+	if userSetFlags["notify-webhook"] {
+		cfg.NotifyWebhook = cliNotifyWebhook
+	}
+	if userSetFlags["notify-format"] {
+		cfg.NotifyFormat = cliNotifyFormat
+	}
+	if userSetFlags["single"] {
+		cfg.NoSplit = cliNoSplit
+	}
+	if userSetFlags["detection-mode"] {
+		cfg.DetectionMode = cliDetectionMode
+	}
+	if userSetFlags["seek-mode"] {
+		cfg.SeekMode = cliSeekMode
+	}
+	if userSetFlags["audacity-labels"] {
+		cfg.AudacityLabels = cliAudacityLabels
+	}
+	if userSetFlags["output-mode"] {
+		cfg.OutputMode = cliOutputMode
+	}
+	if userSetFlags["lossless-boundaries"] {
+		cfg.LosslessBoundaries = cliLosslessBoundaries
+	}
+	if userSetFlags["source-chapters"] {
+		cfg.SourceChapters = cliSourceChapters
+	}
+	if userSetFlags["trim-silence"] {
+		cfg.TrimSilence = cliTrimSilence
+	}
+	if userSetFlags["cache"] {
+		cfg.Cache = cliCache
+	}
+	if userSetFlags["upload-mode"] {
+		cfg.UploadMode = cliUploadMode
+	}
+	if userSetFlags["loudness-report"] {
+		cfg.LoudnessReport = cliLoudnessReport
+	}
+	if userSetFlags["output-container"] {
+		cfg.OutputContainer = cliOutputContainer
+	}
+	if userSetFlags["tracklist"] {
+		cfg.Tracklist = cliTracklist
+	}
+	if userSetFlags["snap-keyframes"] {
+		cfg.SnapKeyframes = cliSnapKeyframes
+	}
+	if userSetFlags["auto-tune"] {
+		cfg.AutoTune = cliAutoTune
+	}
+	if userSetFlags["expected-songs"] {
+		cfg.ExpectedSongs = cliExpectedSongs
+	}
+	if userSetFlags["boundaries"] {
+		cfg.BoundariesFile = cliBoundariesFile
+	}
+	if userSetFlags["output-flat"] {
+		cfg.OutputFlat = cliOutputFlat
+	}
+	if userSetFlags["proof-scale"] {
+		cfg.ProofScale = cliProofScale
+	}
+	if userSetFlags["temp-dir"] {
+		cfg.TempDir = cliTempDir
+	}
+	if userSetFlags["replaygain"] {
+		cfg.ReplayGain = cliReplayGain
+	}
+	if userSetFlags["target-count"] {
+		cfg.TargetCount = cliTargetCount
+	}
+	if userSetFlags["artist"] {
+		cfg.Artist = cliArtist
+	}
+	if userSetFlags["album"] {
+		cfg.Album = cliAlbum
+	}
+	if userSetFlags["handle-vfr"] {
+		cfg.HandleVFR = cliHandleVFR
+	}
+	if userSetFlags["keep-subtitles"] {
+		cfg.KeepSubtitles = cliKeepSubtitles
+	}
+	if userSetFlags["log-file"] {
+		cfg.LogFile = cliLogFile
+	}
+	if userSetFlags["cover-art"] {
+		cfg.CoverArt = cliCoverArt
+	}
+	if userSetFlags["export-gaps"] {
+		cfg.ExportGaps = cliExportGaps
+	}
+	if userSetFlags["gaps-name"] {
+		cfg.GapsName = cliGapsName
+	}
+	if userSetFlags["jobs"] {
+		cfg.Jobs = cliJobs
+	}
+	if userSetFlags["fallback-interval"] {
+		cfg.FallbackInterval = cliFallbackInterval
+	}
+	if userSetFlags["fallback-min-songs"] {
+		cfg.FallbackMinSongs = cliFallbackMinSongs
+	}
+	if userSetFlags["multi-threshold"] {
+		cfg.MultiThreshold = cliMultiThreshold
+	}
+	if userSetFlags["archive"] {
+		cfg.Archive = cliArchive
+	}
+	if userSetFlags["checksums"] {
+		cfg.Checksums = cliChecksums
+	}
+	if userSetFlags["absolute-timing"] {
+		cfg.PreserveAbsoluteTiming = cliPreserveAbsoluteTiming
+	}
+	if userSetFlags["verify-durations"] {
+		cfg.VerifyDurations = cliVerifyDurations
+	}
+	if userSetFlags["duration-tolerance"] {
+		cfg.DurationTolerance = cliDurationTolerance
+	}
+	if userSetFlags["recut-durations"] {
+		cfg.RecutDurations = cliRecutDurations
+	}
+	if userSetFlags["analyze"] {
+		cfg.AnalysisWindows = cliAnalysisWindows
+	}
+	if userSetFlags["session"] {
+		cfg.SessionName = cliSessionName
+	}
+	if userSetFlags["full-set"] {
+		cfg.FullSet = cliFullSet
+	}
+	if userSetFlags["post-hook"] {
+		cfg.PostHook = cliPostHook
+	}
+	if userSetFlags["on-no-songs"] {
+		cfg.OnNoSongs = cliOnNoSongs
+	}
+	if userSetFlags["chunk"] {
+		cfg.ChunkLength = cliChunkLength
+	}
+	if userSetFlags["hwaccel"] {
+		cfg.HWAccel = cliHWAccel
+	}
+	if userSetFlags["upload-no-traverse"] {
+		cfg.UploadNoTraverse = cliUploadNoTraverse
+	}
+	if userSetFlags["upload-immutable"] {
+		cfg.UploadImmutable = cliUploadImmutable
+	}
+	if userSetFlags["timestamp-rounding"] {
+		cfg.TimestampRounding = cliTimestampRounding
+	}
+	if userSetFlags["skip-lead-in"] {
+		cfg.SkipLeadIn = cliSkipLeadIn
+	}
+	if userSetFlags["lead-in-threshold"] {
+		cfg.LeadInThreshold = cliLeadInThreshold
+	}
+	if userSetFlags["embed-title"] {
+		cfg.EmbedTitleMetadata = cliEmbedTitleMetadata
+	}
+	if userSetFlags["boundary-silence"] {
+		cfg.BoundarySilenceDur = cliBoundarySilence
+	}
+	if userSetFlags["handle-dts-warnings"] {
+		cfg.HandleDTSWarnings = cliHandleDTSWarnings
+	}
+	if userSetFlags["fixed-interval"] {
+		cfg.FixedInterval = cliFixedInterval
+	}
+	cfg.RunOptions = runOptionsFromFlags()
+
+	// 4. Check settings that conflict or must be one of a few values
+	cfg, checkWarnings, err := checkConfig(cfg)
+	return cfg, append(warnings, checkWarnings...), err
+}
+
+// checkConfig resolves settings that conflict or must be one of a few
+// values, returning the fixed-up config and a warning for each change.
+// Problems it can't fix are returned as an error.
+func checkConfig(cfg Config) (Config, []string, error) {
+	var warnings []string
+	var err error
+	for _, path := range []*string{&cfg.InputFile, &cfg.OutputDir, &cfg.SetlistFile, &cfg.BoundariesFile, &cfg.Tracklist, &cfg.TempDir, &cfg.LogFile, &cfg.CoverArt} {
+		if *path, err = expandPath(*path); err != nil {
+			return cfg, warnings, err
+		}
+	}
+	if cfg.InputFile == stdinPath && cfg.SetlistFile == stdinPath {
+		return cfg, warnings, errors.New("input_file and setlist_file can't both be '-' (stdin)")
+	}
+	if cfg.LosslessBoundaries && cfg.AutoTrim {
+		warnings = append(warnings, "auto_trim would cut between songs again; ignoring it with lossless_boundaries.")
+		cfg.AutoTrim = false
+	}
+	switch cfg.OutputMode {
+	case outputSongs, outputChapters:
+	default:
+		warnings = append(warnings, fmt.Sprintf("Unknown output_mode '%s', using '%s'.", cfg.OutputMode, outputSongs))
+		cfg.OutputMode = outputSongs
+	}
+	if cfg.OutputContainer != "" {
+		if _, ok := outputContainers[normalizeContainer(cfg.OutputContainer)]; !ok {
+			warnings = append(warnings, fmt.Sprintf("Unknown output_container '%s', keeping the input's container.", cfg.OutputContainer))
+			cfg.OutputContainer = ""
+		} else {
+			cfg.OutputContainer = normalizeContainer(cfg.OutputContainer)
+		}
+	}
+	if cfg.TargetCount > 0 && cfg.AutoTune {
+		warnings = append(warnings, "target_count and auto_tune both set; using target_count.")
+		cfg.AutoTune = false
+	}
+	if cfg.MultiThreshold && (cfg.TargetCount > 0 || cfg.AutoTune) {
+		warnings = append(warnings, "multi_threshold can't be combined with target_count or auto_tune; ignoring it.")
+		cfg.MultiThreshold = false
+	}
+	for _, threshold := range cfg.CandidateThresholds {
+		if _, err := parseDecibels(threshold); err != nil {
+			return cfg, warnings, fmt.Errorf("candidate_thresholds: %v", err)
+		}
+	}
+	if cfg.JSONOutput && !cfg.DetectOnly {
+		warnings = append(warnings, "-json only changes -detect-only's output; ignoring it.")
+	}
+	if cfg.CleanOutput && cfg.FailIfNotEmpty {
+		return cfg, warnings, errors.New("-clean-output and -fail-if-not-empty can't both be set")
+	}
+	if _, err := parseAnalysisWindows(cfg.AnalysisWindows); err != nil {
+		return cfg, warnings, fmt.Errorf("analysis_windows: %v", err)
+	}
+	if cfg.AutoTune {
+		if _, _, ok := expectedSongRange(cfg); !ok {
+			warnings = append(warnings, "auto_tune needs expected_songs (or min/max_expected_segments) to aim for; ignoring it.")
+			cfg.AutoTune = false
+		}
+	}
+	if _, ok := archiveExts[cfg.Archive]; cfg.Archive != "" && !ok {
+		warnings = append(warnings, fmt.Sprintf("Unknown archive '%s', expected zip or tgz; not archiving.", cfg.Archive))
+		cfg.Archive = ""
+	}
+	if cfg.DurationTolerance <= 0 {
+		warnings = append(warnings, fmt.Sprintf("duration_tolerance must be positive, got %g; using %g.", cfg.DurationTolerance, defaultConfig.DurationTolerance))
+		cfg.DurationTolerance = defaultConfig.DurationTolerance
+	}
+	if cfg.FixedInterval != 0 {
+		if cfg.ChunkLength != 0 && cfg.ChunkLength != cfg.FixedInterval {
+			warnings = append(warnings, fmt.Sprintf("fixed_interval is another name for chunk_length; both set (%g and %g), using chunk_length.", cfg.FixedInterval, cfg.ChunkLength))
+		} else {
+			cfg.ChunkLength = cfg.FixedInterval
+		}
+	}
+	if cfg.ChunkLength < 0 {
+		warnings = append(warnings, fmt.Sprintf("chunk_length must be positive, got %g; turning it off.", cfg.ChunkLength))
+		cfg.ChunkLength = 0
+	}
+	if cfg.ChunkLength > 0 && cfg.NoSplit {
+		warnings = append(warnings, "no_split and chunk_length both set; keeping the whole file as one song.")
+		cfg.ChunkLength = 0
+	}
+	if cfg.FallbackInterval < 0 {
+		warnings = append(warnings, fmt.Sprintf("fallback_interval must be positive, got %g; turning it off.", cfg.FallbackInterval))
+		cfg.FallbackInterval = 0
+	}
+	if cfg.BoundarySilenceDur < 0 {
+		warnings = append(warnings, fmt.Sprintf("boundary_silence_duration must be positive, got %g; turning it off.", cfg.BoundarySilenceDur))
+		cfg.BoundarySilenceDur = 0
+	}
+	if cfg.BoundarySilenceDur > 0 && cfg.BoundarySilenceDur <= cfg.MinSilenceDur {
+		warnings = append(warnings, fmt.Sprintf("boundary_silence_duration %gs is no longer than min_silence_duration %gs, so every detected silence is already a boundary.", cfg.BoundarySilenceDur, cfg.MinSilenceDur))
+	}
+	if cfg.Jobs < 0 {
+		warnings = append(warnings, fmt.Sprintf("jobs can't be negative, got %d; picking it from the export mode.", cfg.Jobs))
+		cfg.Jobs = 0
+	}
+	switch cfg.SeekMode {
+	case "", seekFast, seekAccurate:
+	default:
+		warnings = append(warnings, fmt.Sprintf("Unknown seek_mode '%s', expected fast or accurate; using the default.", cfg.SeekMode))
+		cfg.SeekMode = ""
+	}
+	switch cfg.HandleVFR {
+	case vfrWarn, vfrReencode, vfrIgnore:
+	default:
+		warnings = append(warnings, fmt.Sprintf("Unknown handle_vfr '%s', using '%s'.", cfg.HandleVFR, vfrWarn))
+		cfg.HandleVFR = vfrWarn
+	}
+	if _, ok := hwAccels[cfg.HWAccel]; !ok && cfg.HWAccel != hwAccelNone {
+		warnings = append(warnings, fmt.Sprintf("Unknown hw_accel '%s', expected none, nvenc, videotoolbox or qsv; using '%s'.", cfg.HWAccel, hwAccelNone))
+		cfg.HWAccel = hwAccelNone
+	}
+	if _, err := parseDecibels(cfg.LeadInThreshold); err != nil {
+		warnings = append(warnings, fmt.Sprintf("lead_in_threshold: %v; using %s.", err, defaultConfig.LeadInThreshold))
+		cfg.LeadInThreshold = defaultConfig.LeadInThreshold
+	}
+	switch cfg.TimestampRounding {
+	case roundNone, roundSecond, roundFrame:
+	default:
+		warnings = append(warnings, fmt.Sprintf("Unknown timestamp_rounding '%s', expected none, second or frame; using '%s'.", cfg.TimestampRounding, roundNone))
+		cfg.TimestampRounding = roundNone
+	}
+	switch cfg.OnNoSongs {
+	case onNoSongsSkip, onNoSongsLongest, onNoSongsAll:
+	default:
+		warnings = append(warnings, fmt.Sprintf("Unknown on_no_songs '%s', using '%s'.", cfg.OnNoSongs, onNoSongsSkip))
+		cfg.OnNoSongs = onNoSongsSkip
+	}
+	if cfg.FullSet && cfg.OutputMode == outputChapters {
+		warnings = append(warnings, "full_set is what output_mode 'chapters' already writes; ignoring it.")
+		cfg.FullSet = false
+	}
+	if cfg.ReplayGain && cfg.OutputMode == outputChapters {
+		warnings = append(warnings, "replay_gain tags whole files, not chapters; ignoring it with output_mode 'chapters'.")
+		cfg.ReplayGain = false
+	}
+	if _, err := parseMetadataTemplates(cfg.MetadataTemplates); err != nil {
+		return cfg, warnings, err
+	}
+	if err := checkCoverArt(cfg.CoverArt); err != nil {
+		return cfg, warnings, err
+	}
+	if cfg.CoverArt != "" && !coverArtApplies(cfg) {
+		warnings = append(warnings, "cover_art is only embedded with an output_container of mp3, m4a or flac; ignoring it.")
+	}
+	var flagWarnings []string
+	cfg.RcloneGlobalFlags, flagWarnings = checkRcloneGlobalFlags(cfg.RcloneGlobalFlags)
+	warnings = append(warnings, flagWarnings...)
+	switch cfg.UploadMode {
+	case uploadCopy, uploadUpdate, uploadSync:
+	default:
+		warnings = append(warnings, fmt.Sprintf("Unknown upload_mode '%s', using '%s'.", cfg.UploadMode, uploadCopy))
+		cfg.UploadMode = uploadCopy
+	}
+
+	return cfg, warnings, nil
+}
+
+// configPaths splits -config's comma-separated list of files, expanding
+// each path.
+func configPaths(list string) ([]string, error) {
+	var paths []string
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		expanded, err := expandPath(p)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, expanded)
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("-config needs at least one file")
+	}
+	return paths, nil
+}
+
+// mergeFileConfig lays the settings a config file sets over cfg, field by
+// field; zero values count as unset.
+func mergeFileConfig(cfg, fileConfig Config) Config {
+	if fileConfig.InputFile != "" {
+		cfg.InputFile = fileConfig.InputFile
+	}
+	if fileConfig.MinSilenceDur != 0.0 {
+		cfg.MinSilenceDur = fileConfig.MinSilenceDur
+	}
+	if fileConfig.SilenceThreshold != "" {
+		cfg.SilenceThreshold = fileConfig.SilenceThreshold
+	}
+	if fileConfig.MinSongLength != 0.0 {
+		cfg.MinSongLength = fileConfig.MinSongLength
+	}
+	if fileConfig.OutputPrefix != "" {
+		cfg.OutputPrefix = fileConfig.OutputPrefix
+	}
+	if fileConfig.OutputDir != "" {
+		cfg.OutputDir = fileConfig.OutputDir
+	}
+	if fileConfig.UploadToDrive {
+		cfg.UploadToDrive = fileConfig.UploadToDrive
+	}
+	if fileConfig.RcloneRemote != "" {
+		cfg.RcloneRemote = fileConfig.RcloneRemote
+	}
+	if fileConfig.DriveSubfolder != "" {
+		cfg.DriveSubfolder = fileConfig.DriveSubfolder
+	}
+	if fileConfig.SetlistFile != "" {
+		cfg.SetlistFile = fileConfig.SetlistFile
+	}
+	if fileConfig.MapAllAudio {
+		cfg.MapAllAudio = fileConfig.MapAllAudio
+	}
+	if fileConfig.MinExpectedSegments != 0 {
+		cfg.MinExpectedSegments = fileConfig.MinExpectedSegments
+	}
+	if fileConfig.MaxExpectedSegments != 0 {
+		cfg.MaxExpectedSegments = fileConfig.MaxExpectedSegments
+	}
+	if fileConfig.ExportChatter {
+		cfg.ExportChatter = fileConfig.ExportChatter
+	}
+	if fileConfig.Reencode {
+		cfg.Reencode = fileConfig.Reencode
+	}
+	if fileConfig.VideoCRF != 0 {
+		cfg.VideoCRF = fileConfig.VideoCRF
+	}
+	if fileConfig.VideoBitrate != "" {
+		cfg.VideoBitrate = fileConfig.VideoBitrate
+	}
+	if fileConfig.AudioBitrate != "" {
+		cfg.AudioBitrate = fileConfig.AudioBitrate
+	}
+	if fileConfig.AutoTrim {
+		cfg.AutoTrim = fileConfig.AutoTrim
+	}
+	if fileConfig.FFmpegLogLevel != "" {
+		cfg.FFmpegLogLevel = fileConfig.FFmpegLogLevel
+	}
+	if fileConfig.MonoDetection {
+		cfg.MonoDetection = fileConfig.MonoDetection
+	}
+	if fileConfig.NotifyWebhook != "" {
+		cfg.NotifyWebhook = fileConfig.NotifyWebhook
+	}
+	if fileConfig.NotifyFormat != "" {
+		cfg.NotifyFormat = fileConfig.NotifyFormat
+	}
+	if fileConfig.NoSplit {
+		cfg.NoSplit = fileConfig.NoSplit
+	}
+	if fileConfig.DetectionMode != "" {
+		cfg.DetectionMode = fileConfig.DetectionMode
+	}
+	if fileConfig.SeekMode != "" {
+		cfg.SeekMode = fileConfig.SeekMode
+	}
+	if fileConfig.AudacityLabels {
+		cfg.AudacityLabels = fileConfig.AudacityLabels
+	}
+	if fileConfig.OutputMode != "" {
+		cfg.OutputMode = fileConfig.OutputMode
+	}
+	if fileConfig.LosslessBoundaries {
+		cfg.LosslessBoundaries = fileConfig.LosslessBoundaries
+	}
+	if fileConfig.SourceChapters {
+		cfg.SourceChapters = fileConfig.SourceChapters
+	}
+	if fileConfig.TrimSilence {
+		cfg.TrimSilence = fileConfig.TrimSilence
+	}
+	if fileConfig.Cache {
+		cfg.Cache = fileConfig.Cache
+	}
+	if fileConfig.UploadMode != "" {
+		cfg.UploadMode = fileConfig.UploadMode
+	}
+	if fileConfig.LoudnessReport {
+		cfg.LoudnessReport = fileConfig.LoudnessReport
+	}
+	if fileConfig.OutputContainer != "" {
+		cfg.OutputContainer = fileConfig.OutputContainer
+	}
+	if fileConfig.Tracklist != "" {
+		cfg.Tracklist = fileConfig.Tracklist
+	}
+	if fileConfig.SnapKeyframes {
+		cfg.SnapKeyframes = fileConfig.SnapKeyframes
+	}
+	if fileConfig.AutoTune {
+		cfg.AutoTune = fileConfig.AutoTune
+	}
+	if fileConfig.ExpectedSongs != 0 {
+		cfg.ExpectedSongs = fileConfig.ExpectedSongs
+	}
+	if fileConfig.BoundariesFile != "" {
+		cfg.BoundariesFile = fileConfig.BoundariesFile
+	}
+	if fileConfig.OutputFlat {
+		cfg.OutputFlat = fileConfig.OutputFlat
+	}
+	if fileConfig.ProofScale != "" {
+		cfg.ProofScale = fileConfig.ProofScale
+	}
+	if fileConfig.TempDir != "" {
+		cfg.TempDir = fileConfig.TempDir
+	}
+	if fileConfig.ReplayGain {
+		cfg.ReplayGain = fileConfig.ReplayGain
+	}
+	if fileConfig.TargetCount != 0 {
+		cfg.TargetCount = fileConfig.TargetCount
+	}
+	if fileConfig.Artist != "" {
+		cfg.Artist = fileConfig.Artist
+	}
+	if fileConfig.Album != "" {
+		cfg.Album = fileConfig.Album
+	}
+	if len(fileConfig.MetadataTemplates) > 0 {
+		cfg.MetadataTemplates = fileConfig.MetadataTemplates
+	}
+	if fileConfig.HandleVFR != "" {
+		cfg.HandleVFR = fileConfig.HandleVFR
+	}
+	if fileConfig.KeepSubtitles {
+		cfg.KeepSubtitles = fileConfig.KeepSubtitles
+	}
+	if fileConfig.LogFile != "" {
+		cfg.LogFile = fileConfig.LogFile
+	}
+	if len(fileConfig.RcloneGlobalFlags) > 0 {
+		cfg.RcloneGlobalFlags = fileConfig.RcloneGlobalFlags
+	}
+	if fileConfig.CoverArt != "" {
+		cfg.CoverArt = fileConfig.CoverArt
+	}
+	if fileConfig.ExportGaps {
+		cfg.ExportGaps = fileConfig.ExportGaps
+	}
+	if fileConfig.GapsName != "" {
+		cfg.GapsName = fileConfig.GapsName
+	}
+	if fileConfig.Jobs != 0 {
+		cfg.Jobs = fileConfig.Jobs
+	}
+	if fileConfig.FallbackInterval != 0 {
+		cfg.FallbackInterval = fileConfig.FallbackInterval
+	}
+	if fileConfig.FallbackMinSongs != 0 {
+		cfg.FallbackMinSongs = fileConfig.FallbackMinSongs
+	}
+	if fileConfig.MultiThreshold {
+		cfg.MultiThreshold = fileConfig.MultiThreshold
+	}
+	if len(fileConfig.CandidateThresholds) > 0 {
+		cfg.CandidateThresholds = fileConfig.CandidateThresholds
+	}
+	if fileConfig.Archive != "" {
+		cfg.Archive = fileConfig.Archive
+	}
+	if fileConfig.Checksums {
+		cfg.Checksums = fileConfig.Checksums
+	}
+	if fileConfig.PreserveAbsoluteTiming {
+		cfg.PreserveAbsoluteTiming = fileConfig.PreserveAbsoluteTiming
+	}
+	if fileConfig.VerifyDurations {
+		cfg.VerifyDurations = fileConfig.VerifyDurations
+	}
+	if fileConfig.DurationTolerance != 0 {
+		cfg.DurationTolerance = fileConfig.DurationTolerance
+	}
+	if fileConfig.RecutDurations {
+		cfg.RecutDurations = fileConfig.RecutDurations
+	}
+	if fileConfig.AnalysisWindows != "" {
+		cfg.AnalysisWindows = fileConfig.AnalysisWindows
+	}
+	if fileConfig.SessionName != "" {
+		cfg.SessionName = fileConfig.SessionName
+	}
+	if fileConfig.FullSet {
+		cfg.FullSet = fileConfig.FullSet
+	}
+	if fileConfig.PostHook != "" {
+		cfg.PostHook = fileConfig.PostHook
+	}
+	if fileConfig.OnNoSongs != "" {
+		cfg.OnNoSongs = fileConfig.OnNoSongs
+	}
+	if fileConfig.ChunkLength != 0 {
+		cfg.ChunkLength = fileConfig.ChunkLength
+	}
+	if fileConfig.HWAccel != "" {
+		cfg.HWAccel = fileConfig.HWAccel
+	}
+	if fileConfig.UploadNoTraverse {
+		cfg.UploadNoTraverse = fileConfig.UploadNoTraverse
+	}
+	if fileConfig.UploadImmutable {
+		cfg.UploadImmutable = fileConfig.UploadImmutable
+	}
+	if fileConfig.TimestampRounding != "" {
+		cfg.TimestampRounding = fileConfig.TimestampRounding
+	}
+	if fileConfig.SkipLeadIn {
+		cfg.SkipLeadIn = fileConfig.SkipLeadIn
+	}
+	if fileConfig.LeadInThreshold != "" {
+		cfg.LeadInThreshold = fileConfig.LeadInThreshold
+	}
+	if fileConfig.EmbedTitleMetadata {
+		cfg.EmbedTitleMetadata = fileConfig.EmbedTitleMetadata
+	}
+	if fileConfig.BoundarySilenceDur != 0 {
+		cfg.BoundarySilenceDur = fileConfig.BoundarySilenceDur
+	}
+	if fileConfig.HandleDTSWarnings {
+		cfg.HandleDTSWarnings = fileConfig.HandleDTSWarnings
+	}
+	if fileConfig.FixedInterval != 0 {
+		cfg.FixedInterval = fileConfig.FixedInterval
+	}
+	if len(fileConfig.UploadDestinations) > 0 {
+		cfg.UploadDestinations = fileConfig.UploadDestinations
+	}
+	return cfg
+}
+
+// loadConfigFromFile helper (unchanged)
+func loadConfigFromFile(path string) (Config, error) {
+	var fileConfig Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fileConfig, err
+	}
+	err = json.Unmarshal(data, &fileConfig)
+	if err != nil {
+		return fileConfig, err
+	}
+	return fileConfig, nil
+}
+
+// main is the entry point of our script
+func main() {
+	// 1. Define & Parse flags
+	defineFlags()
+	flag.Parse()
+
+	// 2. Load configuration
+	log.SetOutput(colorLogWriter(os.Stderr, colorMode, isTerminal(os.Stderr)))
+	log.Println("Starting practice splitter...")
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		log.Printf("Error loading configuration: %v", err)
+		os.Exit(exitConfig)
+	}
+	if path := logFilePath(cfg); path != "" {
+		closeLog, err := openLogFile(path)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(exitConfig)
+		}
+		defer closeLog()
+		log.Printf("Logging to %s", path)
+	}
+	switch colorMode {
+	case colorAuto, colorAlways, colorNever:
+	default:
+		log.Printf("Warning: Unknown -color '%s', expected auto, always or never; using auto.", colorMode)
+	}
+	for _, w := range warnings {
+		log.Printf("Warning: %s", w)
+	}
+
+	// 3. List the config file's profiles
+	if listProfiles {
+		paths, _ := configPaths(configFilePath) // checked by loadConfig
+		if err := listConfigProfiles(os.Stdout, paths); err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(exitConfig)
+		}
+		return
+	}
+
+	// 4. Doctor mode only checks the environment
+	if doctorMode {
+		results := runDoctor(cfg)
+		printChecklist(os.Stdout, results)
+		if hasCriticalFailure(results) {
+			os.Exit(exitDoctorFailed)
+		}
+		return
+	}
+
+	// 5. Probe mode only describes the input
+	if probeMode {
+		if !isFFprobeInstalled() {
+			log.Println("Error: 'ffprobe' command not found. It ships with FFmpeg; please make sure it's in your system's PATH.")
+			os.Exit(exitMissingTool)
+		}
+		info, err := probeMedia(cfg.InputFile)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printProbeInfo(os.Stdout, cfg.InputFile, info)
+		return
+	}
+
+	// 6. Rename an existing folder from the setlist, without splitting
+	if normalizeDir != "" {
+		if err := normalizeFilenames(normalizeDir, cfg.SetlistFile, normalizeOrder, cfg.SessionName); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	// 7. Upload an already-split folder, without splitting
+	if uploadOnlyMode {
+		if noUpload {
+			log.Println("Error: -upload-only and -no-upload can't both be set")
+			os.Exit(exitConfig)
+		}
+		if err := uploadOnly(cfg); err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(exitCodeFor(err))
+		}
+		return
+	}
+
+	// 8. Serve split requests over HTTP instead of running once
+	if serveAddr != "" {
+		token := serveToken
+		if token == "" {
+			token = os.Getenv(serveTokenEnv)
+		}
+		if err := serve(serveAddr, cfg, token); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	// 9. Run each input, writing its report even if the run fails part-way
+	if inputs := flag.Args(); len(inputs) > 0 {
+		if code := runBatch(cfg, inputs, reportPath); code != exitOK {
+			os.Exit(code)
+		}
+	} else if code := runInput(cfg, reportPath); code != exitOK {
+		os.Exit(code)
+	}
+
+	log.Println("\nAll done!")
+}
+
+// runInput splits one recording, writes its report and sends the completion
+// notification, returning the exit code for the run.
+func runInput(cfg Config, reportPath string) int {
+	splitter := NewSplitter(cfg)
+	splitter.ProgressFunc = logProgress
+	runErr := splitter.Run()
+	rep := splitter.Report
+	if reportPath != "" {
+		manifest := rep
+		if cfg.RelativeTimestamps {
+			manifest = rep.withFileTimes()
+		}
+		if err := writeReport(reportPath, manifest); err != nil {
+			log.Printf("Error: Could not write report '%s': %v", reportPath, err)
+		} else {
+			log.Printf("Wrote run report to '%s'", reportPath)
+		}
+	}
+	if cfg.NotifyWebhook != "" {
+		if err := sendNotification(cfg.NotifyWebhook, cfg.NotifyFormat, newNotification(rep)); err != nil {
+			log.Printf("Warning: Could not send completion notification: %v", err)
+		}
+	}
+	if runErr != nil {
+		log.Printf("Error: %v", runErr)
+	}
+	return exitCodeFor(runErr)
+}
+
+// Splitter splits one recording with a fixed config, recording what
+// happened in Report.
+type Splitter struct {
+	Config Config
+	Report *runReport
+	// ProgressFunc, if set, is called at each milestone of Run.
+	ProgressFunc func(ProgressEvent)
+}
+
+// NewSplitter prepares a run with cfg.
+func NewSplitter(cfg Config) *Splitter {
+	return &Splitter{Config: cfg, Report: newRunReport(cfg)}
+}
+
+// Run detects, exports, renames and uploads the songs, then stamps the
+// report with the outcome. Use exitCodeFor to classify the error.
+func (s *Splitter) Run() error {
+	err := s.run()
+	s.Report.finish(err)
+	return err
+}
+
+func (s *Splitter) run() error {
+	cfg, rep := s.Config, s.Report
+	log.Printf("Using config: Input='%s', Duration=%.1fs, Threshold=%s, MinSong=%.1fs, Output='%s'",
+		cfg.InputFile, cfg.MinSilenceDur, cfg.SilenceThreshold, cfg.MinSongLength, cfg.OutputDir)
+
+	// 1. --- rclone Pre-Check ---
+	if cfg.UploadToDrive && !cfg.CheckSetlist && !cfg.DetectOnly {
+		if cfg.UploadMode == uploadSync && !cfg.ConfirmSync {
+			return withExitCode(exitConfig, errors.New("upload_mode 'sync' deletes remote files that aren't in the output folder; pass -confirm-sync to allow it"))
+		}
+		done := rep.startStage("pre-check")
+		log.Println("Upload enabled, running rclone pre-check...")
+		if !isRcloneInstalled() {
+			return withExitCode(exitMissingTool, errors.New("'upload_to_drive' is true but 'rclone' was not found in your PATH"))
+		}
+
+		reachable := true
+		for _, dest := range uploadDestinations(cfg) {
+			err := testRcloneConnection(dest, cfg.RcloneGlobalFlags)
+			if errors.Is(err, errRemoteUnreachable) && !cfg.NonInteractive && proceedWithoutPrecheck(os.Stdin, os.Stdout, isTerminal(os.Stdin)) {
+				log.Printf("Warning: %v\nContinuing; the upload will be attempted after the export.", err)
+				reachable = false
+				continue
+			}
+			if err != nil {
+				return withExitCode(exitUploadFailed, fmt.Errorf("rclone pre-check failed: %v\nPlease check 'rclone config' and your remote permissions", err))
+			}
+		}
+		if reachable {
+			log.Println("rclone connection successful.")
+		}
+		done()
+	}
+
+	// 2. Check for ffmpeg
+	if !isFFmpegInstalled() {
+		return withExitCode(exitMissingTool, errors.New("'ffmpeg' command not found. Please install FFmpeg and ensure it's in your system's PATH"))
+	}
+
+	// 3. Check if input file exists
+	if _, err := os.Stat(cfg.InputFile); os.IsNotExist(err) {
+		return withExitCode(exitMissingInput, fmt.Errorf("input file '%s' not found", cfg.InputFile))
+	}
+
+	// 4. Get video duration
+	done := rep.startStage("duration")
+	totalDuration, err := getVideoDuration(cfg)
+	done()
+	if err != nil {
+		return withExitCode(exitDetectionFailed, err)
+	}
+	log.Printf("Total video duration: %.2f seconds", totalDuration)
+
+	// 5. Check the input's streams: codecs a new output container can't
+	// copy, variable frame rate video, which a stream copy can desync, and
+	// subtitles the output container can't hold
+	cfg = checkInputStreams(cfg)
+	rep.Config.Reencode = reencoding(cfg)
+
+	// 6. Find the song boundaries, or reuse ones from an earlier run, a cut
+	// list or the input's chapters
+	var songSegments, silences []segment
+	var sourceTitles []string
+	s.emit(ProgressEvent{Kind: ProgressDetectionStarted})
+	if cfg.ManifestPath != "" {
+		songSegments, sourceTitles, err = loadManifest(cfg.ManifestPath)
+		if err != nil {
+			return withExitCode(exitDetectionFailed, fmt.Errorf("could not load manifest '%s': %v", cfg.ManifestPath, err))
+		}
+		log.Printf("Loaded %d song(s) from manifest '%s', skipping detection.", len(songSegments), cfg.ManifestPath)
+	} else if cfg.BoundariesFile != "" {
+		songSegments, sourceTitles, err = loadBoundaries(cfg.BoundariesFile)
+		if err == nil {
+			songSegments, err = clampBoundaries(songSegments, totalDuration)
+		}
+		if err != nil {
+			return withExitCode(exitDetectionFailed, fmt.Errorf("could not load boundaries '%s': %v", cfg.BoundariesFile, err))
+		}
+		log.Printf("Loaded %d song(s) from '%s', skipping detection.", len(songSegments), cfg.BoundariesFile)
+	} else {
+		done = rep.startStage("detection")
+		if cfg.SourceChapters {
+			songSegments, sourceTitles = sourceChapterSegments(cfg)
+		}
+		if len(songSegments) == 0 {
+			if (cfg.AutoTune || cfg.TargetCount > 0 || cfg.MultiThreshold) && !cfg.NoSplit && cfg.ChunkLength == 0 {
+				var threshold string
+				if cfg.TargetCount > 0 {
+					threshold, silences, err = searchThresholdForCount(cfg, totalDuration)
+				} else if cfg.MultiThreshold {
+					threshold, silences, err = multiThreshold(cfg, totalDuration)
+				} else {
+					threshold, silences, err = autoTuneThreshold(cfg, totalDuration)
+				}
+				if err != nil {
+					log.Printf("Warning: %v", err)
+				}
+				log.Printf("Using silence threshold %s.", threshold)
+				cfg.SilenceThreshold = threshold
+				rep.Config.SilenceThreshold = threshold
+				songSegments = songsFromSilences(cfg, silences, totalDuration)
+			} else {
+				songSegments, silences = findSongSegments(cfg, totalDuration)
+			}
+			if songSegments, rep.IntervalFallback = intervalFallback(cfg, songSegments, totalDuration); rep.IntervalFallback {
+				silences = nil // the chunks cover everything, silences included
+			}
+			songSegments = noSongsFallback(cfg, songSegments, silences, totalDuration)
+			if cfg.SkipLeadIn && len(silences) > 0 {
+				var leadIn []segment
+				songSegments, leadIn = skipLeadIn(cfg, songSegments)
+				for _, seg := range leadIn {
+					rep.Skipped = append(rep.Skipped, segmentResult{Start: seg.start, End: seg.end, Status: statusSkipped})
+				}
+			}
+			rep.recordSkipped(silences, songSegments, totalDuration, cfg)
+			if len(silences) > 0 {
+				log.Print(summarizeSegmentLengths(candidateSegments(silences, totalDuration, cfg), cfg.MinSongLength))
+			}
+		}
+		done()
+	}
+	s.emit(ProgressEvent{Kind: ProgressDetectionFinished, Total: len(songSegments)})
+
+	// 7. Join two songs detection split apart, with -merge (Optional)
+	if cfg.MergeSongs != "" {
+		a, b, err := parseMergePair(cfg.MergeSongs)
+		if err == nil {
+			songSegments, err = mergeAdjacent(songSegments, a, b)
+		}
+		if err != nil {
+			return withExitCode(exitConfig, err)
+		}
+		sourceTitles = mergeTitles(sourceTitles, a, b)
+		log.Printf("Merged songs %d and %d into song %d (%.2fs - %.2fs).", a, b, a, songSegments[a-1].start, songSegments[a-1].end)
+	}
+
+	// 8. Tighten song edges (Optional)
+	if cfg.AutoTrim && len(songSegments) > 0 {
+		done = rep.startStage("auto-trim")
+		songSegments = autoTrimSegments(cfg, songSegments)
+		done()
+	}
+
+	// 9. Review and edit the boundaries (Optional)
+	if cfg.Interactive {
+		if isTerminal(os.Stdin) {
+			songSegments, err = editSegmentsInteractive(os.Stdin, promptOutput(cfg), songSegments)
+			if err != nil {
+				return err
+			}
+		} else {
+			log.Println("Skipping interactive editor, stdin is not a terminal.")
+		}
+	}
+
+	// 10. Round the boundaries to whole seconds or frames (Optional)
+	songSegments = applyTimestampRounding(cfg, songSegments)
+
+	// 11. Line song starts up with the keyframes a stream copy cuts on (Optional)
+	if cfg.SnapKeyframes && len(songSegments) > 0 {
+		done = rep.startStage("keyframes")
+		songSegments = snapSegmentsToKeyframes(cfg, songSegments)
+		done()
+	}
+
+	// 12. Sanity-check the song count before spending time on the export
+	if err := checkSegmentCount(len(songSegments), cfg); err != nil {
+		return withExitCode(exitDetectionFailed, err)
+	}
+
+	// 13. Print the boundaries and stop for -detect-only
+	if cfg.DetectOnly {
+		return printBoundaries(os.Stdout, songSegments, cfg.JSONOutput)
+	}
+
+	// 14. Load the setlist before exporting, since it can carry per-song options
+	var songList setlist
+	if cfg.SetlistFile != "" {
+		if cfg.SetlistFile == stdinPath && cfg.stdinSetlist != nil {
+			songList, err = parseSetlist(bytes.NewReader(cfg.stdinSetlist))
+		} else {
+			songList, err = loadSetlist(cfg.SetlistFile)
+		}
+		if err != nil {
+			log.Printf("Error: Could not read setlist file '%s': %v", cfg.SetlistFile, err)
+			log.Println("Continuing without setlist.")
+		}
+		if cfg.IgnoreSetlistArgs && len(songList.extraArgs) > 0 {
+			log.Printf("Warning: ignoring the extra-args of %d song(s) in setlist '%s'; a -serve request can't pass args to ffmpeg.", len(songList.extraArgs), cfg.SetlistFile)
+			songList.extraArgs = make(map[int][]string)
+		}
+	} else {
+		songList.titles = sourceTitles
+	}
+
+	// 15. Compare the setlist with the songs and estimate output sizes, stopping here for -check-setlist
+	if cfg.CheckSetlist {
+		mismatch := printSetlistCheck(os.Stdout, songSegments, songList.titles)
+		printSizeEstimate(os.Stdout, cfg, songSegments)
+		if mismatch != nil && cfg.StrictSetlist {
+			return withExitCode(exitSetlistMismatch, mismatch)
+		}
+		return nil
+	}
+	if cfg.StrictSetlist {
+		if err := setlistMismatch(len(songSegments), len(songList.titles)); err != nil {
+			return withExitCode(exitSetlistMismatch, err)
+		}
+	}
+
+	// 16. Pick the songs to export with -only (Optional)
+	var only []int
+	if cfg.OnlySongs != "" {
+		if only, err = parseSegmentIndices(cfg.OnlySongs); err == nil {
+			err = checkSegmentIndices(only, len(songSegments))
+		}
+		if err != nil {
+			return withExitCode(exitConfig, err)
+		}
+		if cfg.OutputMode == outputChapters {
+			log.Println("Warning: -only is ignored with output_mode 'chapters', which always writes the whole recording.")
+			only = nil
+		} else {
+			log.Printf("Exporting only song(s) %s of %d.", cfg.OnlySongs, len(songSegments))
+		}
+	}
+
+	// 17. Name the output folder now that the song count is known
+	if dir := resolveOutputDir(cfg.OutputDir, len(songSegments)); dir != cfg.OutputDir {
+		log.Printf("Output directory: %s", dir)
+		cfg.OutputDir = dir
+		rep.Config.OutputDir = dir
+	}
+
+	// 18. Clear out or refuse an output folder an earlier run left files in (Optional)
+	if err := prepareRunOutputDir(cfg, []string{cfg.InputFile}); err != nil {
+		return err
+	}
+	if cfg.MergeSongs != "" && cfg.ManifestPath != "" {
+		if only != nil {
+			log.Println("Warning: keeping the manifest's song files, since -only re-exports just some songs; use -clean-output to replace them all.")
+		} else {
+			removeSupersededSongs(cfg.OutputDir, manifestFiles(cfg.ManifestPath))
+		}
+	}
+
+	// 19. Export valid songs
+	if reencoding(cfg) && cfg.VideoCRF > 0 && cfg.VideoBitrate != "" {
+		log.Printf("Warning: both video_crf (%d) and video_bitrate (%s) are set; using CRF.", cfg.VideoCRF, cfg.VideoBitrate)
+	}
+	if hwAccelActive(cfg) && !encoderAvailable(hwAccels[cfg.HWAccel].encoder) {
+		log.Printf("Warning: this ffmpeg has no %s encoder for hw_accel '%s'; re-encoding in software.", hwAccels[cfg.HWAccel].encoder, cfg.HWAccel)
+		cfg.HWAccel = hwAccelNone
+		rep.Config.HWAccel = cfg.HWAccel
+	}
+	cfg.Jobs = exportJobs(cfg) // re-encoding and hw_accel are settled by now
+	if cfg.VerifyDurations && !isFFprobeInstalled() {
+		log.Println("Warning: verify_durations needs ffprobe, which wasn't found; not checking song lengths.")
+		cfg.VerifyDurations = false
+	}
+	rep.Config.Jobs = cfg.Jobs
+	var exportedFiles []string
+	var state *exportState // set with -cache
+	if len(songSegments) == 0 {
+		log.Println("No song segments found that meet the minimum length criteria.")
+	} else {
+		log.Printf("Found %d non-silent (song) segment(s) that meet criteria.", len(songSegments))
+		if cfg.Cache {
+			state = loadState(cfg.OutputDir)
+			if cfg.ForceExport {
+				state = newExportState()
+			}
+		}
+		done = rep.startStage("export")
+		if cfg.OutputMode == outputChapters {
+			labels := make([]string, len(songSegments))
+			for i := range labels {
+				labels[i] = songLabel(i, songList.titles, cfg.OutputPrefix)
+			}
+			rep.Segments = exportChapters(cfg, songSegments, labels)
+		} else {
+			rep.Segments = splitVideoIntoSegments(cfg, songSegments, exportOptions{extraArgs: songList.extraArgs, state: state, titles: songList.titles, only: only, onDone: s.segmentDone})
+		}
+		done()
+		exportedFiles = exportedPaths(rep.Segments)
+		if cfg.OutputMode == outputChapters && len(exportedFiles) > 0 {
+			exportedFiles = exportedFiles[:1]
+		}
+		if len(exportedFiles) == 0 {
+			return withExitCode(exitAllSegmentsFailed, fmt.Errorf("all %d song(s) failed to export", len(rep.Segments)))
+		}
+	}
+
+	// 20. Write the boundaries as an Audacity label track
+	if cfg.AudacityLabels && len(songSegments) > 0 {
+		labelsPath := filepath.Join(cfg.OutputDir, audacityLabelsFile)
+		if err := writeAudacityLabels(labelsPath, songSegments, songList.titles, cfg.OutputPrefix); err != nil {
+			log.Printf("Warning: Could not write Audacity labels '%s': %v", labelsPath, err)
+		} else {
+			log.Printf("Wrote Audacity labels to %s", labelsPath)
+		}
+	}
+
+	// 21. Write a timestamped tracklist for the full recording (Optional)
+	if cfg.Tracklist != "" && len(songSegments) > 0 {
+		labels := make([]string, len(songSegments))
+		for i := range labels {
+			labels[i] = songLabel(i, songList.titles, cfg.OutputPrefix)
+		}
+		if err := writeTracklist(cfg.Tracklist, songSegments, labels, cfg.PreserveAbsoluteTiming); err != nil {
+			log.Printf("Warning: Could not write tracklist '%s': %v", cfg.Tracklist, err)
+		} else {
+			log.Printf("Wrote tracklist to %s", cfg.Tracklist)
+		}
+	}
+
+	// 22. --- Rename from Setlist (Optional) ---
+	if (cfg.SetlistFile != "" || len(sourceTitles) > 0) && cfg.OutputMode != outputChapters {
+		if len(exportedFiles) == 0 {
+			log.Println("Skipping setlist rename, no files were exported.")
+		} else if len(songList.titles) == 0 {
+			log.Println("Skipping setlist rename, no song titles were loaded.")
+		} else {
+			done = rep.startStage("rename")
+			titles := songList.titles
+			if only != nil {
+				// Name each re-exported song after its own number and title.
+				numbers := exportedNumbers(rep.Segments)
+				exportedFiles = renameSongFiles(exportedFiles, numbers, titles, cfg.SessionName)
+				titles = titlesForNumbers(numbers, titles)
+			} else {
+				exportedFiles = renameFilesFromSetlist(exportedFiles, titles, cfg.SessionName)
+			}
+			done()
+			rep.updateExportedPaths(exportedFiles)
+			rep.recordTitles(titles)
+			if err := writeResolvedSetlist(cfg.OutputDir, resolvedSetlist(rep.Segments, songList.titles, len(songSegments))); err != nil {
+				log.Printf("Warning: Could not write %s: %v", resolvedSetlistFile, err)
+			}
+		}
+	}
+
+	// 23. Make low-resolution proof copies for quick review (Optional)
+	if cfg.ProofScale != "" && len(exportedFiles) > 0 {
+		done = rep.startStage("proofs")
+		exportProofs(cfg, exportedFiles)
+		done()
+	}
+
+	// 24. Report how much space the songs take, and how loud they are
+	if len(exportedFiles) > 0 {
+		logOutputSummary(cfg.InputFile, exportedFiles)
+	}
+	if (cfg.LoudnessReport || cfg.ReplayGain) && len(exportedFiles) > 0 {
+		done = rep.startStage("loudness")
+		if cfg.LoudnessReport {
+			measureSegmentLoudness(rep.Segments)
+		}
+		if cfg.ReplayGain {
+			applyReplayGain(rep.Segments)
+		}
+		done()
+		log.Printf("Loudness per song:\n%s", loudnessTable(rep.Segments))
+	}
+
+	// 25. Export the between-song chatter (Optional)
+	if cfg.ExportChatter {
+		chatter := invertSegments(songSegments, totalDuration)
+		if len(chatter) == 0 {
+			log.Println("No chatter segments to export.")
+		} else {
+			log.Printf("Exporting %d chatter segment(s).", len(chatter))
+			chatterCfg := cfg
+			chatterCfg.OutputDir = filepath.Join(cfg.OutputDir, chatterDirName)
+			chatterCfg.OutputPrefix = "Chatter"
+			done = rep.startStage("chatter")
+			rep.Chatter = splitVideoIntoSegments(chatterCfg, chatter, exportOptions{state: state})
+			done()
+		}
+	}
+
+	// 26. Join the between-song gaps into one file (Optional)
+	if cfg.ExportGaps {
+		if gaps := invertSegments(songSegments, totalDuration); len(gaps) == 0 {
+			log.Println("No gaps between songs to join.")
+		} else {
+			log.Printf("Joining %d gap(s) between songs into one file.", len(gaps))
+			done = rep.startStage("gaps")
+			path, err := exportGaps(cfg, gaps)
+			done()
+			if err != nil {
+				log.Printf("Warning: Could not export the gaps between songs: %v", err)
+			} else {
+				log.Printf("Wrote the gaps between songs to %s", path)
+				rep.GapsFile = path
+			}
+		}
+	}
+
+	// 27. Join the songs into one full-set file (Optional)
+	if cfg.FullSet && len(exportedFiles) > 0 {
+		if only != nil {
+			log.Println("Skipping the full set, -only exported just some of the songs.")
+		} else {
+			log.Printf("Joining %d song(s) into one full-set file.", len(exportedFiles))
+			done = rep.startStage("full-set")
+			path, err := exportFullSet(cfg, exportedFiles)
+			done()
+			if err != nil {
+				log.Printf("Warning: Could not export the full set: %v", err)
+			} else {
+				log.Printf("Wrote the full set to %s", path)
+				rep.FullSetFile = path
+			}
+		}
+	}
+
+	// 28. Write checksums of the exported files (Optional)
+	if cfg.Checksums {
+		if err := writeChecksums(cfg.OutputDir, checksumFiles(rep)); err != nil {
+			log.Printf("Warning: Could not write %s: %v", checksumsFileName, err)
+		} else {
+			log.Printf("Wrote checksums to %s", filepath.Join(cfg.OutputDir, checksumsFileName))
+		}
+	}
+
+	// 29. Pack the output folder into one archive (Optional)
+	if cfg.Archive != "" {
+		done = rep.startStage("archive")
+		path, err := createArchive(cfg.OutputDir, cfg.Archive)
+		done()
+		if err != nil {
+			log.Printf("Warning: Could not create the %s archive, uploading the folder instead: %v", cfg.Archive, err)
+			cfg.Archive = ""
+		} else {
+			log.Printf("Packed the output folder into %s", path)
+			rep.Archive = path
+		}
+	}
+
+	// 30. Remember what was exported, so the next run can skip it
+	outputs := append(append([]segmentResult(nil), rep.Segments...), rep.Chatter...)
+	if state != nil {
+		state.record(outputs)
+		if err := state.save(cfg.OutputDir); err != nil {
+			log.Printf("Warning: Could not save export state: %v", err)
+		}
+	}
+
+	// 31. Upload to Drive (Optional)
+	if cfg.UploadToDrive && !cfg.skipUpload {
+		uploadCfg := cfg
+		if state != nil {
+			uploadCfg.UploadDestinations = state.pendingDestinations(outputs, uploadDestinations(cfg))
+		}
+		if _, err := os.Stat(cfg.OutputDir); os.IsNotExist(err) {
+			log.Printf("Skipping upload, output directory '%s' does not exist.", cfg.OutputDir)
+		} else if state != nil && len(uploadCfg.UploadDestinations) == 0 {
+			log.Println("Skipping upload, nothing changed since the last upload.")
+		} else {
+			done = rep.startStage("upload")
+			for _, dest := range uploadDestinations(uploadCfg) {
+				s.emit(ProgressEvent{Kind: ProgressUploadStarted, Destination: dest.path()})
+			}
+			rep.Uploads = uploadToDrive(uploadCfg)
+			done()
+			for _, u := range rep.Uploads {
+				e := ProgressEvent{Kind: ProgressUploadFinished, Destination: u.Destination}
+				if u.Status == statusFailed {
+					e.Err = errors.New(u.Error)
+				}
+				s.emit(e)
+			}
+			if state != nil {
+				for _, u := range rep.Uploads {
+					if u.Status == statusUploaded {
+						state.markUploaded(outputs, u.Destination)
+					}
+				}
+				if err := state.save(cfg.OutputDir); err != nil {
+					log.Printf("Warning: Could not save export state: %v", err)
+				}
+			}
+			if failed := failedUploads(rep.Uploads); failed > 0 {
+				return withExitCode(exitUploadFailed, fmt.Errorf("upload failed for %d of %d destination(s)", failed, len(rep.Uploads)))
+			}
+		}
+	}
+
+	return nil
+}
+
+// --- Helper Functions ---
+
+// isFFmpegInstalled (unchanged)
+func isFFmpegInstalled() bool {
+	cmd := execCommand("ffmpeg", "-version")
+	if err := cmd.Run(); err != nil {
+		return false
+	}
+	return true
+}
+
+// runFFmpeg runs ffmpeg at the given log level and returns its stderr.
+// Anything that parses ffmpeg's informational output (the "Duration:" line,
+// silencedetect's silence_start/silence_end) must pass analysisLogLevel,
+// since those lines are logged at info level and vanish at "warning".
+func runFFmpeg(logLevel string, args ...string) (string, error) {
+	cmd := execCommand("ffmpeg", ffmpegArgs(logLevel, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stderr.String(), err
+}
+
+// ffmpegArgs prefixes args with the options that keep ffmpeg's stderr quiet:
+// no build banner, and only messages at logLevel or above.
+func ffmpegArgs(logLevel string, args ...string) []string {
+	if logLevel == "" {
+		logLevel = defaultConfig.FFmpegLogLevel
+	}
+	return append([]string{"-hide_banner", "-loglevel", logLevel}, args...)
+}
+
+// isRcloneInstalled (unchanged)
+func isRcloneInstalled() bool {
+	cmd := execCommand("rclone", "version")
+	if err := cmd.Run(); err != nil {
+		return false
+	}
+	return true
+}
+
+// uploadDestinations returns the configured upload destinations, falling back
+// to the single RcloneRemote/DriveSubfolder pair.
+func uploadDestinations(cfg Config) []UploadDestination {
+	if len(cfg.UploadDestinations) > 0 {
+		return cfg.UploadDestinations
+	}
+	return []UploadDestination{{Remote: cfg.RcloneRemote, Subfolder: cfg.DriveSubfolder}}
+}
+
+// testRcloneConnection checks that the destination folder can be created
+func testRcloneConnection(dest UploadDestination, globalFlags []string) error {
+	log.Println("Verifying rclone remote and permissions...")
+	destination := dest.path()
+	var msg string
+	for attempt := 1; attempt <= precheckAttempts; attempt++ {
+		cmd := rcloneCommand(globalFlags, "mkdir", destination)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil {
+			return nil
+		}
+		msg = stderr.String()
+		if !isRetryableRcloneError(msg) {
+			return fmt.Errorf("could not access rclone remote '%s'.\nError: %s", destination, msg)
+		}
+		if attempt < precheckAttempts {
+			log.Printf("rclone pre-check for '%s' failed (attempt %d of %d), retrying...", destination, attempt, precheckAttempts)
+			time.Sleep(precheckRetryDelay)
+		}
+	}
+	return fmt.Errorf("%w '%s' after %d attempts.\nError: %s", errRemoteUnreachable, destination, precheckAttempts, msg)
+}
+
+// The rclone pre-check retries network failures a few times before giving up.
+var (
+	precheckAttempts   = 3
+	precheckRetryDelay = 2 * time.Second
+)
+
+// errRemoteUnreachable marks a pre-check that only failed on network errors,
+// so the remote may be fine by the time the export is done.
+var errRemoteUnreachable = errors.New("could not reach rclone remote")
+
+// retryableRcloneErrors are stderr fragments of transient network failures.
+// Anything else (a remote missing from rclone.conf, a revoked token, no
+// permission) won't fix itself and fails the pre-check straight away.
+var retryableRcloneErrors = []string{
+	"timeout", "timed out", "connection reset", "connection refused",
+	"no such host", "temporary failure", "network is unreachable",
+	"tls handshake", "unexpected eof", "error 500", "error 502", "error 503",
+}
+
+// isRetryableRcloneError reports whether rclone's stderr looks like a
+// transient network failure.
+func isRetryableRcloneError(stderr string) bool {
+	lower := strings.ToLower(stderr)
+	for _, fragment := range retryableRcloneErrors {
+		if strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// proceedWithoutPrecheck decides whether to keep going after the remote
+// couldn't be reached. At a terminal the user is asked; unattended runs go
+// ahead, since the upload at the end tries the remote again and, if it is
+// still down, only the upload fails, not the export.
+func proceedWithoutPrecheck(in io.Reader, out io.Writer, interactive bool) bool {
+	if !interactive {
+		return true
+	}
+	fmt.Fprint(out, "The upload remote is unreachable right now. Export anyway and try the upload at the end? [Y/n] ")
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// getVideoDuration reads the input's duration from ffmpeg's banner output
+func getVideoDuration(cfg Config) (float64, error) {
+	log.Println("Getting video duration...")
+	output, _ := runFFmpeg(analysisLogLevel, "-i", cfg.InputFile)
+	re := regexp.MustCompile(`Duration: (\d{2}):(\d{2}):(\d{2})\.(\d{2})`)
+	matches := re.FindStringSubmatch(output)
+	if len(matches) < 5 {
+		return 0, fmt.Errorf("could not parse video duration from ffmpeg output. Output was: %s", output)
+	}
+	hours, _ := strconv.ParseFloat(matches[1], 64)
+	minutes, _ := strconv.ParseFloat(matches[2], 64)
+	seconds, _ := strconv.ParseFloat(matches[3], 64)
+	hundredths, _ := strconv.ParseFloat(matches[4], 64)
+	return (hours * 3600) + (minutes * 60) + seconds + (hundredths / 100.0), nil
+}
+
+// detectSilentSegments runs silencedetect over the recording, or just the
+// analysis window if one is given. Times are from the start of what was
+// analysed.
+func detectSilentSegments(cfg Config, window *segment) []segment {
+	log.Println("Detecting silence... This may take a few minutes.")
+	output, _ := runFFmpeg(analysisLogLevel, append(analysisInput(cfg, window), "-af", silenceFilter(cfg, cfg.MinSilenceDur), "-f", "null", "-")...)
+	starts, ends := parseSilenceTimes(output)
+	var silences []segment
+	for i := 0; i < len(starts) && i < len(ends); i++ {
+		silences = append(silences, segment{starts[i], ends[i]})
+	}
+	return silences
+}
+
+// detectSilence finds the quiet gaps using the configured DetectionMode,
+// reusing an earlier run's results for the same input and settings.
+func detectSilence(cfg Config) []segment {
+	return cachedDetectSilence(cfg, runDetection)
+}
+
+// runDetection runs the DetectionMode's ffmpeg detection pass, over each
+// analysis window in turn if any are set.
+func runDetection(cfg Config) []segment {
+	windows, _ := parseAnalysisWindows(cfg.AnalysisWindows) // checked by loadConfig
+	if len(windows) == 0 {
+		return detectionPass(cfg, nil)
+	}
+	log.Printf("Detecting silence in %d analysis window(s) only.", len(windows))
+	var silences []segment
+	for _, w := range windows {
+		silences = append(silences, windowToAbsolute(detectionPass(cfg, &w), w)...)
+	}
+	return silences
+}
+
+// detectionPass runs one detection over window (nil for the whole file).
+func detectionPass(cfg Config, window *segment) []segment {
+	switch cfg.DetectionMode {
+	case detectionRMS:
+		return detectSilenceRMS(cfg, window)
+	case detectionPeak, "":
+		return detectSilentSegments(cfg, window)
+	default:
+		log.Printf("Warning: Unknown detection_mode '%s', using '%s'", cfg.DetectionMode, detectionPeak)
+		return detectSilentSegments(cfg, window)
+	}
+}
+
+// rmsLevel is the RMS level of one analysis window starting at time.
+type rmsLevel struct {
+	time  float64
+	level float64 // dBFS; -Inf for digital silence
+}
+
+// detectSilenceRMS finds silences from the RMS level of fixed windows rather
+// than silencedetect's per-sample peaks, so a stray click or cough in an
+// otherwise quiet passage doesn't break it up.
+func detectSilenceRMS(cfg Config, window *segment) []segment {
+	threshold, err := parseDecibels(cfg.SilenceThreshold)
+	if err != nil {
+		log.Printf("Warning: %v; rms detection needs a dB threshold like -30dB", err)
+		return nil
+	}
+	log.Println("Detecting silence (RMS)... This may take a few minutes.")
+	output, _ := runFFmpeg(analysisLogLevel, append(analysisInput(cfg, window), "-af", rmsFilter(cfg), "-f", "null", "-")...)
+	return groupQuietWindows(parseRMSLevels(output), threshold, rmsWindow, cfg.MinSilenceDur)
+}
+
+// rmsWindow is the length in seconds of each RMS analysis window, and
+// rmsSampleRate the rate audio is resampled to so a window is a whole
+// number of samples.
+const (
+	rmsWindow     = 0.5
+	rmsSampleRate = 8000
+)
+
+// rmsFilter builds the filter chain that prints one RMS level per window.
+// astats resets every frame, and asetnsamples makes each frame one window.
+func rmsFilter(cfg Config) string {
+	layout := ""
+	if cfg.MonoDetection {
+		layout = ":channel_layouts=mono"
+	}
+	return fmt.Sprintf("aformat=sample_rates=%d%s,asetnsamples=n=%d:p=0,astats=metadata=1:reset=1,ametadata=mode=print:key=lavfi.astats.Overall.RMS_level",
+		rmsSampleRate, layout, int(rmsWindow*rmsSampleRate))
+}
+
+// parseRMSLevels pairs each window's pts_time with the RMS level ametadata
+// prints after it.
+func parseRMSLevels(output string) []rmsLevel {
+	ptsRe := regexp.MustCompile(`pts_time:(-?\d+\.?\d*)`)
+	levelRe := regexp.MustCompile(`RMS_level=(-?inf|-?\d+\.?\d*)`)
+	var levels []rmsLevel
+	current := -1.0
+	for _, line := range strings.Split(output, "\n") {
+		if m := ptsRe.FindStringSubmatch(line); m != nil {
+			current, _ = strconv.ParseFloat(m[1], 64)
+		} else if m := levelRe.FindStringSubmatch(line); m != nil && current >= 0 {
+			level, _ := strconv.ParseFloat(m[1], 64)
+			levels = append(levels, rmsLevel{time: current, level: level})
+			current = -1
+		}
+	}
+	return levels
+}
+
+// groupQuietWindows merges runs of consecutive windows below threshold into
+// silences, keeping those at least minDur long.
+func groupQuietWindows(levels []rmsLevel, threshold, window, minDur float64) []segment {
+	var silences []segment
+	inSilence := false
+	var current segment
+	for _, l := range levels {
+		if l.level < threshold {
+			if !inSilence {
+				current = segment{start: l.time}
+				inSilence = true
+			}
+			current.end = l.time + window
+			continue
+		}
+		if inSilence && current.end-current.start >= minDur {
+			silences = append(silences, current)
+		}
+		inSilence = false
+	}
+	if inSilence && current.end-current.start >= minDur {
+		silences = append(silences, current)
+	}
+	return silences
+}
+
+// parseDecibels reads a threshold like "-30dB" as a number of decibels.
+func parseDecibels(s string) (float64, error) {
+	trimmed := strings.TrimSuffix(strings.TrimSpace(s), "dB")
+	db, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || trimmed == strings.TrimSpace(s) {
+		return 0, fmt.Errorf("could not read silence threshold '%s' as decibels", s)
+	}
+	return db, nil
+}
+
+// silenceFilter builds the silencedetect filter chain. With MonoDetection the
+// audio is downmixed first so one noisy channel can't mask a quiet
+// passage. The downmix must be part of the chain: an output -ac 1 would only
+// apply after silencedetect has already seen every channel. Timestamps are
+// unaffected, and exports are separate commands, so they keep all channels.
+func silenceFilter(cfg Config, minDur float64) string {
+	filter := fmt.Sprintf("silencedetect=noise=%s:d=%.1f", cfg.SilenceThreshold, minDur)
+	if cfg.MonoDetection {
+		filter = "aformat=channel_layouts=mono," + filter
+	}
+	return filter
+}
+
+// parseSilenceTimes pulls the silence_start and silence_end times out of
+// silencedetect's stderr. A silence still running at end of input may have a
+// start with no matching end.
+func parseSilenceTimes(output string) (starts, ends []float64) {
+	startRe := regexp.MustCompile(`silence_start: (-?\d+\.?\d*)`)
+	endRe := regexp.MustCompile(`silence_end: (-?\d+\.?\d*)`)
+	for _, m := range startRe.FindAllStringSubmatch(output, -1) {
+		start, _ := strconv.ParseFloat(m[1], 64)
+		starts = append(starts, start)
+	}
+	for _, m := range endRe.FindAllStringSubmatch(output, -1) {
+		end, _ := strconv.ParseFloat(m[1], 64)
+		ends = append(ends, end)
+	}
+	return starts, ends
+}
+
+// autoTrimSegments tightens each song to its first and last sound by running
+// a second silencedetect pass over just that song.
+func autoTrimSegments(cfg Config, segments []segment) []segment {
+	log.Println("Auto-trimming songs... This runs one extra analysis pass per song.")
+	trimmed := make([]segment, len(segments))
+	for i, seg := range segments {
+		duration := seg.end - seg.start
+		output, _ := runFFmpeg(analysisLogLevel,
+			"-ss", fmt.Sprintf("%.3f", seg.start),
+			"-t", fmt.Sprintf("%.3f", duration),
+			"-i", cfg.InputFile,
+			"-af", silenceFilter(cfg, autoTrimSilenceDur), "-f", "null", "-",
+		)
+		starts, ends := parseSilenceTimes(output)
+		trimmed[i] = tightenSegment(seg, starts, ends)
+		if trimmed[i] != seg {
+			log.Printf("Song %d trimmed to %.2fs-%.2fs (was %.2fs-%.2fs)", i+1, trimmed[i].start, trimmed[i].end, seg.start, seg.end)
+		}
+	}
+	return trimmed
+}
+
+// tightenSegment moves a song's edges past any silence touching its start or
+// end. starts and ends are silencedetect times relative to the song's start.
+// If the result would keep less than autoTrimMinKeep of the song (a mostly
+// quiet take), the song is left untouched.
+func tightenSegment(seg segment, starts, ends []float64) segment {
+	const edge = 0.05 // how close to an edge a silence must be to count as touching it
+	duration := seg.end - seg.start
+	onset, offset := 0.0, duration
+
+	if len(starts) > 0 && starts[0] <= edge {
+		if len(ends) == 0 {
+			return seg // silent throughout
+		}
+		onset = ends[0]
+	}
+	if len(starts) > 0 {
+		last := starts[len(starts)-1]
+		unterminated := len(starts) > len(ends)
+		if unterminated || ends[len(ends)-1] >= duration-edge {
+			offset = last
+		}
+	}
+
+	if offset-onset < duration*autoTrimMinKeep {
+		return seg
+	}
+	return segment{start: seg.start + onset, end: seg.start + offset}
+}
+
+// calculateNonSilentSegments (unchanged)
+func calculateNonSilentSegments(silences []segment, totalDuration float64, cfg Config) []segment {
+	songSegments := make([]segment, 0)
+	lastEndTime := 0.0
+
+	if len(silences) == 0 {
+		return songSegments
+	}
+	if cfg.LosslessBoundaries {
+		return splitAtSilenceMidpoints(silences, totalDuration, cfg.MinSongLength)
+	}
+	start := lastEndTime
+	end := silences[0].start
+	if (end - start) >= cfg.MinSongLength {
+		songSegments = append(songSegments, segment{start: start, end: end})
+	}
+	for i := 0; i < len(silences)-1; i++ {
+		start = silences[i].end
+		end = silences[i+1].start
+		if (end - start) >= cfg.MinSongLength {
+			songSegments = append(songSegments, segment{start: start, end: end})
+		}
+	}
+	lastSilenceEnd := silences[len(silences)-1].end
+	start = lastSilenceEnd
+	end = totalDuration
+	if (end-start) > 0.1 && (end-start) >= cfg.MinSongLength {
+		songSegments = append(songSegments, segment{start: start, end: end})
+	}
+	return songSegments
+}
+
+// splitAtSilenceMidpoints cuts the whole timeline at the middle of each
+// silence, so songs meet end to end and keep half of each neighbouring
+// silence. A piece shorter than minSongLength is merged into the song before
+// it (or, at the start, the one after) rather than dropped, so nothing is
+// lost.
+func splitAtSilenceMidpoints(silences []segment, totalDuration, minSongLength float64) []segment {
+	cuts := []float64{0}
+	for _, s := range silences {
+		mid := (s.start + s.end) / 2
+		if mid > cuts[len(cuts)-1] && mid < totalDuration {
+			cuts = append(cuts, mid)
+		}
+	}
+	cuts = append(cuts, totalDuration)
+
+	var songs []segment
+	for i := 0; i < len(cuts)-1; i++ {
+		piece := segment{start: cuts[i], end: cuts[i+1]}
+		if n := len(songs); n > 0 && (piece.end-piece.start < minSongLength || songs[n-1].end-songs[n-1].start < minSongLength) {
+			songs[n-1].end = piece.end
+			continue
+		}
+		songs = append(songs, piece)
+	}
+	return songs
+}
+
+// findSongSegments decides where the songs are, returning them along with the
+// silences they were derived from (if any).
+func findSongSegments(cfg Config, totalDuration float64) ([]segment, []segment) {
+	// 1. Whole file as one song, or fixed-length chunks, no detection
+	if cfg.NoSplit {
+		log.Println("Splitting disabled, treating the entire video as one song.")
+		return []segment{{start: 0, end: totalDuration}}, nil
+	}
+	if cfg.ChunkLength > 0 {
+		chunks := segmentsByInterval(totalDuration, cfg.ChunkLength)
+		log.Printf("Chunking enabled, cutting the video into %d chunk(s) of %s without detecting silence.", len(chunks), formatTrackTime(cfg.ChunkLength))
+		return chunks, nil
+	}
+
+	// 2. Detect silence
+	silences := detectSilence(cfg)
+	return songsFromSilences(cfg, silences, totalDuration), silences
+}
+
+// songsFromSilences turns detected silences into songs, treating a recording
+// with no silence at all as one song.
+func songsFromSilences(cfg Config, silences []segment, totalDuration float64) []segment {
+	boundaries := boundarySilences(cfg, silences)
+	if rests := len(silences) - len(boundaries); rests > 0 {
+		log.Printf("Ignoring %d silence(s) shorter than %gs as rests within a song.", rests, cfg.BoundarySilenceDur)
+	}
+	silences = withUnanalyzed(cfg, boundaries, totalDuration)
+
+	// 1. Calculate valid song segments
+	songSegments := calculateNonSilentSegments(silences, totalDuration, cfg)
+
+	// 2. Handle "no silence" case
+	if len(silences) == 0 {
+		log.Println("No silence detected.")
+		if totalDuration >= cfg.MinSongLength {
+			log.Println("Treating the entire video as one song.")
+			songSegments = []segment{{start: 0, end: totalDuration}}
+		}
+	}
+	return songSegments
+}
+
+// boundarySilences drops the silences shorter than boundary_silence_duration,
+// so songs run through a rest instead of being cut at it. Every detected
+// silence is a boundary when it isn't set.
+func boundarySilences(cfg Config, silences []segment) []segment {
+	if cfg.BoundarySilenceDur <= 0 {
+		return silences
+	}
+	boundaries := make([]segment, 0, len(silences))
+	for _, s := range silences {
+		if s.end-s.start >= cfg.BoundarySilenceDur {
+			boundaries = append(boundaries, s)
+		}
+	}
+	return boundaries
+}
+
+// invertSegments returns the gaps around and between the song segments,
+// i.e. everything that isn't a song. Slivers under 0.1s are dropped.
+func invertSegments(songs []segment, total float64) []segment {
+	gaps := make([]segment, 0)
+	lastEnd := 0.0
+	for _, song := range songs {
+		if song.start-lastEnd > 0.1 {
+			gaps = append(gaps, segment{start: lastEnd, end: song.start})
+		}
+		lastEnd = song.end
+	}
+	if total-lastEnd > 0.1 {
+		gaps = append(gaps, segment{start: lastEnd, end: total})
+	}
+	return gaps
+}
+
+// segmentsLength is the combined length of segments, in seconds.
+func segmentsLength(segments []segment) float64 {
+	total := 0.0
+	for _, seg := range segments {
+		total += seg.end - seg.start
+	}
+	return total
+}
+
+// checkSegmentCount guards against a badly tuned threshold producing far too
+// many or too few songs. A bound of 0 disables that side of the check.
+func checkSegmentCount(count int, cfg Config) error {
+	if cfg.MinExpectedSegments > 0 && count < cfg.MinExpectedSegments {
+		return fmt.Errorf("found %d song(s), fewer than the expected minimum of %d; try a higher (less negative) silence_threshold or a shorter min_song_length", count, cfg.MinExpectedSegments)
+	}
+	if cfg.MaxExpectedSegments > 0 && count > cfg.MaxExpectedSegments {
+		return fmt.Errorf("found %d song(s), more than the expected maximum of %d; try a lower (more negative) silence_threshold or a longer min_silence_duration", count, cfg.MaxExpectedSegments)
 	}
-	if userSetFlags["upload"] {
-		cfg.UploadToDrive = cliUpload
+	return nil
+}
+
+// exportOptions are the optional extras for splitVideoIntoSegments.
+type exportOptions struct {
+	// extraArgs holds per-segment ffmpeg arguments keyed by 0-based segment
+	// index (e.g. from setlist directives).
+	extraArgs map[int][]string
+	// state, if set, lets unchanged segments from an earlier run be reused.
+	state *exportState
+	// titles, if set, are the setlist titles for metadata_templates.
+	titles []string
+	// only, if set, limits the export to these 1-based song numbers.
+	only []int
+	// onDone, if set, is called after each segment with its result; with
+	// OrderedLogs, in song order.
+	onDone func(result segmentResult, total int)
+}
+
+// splitVideoIntoSegments exports each segment to its own file and returns
+// the outcome for each one, in segment order however many run at once.
+func splitVideoIntoSegments(cfg Config, segments []segment, opts exportOptions) []segmentResult {
+	if _, err := os.Stat(cfg.OutputDir); os.IsNotExist(err) {
+		os.MkdirAll(cfg.OutputDir, 0755)
+		log.Printf("Created output directory: %s", cfg.OutputDir)
 	}
-	if userSetFlags["remote"] {
-		cfg.RcloneRemote = cliRemote
+	fileExt := outputExt(cfg)
+	templates, _ := parseMetadataTemplates(cfg.MetadataTemplates) // checked by loadConfig
+	picked := filterSegmentsByIndex(segments, opts.only)
+	results := make([]segmentResult, len(picked))
+	progress := newProgressReporter(len(picked))
+	progress.ordered = cfg.OrderedLogs
+	progress.trackMedia(segmentsLength(picked))
+
+	type exportJob struct {
+		n   int // position among the picked segments
+		i   int // segment index
+		seg segment
 	}
-	if userSetFlags["subfolder"] {
-		cfg.DriveSubfolder = cliSubfolder
+	jobs := make(chan exportJob)
+	var wg sync.WaitGroup
+	for range max(min(cfg.Jobs, len(picked)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				outputFilename := fmt.Sprintf("%s/%s%s_%02d%s", cfg.OutputDir, sessionPrefix(cfg.SessionName), cfg.OutputPrefix, job.i+1, fileExt)
+				progress.start()
+				metadata := append(titleMetadataArgs(cfg, job.i, opts.titles, templates), metadataArgs(templates, songMetadata(cfg, job.i, len(segments), opts.titles))...)
+				logf := func(format string, args ...any) { progress.jobLogf(job.n, format, args...) }
+				result := exportSegment(cfg, job.i, job.seg, outputFilename, metadata, opts, logf)
+				result = fixDTSWarnings(cfg, job.i, job.seg, result, metadata, opts, logf)
+				result = verifyDuration(cfg, job.i, job.seg, result, metadata, opts, logf)
+				if cfg.PostHook != "" && result.Status == statusExported && !result.Cached {
+					runPostHook(cfg.PostHook, result.File, logf)
+				}
+				results[job.n] = result
+				if opts.onDone != nil {
+					// Through jobDo, so -ordered-logs holds the callback's
+					// output with the job's other lines.
+					progress.jobDo(job.n, func() { opts.onDone(result, len(picked)) })
+				}
+				progress.finishJob(job.n, job.seg.end-job.seg.start, result.Cached)
+				progress.logETA()
+			}
+		}()
 	}
-	if userSetFlags["setlist"] {
-		cfg.SetlistFile = cliSetlistFile
+	n := 0
+	for i, seg := range segments {
+		if opts.only != nil && !slices.Contains(opts.only, i+1) {
+			continue
+		}
+		jobs <- exportJob{n: n, i: i, seg: seg}
+		n++
 	}
-
-	return cfg, nil
+	close(jobs)
+	wg.Wait()
+	progress.close()
+	progress.logSummary()
+	logFailureSummary(results)
+	return results
 }
 
-// loadConfigFromFile helper (unchanged)
-func loadConfigFromFile(path string) (Config, error) {
-	var fileConfig Config
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fileConfig, err
+// exportSegment cuts segment i to outputFilename, tagged with the -metadata
+// args in metadata, or reuses an unchanged output from an earlier run. It
+// logs through logf.
+func exportSegment(cfg Config, i int, seg segment, outputFilename string, metadata []string, opts exportOptions, logf func(format string, args ...any)) segmentResult {
+	extra := opts.extraArgs[i]
+	duration := seg.end - seg.start
+	logf("Exporting segment %d: %s (from %.2fs, duration %.2fs)", i+1, outputFilename, seg.start, duration)
+	if len(extra) > 0 {
+		logf("Segment %d extra ffmpeg args: %s", i+1, strings.Join(extra, " "))
+	}
+	args := buildExportArgs(cfg, seg, outputFilename, append(append([]string(nil), metadata...), extra...))
+	result := segmentResult{Index: i + 1, Start: seg.start, End: seg.end, File: outputFilename}
+	if opts.state != nil {
+		result.stateKey = outputFilename
+		result.paramHash = paramHash(cfg.InputFile, args)
+		if file, ok := opts.state.unchanged(result.stateKey, result.paramHash); ok {
+			logf("Segment %d is unchanged since the last run (%s), skipping export", i+1, file)
+			result.File = file
+			result.Status = statusExported
+			result.Cached = true
+			return result
+		}
+	}
+	cmd := execCommand("ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if copyCfg, ok := copyFallbackConfig(cfg); err != nil && ok && missingEncoder(output) {
+		logf("Warning: Segment %d could not be re-encoded (encoder not available in this ffmpeg), retrying with stream copy", i+1)
+		cmd = execCommand("ffmpeg", buildExportArgs(copyCfg, seg, outputFilename, append(append([]string(nil), metadata...), extra...))...)
+		output, err = cmd.CombinedOutput()
+		result.CopyFallback = err == nil
 	}
-	err = json.Unmarshal(data, &fileConfig)
 	if err != nil {
-		return fileConfig, err
+		logf("Error splitting segment %d: %s\nOutput: %s\n", i+1, err, string(output))
+		result.Status = statusFailed
+		result.Error = err.Error()
+		result.ErrorLog = writeErrorLog(outputFilename, output, logf)
+	} else {
+		result.Status = statusExported
+		if result.DTSWarnings = hasDTSWarnings(string(output)); result.DTSWarnings {
+			logf("Warning: ffmpeg reported non-monotonous timestamps (DTS) in segment %d; the song may glitch", i+1)
+		}
 	}
-	return fileConfig, nil
+	return result
 }
 
-// main is the entry point of our script (MODIFIED)
-func main() {
-	// 1. Define & Parse flags
-	defineFlags()
-	flag.Parse()
+// missingEncoderErrors are what ffmpeg prints when a re-encode asks for an
+// encoder its build doesn't include (e.g. libx264 in an LGPL-only build).
+var missingEncoderErrors = []string{
+	"unknown encoder",
+	"encoder not found",
+	"automatic encoder selection failed",
+	"error selecting an encoder",
+}
 
-	// 2. Load configuration
-	log.Println("Starting practice splitter...")
-	cfg, err := loadConfig()
-	if err != nil {
-		log.Fatalf("Error loading configuration: %v", err)
+// missingEncoder reports whether an export failed because of a missing
+// encoder, rather than something a stream copy would also hit.
+func missingEncoder(output []byte) bool {
+	text := strings.ToLower(string(output))
+	for _, pattern := range missingEncoderErrors {
+		if strings.Contains(text, pattern) {
+			return true
+		}
 	}
+	return false
+}
 
-	log.Printf("Using config: Input='%s', Duration=%.1fs, Threshold=%s, MinSong=%.1fs, Output='%s'",
-		cfg.InputFile, cfg.MinSilenceDur, cfg.SilenceThreshold, cfg.MinSongLength, cfg.OutputDir)
+// copyFallbackConfig returns cfg set up for a stream-copy retry of a failed
+// re-encode. It reports false when there is nothing to fall back from or
+// the retry isn't possible: with -no-copy-fallback, or when the output
+// container can't hold the input's streams as they are. trim_silence is
+// dropped for the retry, since ffmpeg can't filter a copied stream. A
+// setlist song's audio filter is kept: buildExportArgs re-encodes just the
+// audio for it, still copying the video.
+func copyFallbackConfig(cfg Config) (Config, bool) {
+	if cfg.NoCopyFallback || !cfg.Reencode || containerForcesReencode(cfg) {
+		return cfg, false
+	}
+	cfg.Reencode = false
+	cfg.TrimSilence = false
+	return cfg, true
+}
 
-	// 3. --- rclone Pre-Check (NEW) ---
-	if cfg.UploadToDrive {
-		log.Println("Upload enabled, running rclone pre-check...")
-		if !isRcloneInstalled() {
-			log.Fatal("Error: 'upload_to_drive' is true but 'rclone' was not found in your PATH.")
-		}
+// writeErrorLog saves ffmpeg's output for a failed export next to where the
+// output would have been, returning the log's path ("" if it couldn't be
+// written). It logs through logf.
+func writeErrorLog(outputFilename string, output []byte, logf func(format string, args ...any)) string {
+	logPath := outputFilename + errorLogSuffix
+	if err := os.WriteFile(logPath, output, 0644); err != nil {
+		logf("Warning: Could not write error log '%s': %v", logPath, err)
+		return ""
+	}
+	return logPath
+}
 
-		if err := testRcloneConnection(cfg); err != nil {
-			log.Fatalf("rclone pre-check failed: %v\nPlease check 'rclone config' and your remote permissions.", err)
+// logFailureSummary lists the segments that failed to export, if any.
+func logFailureSummary(results []segmentResult) {
+	var failed []segmentResult
+	for _, r := range results {
+		if r.Status == statusFailed {
+			failed = append(failed, r)
 		}
-		log.Println("rclone connection successful.")
 	}
+	if len(failed) == 0 {
+		return
+	}
+	log.Printf("%d of %d segment(s) failed to export:", len(failed), len(results))
+	for _, r := range failed {
+		if r.ErrorLog != "" {
+			log.Printf("  Segment %d: %s (ffmpeg output in %s)", r.Index, r.Error, r.ErrorLog)
+		} else {
+			log.Printf("  Segment %d: %s", r.Index, r.Error)
+		}
+	}
+}
 
-	// 4. Check for ffmpeg
-	if !isFFmpegInstalled() {
-		log.Fatal("Error: 'ffmpeg' command not found. Please install FFmpeg and ensure it's in your system's PATH.")
+// exportedNumbers returns the 1-based song number of each exported segment,
+// in the same order as exportedPaths.
+func exportedNumbers(results []segmentResult) []int {
+	numbers := make([]int, 0, len(results))
+	for _, r := range results {
+		if r.Status == statusExported {
+			numbers = append(numbers, r.Index)
+		}
 	}
+	return numbers
+}
 
-	// 5. Check if input file exists
-	if _, err := os.Stat(cfg.InputFile); os.IsNotExist(err) {
-		log.Fatalf("Error: Input file '%s' not found.", cfg.InputFile)
+// titlesForNumbers picks the title of each song number, "" past the end of
+// titles.
+func titlesForNumbers(numbers []int, titles []string) []string {
+	picked := make([]string, len(numbers))
+	for i, n := range numbers {
+		if n >= 1 && n <= len(titles) {
+			picked[i] = titles[n-1]
+		}
 	}
+	return picked
+}
 
-	// 6. Get video duration
-	totalDuration := getVideoDuration(cfg)
-	log.Printf("Total video duration: %.2f seconds", totalDuration)
+// exportedPaths returns the files of the successfully exported segments
+func exportedPaths(results []segmentResult) []string {
+	paths := make([]string, 0, len(results))
+	for _, r := range results {
+		if r.Status == statusExported {
+			paths = append(paths, r.File)
+		}
+	}
+	return paths
+}
 
-	// 7. Detect silence
-	silences := detectSilentSegments(cfg)
+// buildExportArgs assembles the ffmpeg arguments that cut one segment.
+// Any extra args are placed just before the output file so they act as
+// output options and can override the defaults above them.
+func buildExportArgs(cfg Config, seg segment, outputFilename string, extra []string) []string {
+	duration := seg.end - seg.start
+	seek := []string{"-ss", fmt.Sprintf("%.3f", seg.start)}
+	// The cover goes right after the recording, so -ss never applies to it.
+	input := append(append(append(hwAccelInputArgs(cfg), genPTSArgs(cfg)...), "-i", cfg.InputFile), coverArtInputArgs(cfg)...)
+	var args []string
+	// An output-side -ss would also cut the cover's single frame at 0s, so
+	// with a cover only the recording is seeked. Since the audio is
+	// re-encoded, ffmpeg still decodes up to the exact cut.
+	if seekMode(cfg) == seekFast || coverArtApplies(cfg) {
+		args = ffmpegArgs(cfg.FFmpegLogLevel, append(seek, input...)...)
+	} else {
+		args = ffmpegArgs(cfg.FFmpegLogLevel, append(input, seek...)...)
+	}
+	args = append(args, "-t", fmt.Sprintf("%.3f", duration))
+	cfg.audioFiltered = filtersAudio(extra)
+	filters, extra := splitAudioFilters(extra)
+	args = append(args, streamMapArgs(cfg)...)
+	args = append(args, codecArgs(cfg)...)
+	args = append(args, frameRateArgs(cfg)...)
+	args = append(args, timingArgs(cfg, seg)...)
+	args = append(args, audioFilterArgs(cfg, filters)...)
+	args = append(args, extra...)
+	return append(args, outputFilename)
+}
 
-	// 8. Calculate valid song segments
-	songSegments := calculateNonSilentSegments(silences, totalDuration, cfg)
+// timingArgs shifts an export's timestamps by the song's start with
+// preserve_absolute_timing, so a song cut from 12:00 plays from 12:00 and
+// lines up with notes taken against the whole recording.
+func timingArgs(cfg Config, seg segment) []string {
+	if !cfg.PreserveAbsoluteTiming {
+		return nil
+	}
+	return []string{"-output_ts_offset", fmt.Sprintf("%.3f", seg.start)}
+}
 
-	// 9. Handle "no silence" case
-	if len(silences) == 0 {
-		log.Println("No silence detected.")
-		if totalDuration >= cfg.MinSongLength {
-			log.Println("Treating the entire video as one song.")
-			songSegments = []segment{{start: 0, end: totalDuration}}
-		}
+// seekMode resolves SeekMode, defaulting to fast seeks for stream copy and
+// accurate ones when re-encoding.
+//
+// With -ss before -i, ffmpeg jumps straight to the nearest keyframe, which is
+// near-instant but may start a copied song up to a GOP early. After -i it
+// decodes from the start of the file up to the cut, which is frame-accurate
+// when re-encoding but slow for late songs.
+func seekMode(cfg Config) string {
+	switch cfg.SeekMode {
+	case seekFast, seekAccurate:
+		return cfg.SeekMode
 	}
+	if reencoding(cfg) {
+		return seekAccurate
+	}
+	return seekFast
+}
 
-	// 10. Export valid songs
-	var exportedFiles []string // <-- DECLARED HERE
-	if len(songSegments) == 0 {
-		log.Println("No song segments found that meet the minimum length criteria.")
+// codecArgs returns the codec options for an export: stream copy by default,
+// or the output container's encoders (H.264/AAC for most) with the
+// configured quality when re-encoding. TrimSilence and per-song audio
+// filters re-encode the audio even in copy mode. KeepSubtitles copies subtitles.
+// Audio formats drop the video, apart from any cover_art picture.
+func codecArgs(cfg Config) []string {
+	video, audio := exportCodecs(cfg)
+	reencode := reencoding(cfg)
+	args := []string{"-c:v", "copy"}
+	switch {
+	case coverArtApplies(cfg):
+		args = append(args, coverArtDispositionArgs(cfg)...)
+	case audioOnlyOutput(cfg):
+		args = []string{"-vn"}
+	case reencode && hwAccelActive(cfg):
+		args = append([]string{"-c:v", hwEncoder(cfg, video)}, hwQualityArgs(cfg)...)
+	case reencode:
+		args = append([]string{"-c:v", video}, videoQualityArgs(cfg)...)
+	}
+	if !reencode && !cfg.TrimSilence && !cfg.audioFiltered {
+		args = append(args, "-c:a", "copy")
 	} else {
-		log.Printf("Found %d non-silent (song) segment(s) that meet criteria.", len(songSegments))
-		exportedFiles = splitVideoIntoSegments(cfg, songSegments) // <-- ASSIGNED HERE
+		args = append(args, "-c:a", audio)
+		if cfg.AudioBitrate != "" {
+			args = append(args, "-b:a", cfg.AudioBitrate)
+		}
+	}
+	if cfg.KeepSubtitles {
+		// Subtitles are copied even when re-encoding.
+		args = append(args, "-c:s", "copy")
 	}
+	return args
+}
 
-	// 11. --- Rename from Setlist (Optional) ---
-	if cfg.SetlistFile != "" {
-		if len(exportedFiles) > 0 {
-			renameFilesFromSetlist(cfg, exportedFiles)
-		} else {
-			log.Println("Skipping setlist rename, no files were exported.")
+// audioFilterOptions are the ffmpeg options that filter the audio, which a
+// stream copy can't do.
+var audioFilterOptions = []string{"-af", "-filter:a", "-filter_complex"}
+
+// filtersAudio reports whether a song's extra args filter its audio.
+func filtersAudio(extra []string) bool {
+	for _, arg := range extra {
+		if slices.Contains(audioFilterOptions, arg) {
+			return true
 		}
 	}
+	return false
+}
 
-	// 12. Upload to Drive (Optional)
-	if cfg.UploadToDrive {
-		if _, err := os.Stat(cfg.OutputDir); os.IsNotExist(err) {
-			log.Printf("Skipping upload, output directory '%s' does not exist.", cfg.OutputDir)
-		} else {
-			uploadToDrive(cfg)
+// splitAudioFilters takes the -af and -filter:a options out of a song's
+// extra args, returning their filters and the args left over.
+func splitAudioFilters(extra []string) (filters, rest []string) {
+	for i := 0; i < len(extra); i++ {
+		if (extra[i] == "-af" || extra[i] == "-filter:a") && i+1 < len(extra) {
+			filters = append(filters, extra[i+1])
+			i++
+			continue
 		}
+		rest = append(rest, extra[i])
 	}
+	return filters, rest
+}
 
-	log.Println("\nAll done!")
+// audioFilterArgs returns the -af option for an export, if any: TrimSilence's
+// filters, then the song's own, in one chain, since ffmpeg only uses the
+// last -af it's given. TrimSilence runs silenceremove on the start, then
+// again on the reversed audio to trim the end; silenceremove's own stop
+// options would also cut quiet passages in the middle of a song.
+func audioFilterArgs(cfg Config, filters []string) []string {
+	var chain []string
+	if cfg.TrimSilence {
+		trim := fmt.Sprintf("silenceremove=start_periods=1:start_threshold=%s", cfg.SilenceThreshold)
+		chain = append(chain, trim, "areverse", trim, "areverse")
+	}
+	chain = append(chain, filters...)
+	if len(chain) == 0 {
+		return nil
+	}
+	return []string{"-af", strings.Join(chain, ",")}
 }
 
-// --- Helper Functions ---
+// videoQualityArgs picks the re-encode rate control. An explicit CRF wins
+// over a bitrate; with neither set, defaultVideoCRF is used.
+func videoQualityArgs(cfg Config) []string {
+	switch {
+	case cfg.VideoCRF > 0:
+		return []string{"-crf", strconv.Itoa(cfg.VideoCRF)}
+	case cfg.VideoBitrate != "":
+		return []string{"-b:v", cfg.VideoBitrate}
+	default:
+		return []string{"-crf", strconv.Itoa(defaultVideoCRF)}
+	}
+}
 
-// isFFmpegInstalled (unchanged)
-func isFFmpegInstalled() bool {
-	cmd := exec.Command("ffmpeg", "-version")
-	if err := cmd.Run(); err != nil {
-		return false
+// streamMapArgs returns the -map options for an export. With no mapping
+// ffmpeg picks one video and one audio stream on its own, which drops the
+// extra tracks of a multi-mic recording, and never picks subtitles.
+func streamMapArgs(cfg Config) []string {
+	var args []string
+	switch {
+	case coverArtApplies(cfg):
+		return coverArtMapArgs(cfg)
+	case cfg.MapAllAudio:
+		// "0:v?" keeps this working for audio-only inputs.
+		args = []string{"-map", "0:v?", "-map", "0:a"}
+	case cfg.KeepSubtitles:
+		// Any -map turns off ffmpeg's own pick, so make it explicitly.
+		args = []string{"-map", "0:v:0?", "-map", "0:a:0?"}
 	}
-	return true
+	if cfg.KeepSubtitles {
+		args = append(args, "-map", "0:s?")
+	}
+	return args
 }
 
-// runFFmpeg (unchanged)
-func runFFmpeg(args ...string) (string, error) {
-	cmd := exec.Command("ffmpeg", args...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	return stderr.String(), err
+// uploadToDrive uploads the output folder to every destination. A failure on
+// one destination doesn't stop the others.
+func uploadToDrive(cfg Config) []uploadResult {
+	log.Println("--- Starting Google Drive Upload ---")
+	destinations := uploadDestinations(cfg)
+	results := make([]uploadResult, 0, len(destinations))
+	flags := uploadRcloneFlags(cfg)
+	failed := 0
+	for _, dest := range destinations {
+		result := uploadResult{Destination: dest.path(), Status: statusUploaded}
+		var err error
+		if cfg.Archive != "" {
+			archive, _ := archivePath(cfg.OutputDir, cfg.Archive)
+			err = uploadArchive(archive, cfg.OutputDir, dest, cfg.UploadMode, flags)
+		} else {
+			err = uploadToDestination(cfg.OutputDir, dest, cfg.UploadMode, flags)
+		}
+		if err != nil {
+			failed++
+			result.Status = statusFailed
+			result.Error = err.Error()
+			log.Printf("Error: rclone upload to '%s' failed: %v", result.Destination, err)
+			log.Println("Please ensure rclone is installed and configured ('rclone config').")
+		} else {
+			log.Printf("Upload to '%s' complete.", result.Destination)
+		}
+		results = append(results, result)
+	}
+	if len(destinations) > 1 {
+		log.Printf("Uploaded to %d of %d destination(s).", len(destinations)-failed, len(destinations))
+	}
+	if failed == 0 {
+		log.Println("--- Google Drive Upload Complete ---")
+	}
+	return results
 }
 
-// isRcloneInstalled (unchanged)
-func isRcloneInstalled() bool {
-	cmd := exec.Command("rclone", "version")
-	if err := cmd.Run(); err != nil {
-		return false
+// rcloneUploadArgs picks the rclone command for an UploadMode. copy always
+// transfers changed files; update skips files that are newer on the remote;
+// sync also deletes remote files that aren't in the output folder.
+func rcloneUploadArgs(mode, outputDir, destination string) []string {
+	switch mode {
+	case uploadSync:
+		return []string{"sync", outputDir, destination, "-P"}
+	case uploadUpdate:
+		return []string{"copy", outputDir, destination, "--update", "-P"}
+	default:
+		return []string{"copy", outputDir, destination, "-P"}
 	}
-	return true
 }
 
-// testRcloneConnection (unchanged)
-func testRcloneConnection(cfg Config) error {
-	log.Println("Verifying rclone remote and permissions...")
-	destination := cfg.RcloneRemote + cfg.DriveSubfolder
-	cmd := exec.Command("rclone", "mkdir", destination)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("could not access rclone remote '%s'.\nError: %s", destination, stderr.String())
+// rcloneCommand runs an rclone subcommand with rclone_global_flags (such as
+// --fast-list or backend options) ahead of the subcommand's own args.
+func rcloneCommand(globalFlags []string, args ...string) *exec.Cmd {
+	return execCommand("rclone", append(append([]string(nil), globalFlags...), args...)...)
+}
+
+// reservedRcloneFlags are set by the tool itself, so rclone_global_flags
+// can't repeat or fight them.
+var reservedRcloneFlags = []string{"-P", "--progress"}
+
+// checkRcloneGlobalFlags drops entries that aren't flags (a stray word would
+// become rclone's subcommand) or that the tool already sets, with a warning
+// for each.
+func checkRcloneGlobalFlags(flags []string) ([]string, []string) {
+	var kept, warnings []string
+	for _, f := range flags {
+		name, _, _ := strings.Cut(f, "=")
+		switch {
+		case !strings.HasPrefix(f, "-"):
+			warnings = append(warnings, fmt.Sprintf("Ignoring rclone_global_flags entry '%s': only flags (starting with '-') are allowed; use '--flag=value' for values.", f))
+		case slices.Contains(reservedRcloneFlags, name):
+			warnings = append(warnings, fmt.Sprintf("Ignoring rclone_global_flags entry '%s', which the tool already sets.", f))
+		default:
+			kept = append(kept, f)
+		}
 	}
-	return nil
+	return kept, warnings
 }
 
-// getVideoDuration (unchanged)
-func getVideoDuration(cfg Config) float64 {
-	log.Println("Getting video duration...")
-	output, _ := runFFmpeg("-i", cfg.InputFile)
-	re := regexp.MustCompile(`Duration: (\d{2}):(\d{2}):(\d{2})\.(\d{2})`)
-	matches := re.FindStringSubmatch(output)
-	if len(matches) < 5 {
-		log.Fatalf("Could not parse video duration from ffmpeg output. Output was: %s", output)
+// uploadToggles maps the upload_* config toggles to the rclone flag each
+// one turns on.
+var uploadToggles = []struct {
+	enabled func(Config) bool
+	flag    string
+}{
+	{func(c Config) bool { return c.UploadNoTraverse }, "--no-traverse"},
+	{func(c Config) bool { return c.UploadImmutable }, "--immutable"},
+}
+
+// uploadRcloneFlags returns rclone_global_flags plus the flags the upload_*
+// toggles turn on, skipping any already given there.
+func uploadRcloneFlags(cfg Config) []string {
+	flags := append([]string(nil), cfg.RcloneGlobalFlags...)
+	for _, t := range uploadToggles {
+		if t.enabled(cfg) && !slices.Contains(flags, t.flag) {
+			flags = append(flags, t.flag)
+		}
 	}
-	hours, _ := strconv.ParseFloat(matches[1], 64)
-	minutes, _ := strconv.ParseFloat(matches[2], 64)
-	seconds, _ := strconv.ParseFloat(matches[3], 64)
-	hundredths, _ := strconv.ParseFloat(matches[4], 64)
-	return (hours * 3600) + (minutes * 60) + seconds + (hundredths / 100.0)
+	return flags
 }
 
-// detectSilentSegments (unchanged)
-func detectSilentSegments(cfg Config) []segment {
-	log.Println("Detecting silence... This may take a few minutes.")
-	silenceFilter := fmt.Sprintf("silencedetect=noise=%s:d=%.1f", cfg.SilenceThreshold, cfg.MinSilenceDur)
-	output, _ := runFFmpeg("-i", cfg.InputFile, "-af", silenceFilter, "-f", "null", "-")
-	startRe := regexp.MustCompile(`silence_start: (\d+\.?\d*)`)
-	endRe := regexp.MustCompile(`silence_end: (\d+\.?\d*)`)
-	startMatches := startRe.FindAllStringSubmatch(output, -1)
-	endMatches := endRe.FindAllStringSubmatch(output, -1)
-	var silences []segment
-	for i := 0; i < len(startMatches) && i < len(endMatches); i++ {
-		start, _ := strconv.ParseFloat(startMatches[i][1], 64)
-		end, _ := strconv.ParseFloat(endMatches[i][1], 64)
-		silences = append(silences, segment{start, end})
+// failedUploads counts the destinations an upload failed for.
+func failedUploads(results []uploadResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Status == statusFailed {
+			failed++
+		}
 	}
-	return silences
+	return failed
 }
 
-// calculateNonSilentSegments (unchanged)
-func calculateNonSilentSegments(silences []segment, totalDuration float64, cfg Config) []segment {
-	songSegments := make([]segment, 0)
-	lastEndTime := 0.0
+// uploadToDestination copies the local output folder into one destination
+func uploadToDestination(outputDir string, dest UploadDestination, mode string, globalFlags []string) error {
+	destination := buildRemotePath(dest.Remote, dest.Subfolder, outputDir)
+	log.Printf("Uploading local folder '%s' to '%s' (%s)", outputDir, destination, mode)
+	cmd := rcloneCommand(globalFlags, append(rcloneUploadArgs(mode, outputDir, destination), uploadExcludeArgs...)...)
+	cmd.Stdout = log.Writer()
+	cmd.Stderr = log.Writer()
+	return cmd.Run()
+}
 
-	if len(silences) == 0 {
-		return songSegments
-	}
-	start := lastEndTime
-	end := silences[0].start
-	if (end - start) >= cfg.MinSongLength {
-		songSegments = append(songSegments, segment{start: start, end: end})
+// errorLogSuffix is added to an output's name for the file holding ffmpeg's
+// output when its export failed.
+const errorLogSuffix = ".error.log"
+
+// uploadExcludeArgs keep the tool's own files out of a folder upload: the
+// error logs of failed exports and the -cache state file. With sync they
+// also leave any such files on the remote alone.
+var uploadExcludeArgs = []string{"--exclude", "*" + errorLogSuffix, "--exclude", "/" + stateFileName}
+
+// uploadExcluded reports whether path, a file under the output folder dir,
+// is one uploadExcludeArgs leaves out.
+func uploadExcluded(dir, path string) bool {
+	name := filepath.Base(path)
+	return strings.HasSuffix(name, errorLogSuffix) || name == stateFileName && filepath.Dir(path) == filepath.Clean(dir)
+}
+
+// buildRemotePath joins an rclone remote ("gdrive:" or "gdrive:Music"), a
+// subfolder and a local folder name into one remote path. rclone paths use
+// forward slashes on every OS, so backslashes become slashes, and empty,
+// "." and ".." parts are dropped so stray slashes or a "./output/" can't
+// produce "//" or climb out of the subfolder.
+func buildRemotePath(remote, subfolder, dir string) string {
+	name, base := "", strings.TrimSpace(remote)
+	if i := strings.Index(base, ":"); i >= 0 {
+		name, base = base[:i+1], base[i+1:]
+	} else if strings.HasPrefix(base, "/") {
+		name = "/" // a local or mounted path rather than a named remote
 	}
-	for i := 0; i < len(silences)-1; i++ {
-		start = silences[i].end
-		end = silences[i+1].start
-		if (end - start) >= cfg.MinSongLength {
-			songSegments = append(songSegments, segment{start: start, end: end})
+	var parts []string
+	for _, p := range []string{base, subfolder, dir} {
+		for _, part := range strings.Split(strings.ReplaceAll(p, "\\", "/"), "/") {
+			part = strings.TrimSpace(part)
+			if part != "" && part != "." && part != ".." {
+				parts = append(parts, part)
+			}
 		}
 	}
-	lastSilenceEnd := silences[len(silences)-1].end
-	start = lastSilenceEnd
-	end = totalDuration
-	if (end-start) > 0.1 && (end-start) >= cfg.MinSongLength {
-		songSegments = append(songSegments, segment{start: start, end: end})
-	}
-	return songSegments
+	return name + strings.Join(parts, "/")
 }
 
-// splitVideoIntoSegments (MODIFIED)
-// Now returns a list of the files it created
-func splitVideoIntoSegments(cfg Config, segments []segment) []string {
-	if _, err := os.Stat(cfg.OutputDir); os.IsNotExist(err) {
-		os.Mkdir(cfg.OutputDir, 0755)
-		log.Printf("Created output directory: %s", cfg.OutputDir)
+// logOutputSummary logs the total size of the exported files next to the
+// size of the input.
+func logOutputSummary(inputFile string, files []string) {
+	inputInfo, err := os.Stat(inputFile)
+	if err != nil {
+		log.Printf("Warning: Could not stat input file for the size summary: %v", err)
+		return
 	}
-	fileExt := filepath.Ext(cfg.InputFile)
-	exportedFiles := make([]string, 0)
-
-	for i, seg := range segments {
-		outputFilename := fmt.Sprintf("%s/%s_%02d%s", cfg.OutputDir, cfg.OutputPrefix, i+1, fileExt)
-		duration := seg.end - seg.start
-		log.Printf("Exporting segment %d: %s (from %.2fs, duration %.2fs)", i+1, outputFilename, seg.start, duration)
-		args := []string{
-			"-i", cfg.InputFile,
-			"-ss", fmt.Sprintf("%.3f", seg.start),
-			"-t", fmt.Sprintf("%.3f", duration),
-			"-c:v", "copy",
-			"-c:a", "copy",
-			outputFilename,
-		}
-		cmd := exec.Command("ffmpeg", args...)
-		output, err := cmd.CombinedOutput()
+	var outputSize int64
+	for _, f := range files {
+		info, err := os.Stat(f)
 		if err != nil {
-			log.Printf("Error splitting segment %d: %s\nOutput: %s\n", i+1, err, string(output))
-		} else {
-			exportedFiles = append(exportedFiles, outputFilename)
+			log.Printf("Warning: Could not stat '%s' for the size summary: %v", f, err)
+			continue
 		}
+		outputSize += info.Size()
 	}
-	return exportedFiles
+	log.Printf("Input %s -> Output %s across %d files (%.1f%%)",
+		humanizeBytes(inputInfo.Size()), humanizeBytes(outputSize), len(files), compressionRatio(inputInfo.Size(), outputSize))
 }
 
-// uploadToDrive (unchanged)
-func uploadToDrive(cfg Config) {
-	log.Println("--- Starting Google Drive Upload ---")
-	destination := cfg.RcloneRemote + cfg.DriveSubfolder + "/" + cfg.OutputDir
-	log.Printf("Uploading local folder '%s' to '%s'", cfg.OutputDir, destination)
-	cmd := exec.Command("rclone", "copy", cfg.OutputDir, destination, "-P")
-	cmd.Stdout = log.Writer()
-	cmd.Stderr = log.Writer()
-	if err := cmd.Run(); err != nil {
-		log.Printf("Error: rclone upload failed: %v", err)
-		log.Println("Please ensure rclone is installed and configured ('rclone config').")
-	} else {
-		log.Println("--- Google Drive Upload Complete ---")
+// compressionRatio returns output size as a percentage of input size.
+func compressionRatio(inputSize, outputSize int64) float64 {
+	if inputSize <= 0 {
+		return 0
+	}
+	return float64(outputSize) / float64(inputSize) * 100
+}
+
+// humanizeBytes formats a byte count like "2.1 GB" or "180 MB".
+func humanizeBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
 	}
+	value := float64(n)
+	suffixes := []string{"KB", "MB", "GB", "TB"}
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f %s", value, suffixes[i])
+	}
+	return fmt.Sprintf("%.0f %s", value, suffixes[i])
+}
+
+// resolveOutputDir fills in the placeholders output_dir can hold; "{count}"
+// becomes the number of songs found, so "output_{count}songs" can become
+// "output_12songs".
+func resolveOutputDir(dir string, count int) string {
+	return strings.ReplaceAll(dir, "{count}", strconv.Itoa(count))
 }
 
-// --- ADD THIS NEW FUNCTION ---
-// sanitizeFilename cleans a song title to be a valid file name
+// sessionPrefix is session_name cleaned like a song title, with the "_"
+// that joins it to the rest of a file name; "" without a session name.
+func sessionPrefix(session string) string {
+	if strings.TrimSpace(session) == "" {
+		return ""
+	}
+	return sanitizeFilename(session) + "_"
+}
+
+// sanitizeFilename cleans a song title to be a valid file name. The result
+// never contains a path separator or "..", so it can't point outside the
+// folder it's joined to.
 func sanitizeFilename(name string) string {
-	// 1. Trim whitespace
+	// 1. Turn path separators into spaces ("AC/DC" -> "AC_DC") and drop ".."
+	name = strings.NewReplacer("/", " ", "\\", " ").Replace(name)
+	name = strings.ReplaceAll(name, "..", "")
+	// 2. Trim whitespace
 	name = strings.TrimSpace(name)
-	// 2. Define invalid characters (anything not a letter, number, space, hyphen, underscore)
+	// 3. Define invalid characters (anything not a letter, number, space, hyphen, underscore)
 	invalidChars := regexp.MustCompile(`[^\w\s\-]`)
 	name = invalidChars.ReplaceAllString(name, "")
-	// 3. Replace spaces with underscores
+	// 4. Replace spaces with underscores
 	name = strings.ReplaceAll(name, " ", "_")
-	// 4. Handle potential empty names
+	// 5. Handle potential empty names
 	if name == "" {
 		name = "Untitled_Song"
 	}
 	return name
 }
 
-// --- ADD THIS NEW FUNCTION ---
-// renameFilesFromSetlist reads a setlist file and renames exported files
-func renameFilesFromSetlist(cfg Config, exportedFiles []string) {
-	log.Println("--- Renaming files from setlist ---")
+// uniquePath returns path, or if something already exists there, the first
+// free "name (2).ext", "name (3).ext", ... beside it.
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	return uniqueName(strings.TrimSuffix(path, ext), ext, func(candidate string) bool {
+		_, err := os.Lstat(candidate)
+		return !os.IsNotExist(err)
+	})
+}
+
+// uniqueName returns base+ext, or the first "base (2)ext", "base (3)ext", ...
+// that taken reports as free.
+func uniqueName(base, ext string, taken func(string) bool) string {
+	candidate := base + ext
+	for n := 2; taken(candidate); n++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	return candidate
+}
+
+// envVarRe matches a $VAR or ${VAR} reference in a path.
+var envVarRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// expandPath expands a leading "~" (or "~/...") to the home directory and
+// any $VAR or ${VAR} that is set to its value, as a shell would before
+// passing a quoted path that it left alone. Any other "$" is kept, so a file
+// really named "take$2.mp4" still works. "~user" forms are left as they are.
+func expandPath(p string) (string, error) {
+	tilde := p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "~"+string(filepath.Separator))
+	if tilde {
+		p = p[1:]
+	}
+	p = envVarRe.ReplaceAllStringFunc(p, func(ref string) string {
+		m := envVarRe.FindStringSubmatch(ref)
+		if value, ok := os.LookupEnv(m[1] + m[2]); ok {
+			return value
+		}
+		return ref
+	})
+	if !tilde {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot expand '~%s': %v", p, err)
+	}
+	return filepath.Join(home, p), nil
+}
 
-	// 1. Open the setlist file
-	file, err := os.Open(cfg.SetlistFile)
+// withinDir reports whether path is dir itself or somewhere inside it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
 	if err != nil {
-		log.Printf("Error: Could not open setlist file '%s': %v", cfg.SetlistFile, err)
-		log.Println("Skipping rename.")
-		return
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// setlist holds the parsed contents of a setlist file.
+type setlist struct {
+	titles    []string
+	extraArgs map[int][]string // per-song ffmpeg args, keyed by 0-based song index
+}
+
+// loadSetlist reads a setlist file, or stdin if path is "-": one song title
+// per line, optionally followed by "|"-separated directives, e.g.
+// "Reba | extra-args: -af volume=2".
+func loadSetlist(path string) (setlist, error) {
+	if path == stdinPath {
+		return parseSetlist(os.Stdin)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return setlist{extraArgs: make(map[int][]string)}, err
 	}
 	defer file.Close()
+	return parseSetlist(file)
+}
 
-	// 2. Read song titles into a slice
-	var songTitles []string
-	scanner := bufio.NewScanner(file)
+// parseSetlist reads setlist lines from r.
+func parseSetlist(r io.Reader) (setlist, error) {
+	list := setlist{extraArgs: make(map[int][]string)}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
 	for scanner.Scan() {
-		title := scanner.Text()
-		if title != "" { // Skip empty lines
-			songTitles = append(songTitles, title)
+		lineNum++
+		if strings.TrimSpace(scanner.Text()) == "" { // Skip empty lines
+			continue
 		}
+		title, extra := parseSetlistLine(scanner.Text(), lineNum)
+		if len(extra) > 0 {
+			list.extraArgs[len(list.titles)] = extra
+		}
+		list.titles = append(list.titles, title)
 	}
 	if err := scanner.Err(); err != nil {
-		log.Printf("Error reading setlist file: %v", err)
-		log.Println("Skipping rename.")
-		return
+		return list, err
+	}
+	return list, nil
+}
+
+// parseSetlistLine splits a setlist line into its title and any extra ffmpeg
+// args from an "extra-args:" directive. Unknown directives are warned about
+// and ignored.
+func parseSetlistLine(line string, lineNum int) (string, []string) {
+	parts := strings.Split(line, "|")
+	title := strings.TrimSpace(parts[0])
+	var extra []string
+	for _, directive := range parts[1:] {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+		key, value, found := strings.Cut(directive, ":")
+		key = strings.TrimSpace(key)
+		switch {
+		case found && key == "extra-args":
+			extra = append(extra, strings.Fields(value)...)
+		default:
+			log.Printf("Warning: setlist line %d: ignoring unrecognized directive '%s'", lineNum, directive)
+		}
 	}
+	return title, extra
+}
 
-	// 3. Compare file counts
+// renameFilesFromSetlist renames exported files using the setlist titles, in
+// order, and returns the files' paths after renaming. A session name, if
+// given, starts each new name.
+func renameFilesFromSetlist(exportedFiles []string, songTitles []string, session string) []string {
+	// 1. Compare file counts
 	if len(songTitles) < len(exportedFiles) {
 		log.Printf("Warning: Setlist has %d songs, but %d files were exported.", len(songTitles), len(exportedFiles))
-		log.Println("Only the first %d files will be renamed.", len(songTitles))
+		log.Printf("Only the first %d files will be renamed.", len(songTitles))
 	} else if len(songTitles) > len(exportedFiles) {
 		log.Printf("Warning: Setlist has %d songs, but only %d files were exported.", len(songTitles), len(exportedFiles))
 	}
 
-	// 4. Rename files
-	for i, oldFilePath := range exportedFiles {
-		if i >= len(songTitles) {
-			break // Stop if we run out of song titles
+	// 2. Rename files
+	numbers := make([]int, len(exportedFiles))
+	for i := range numbers {
+		numbers[i] = i + 1
+	}
+	return renameSongFiles(exportedFiles, numbers, songTitles, session)
+}
+
+// renameSongFiles renames each file after its song: numbers holds each
+// file's 1-based song number, giving "NN - Title" from that song's title,
+// or "Session_NN - Title" with a session name. It returns the files' paths
+// after renaming.
+func renameSongFiles(files []string, numbers []int, songTitles []string, session string) []string {
+	log.Println("--- Renaming files from setlist ---")
+	finalFiles := append([]string(nil), files...)
+	for i, oldFilePath := range files {
+		n := numbers[i]
+		if n > len(songTitles) {
+			continue // No title for this song
+		}
+		title := songTitles[n-1]
+		if strings.TrimSpace(title) == "" {
+			continue // Keep the exported name for untitled songs
 		}
 
 		// Get components of the old path
@@ -483,18 +3334,29 @@ func renameFilesFromSetlist(cfg Config, exportedFiles []string) {
 		ext := filepath.Ext(oldFilePath)
 
 		// Create new name
-		newSongName := sanitizeFilename(songTitles[i])
+		newSongName := sanitizeFilename(title)
 		// Format: 01 - Song_Name.mp4
-		newFileName := fmt.Sprintf("%02d - %s%s", i+1, newSongName, ext)
+		newFileName := fmt.Sprintf("%s%02d - %s%s", sessionPrefix(session), n, newSongName, ext)
 		newFilePath := filepath.Join(dir, newFileName)
+		if !withinDir(dir, newFilePath) {
+			log.Printf("Error: refusing to rename '%s' to '%s', which is outside '%s'", oldFilePath, newFilePath, dir)
+			continue
+		}
+
+		if newFilePath == filepath.Clean(oldFilePath) {
+			continue // Already named
+		}
+		newFilePath = uniquePath(newFilePath)
 
 		// Rename
 		err := os.Rename(oldFilePath, newFilePath)
 		if err != nil {
 			log.Printf("Error renaming '%s' to '%s': %v", oldFilePath, newFilePath, err)
 		} else {
-			log.Printf("Renamed '%s' -> '%s'", filepath.Base(oldFilePath), newFileName)
+			log.Printf("Renamed '%s' -> '%s'", filepath.Base(oldFilePath), filepath.Base(newFilePath))
+			finalFiles[i] = newFilePath
 		}
 	}
 	log.Println("--- Setlist renaming complete ---")
+	return finalFiles
 }
@@ -1,11 +1,25 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // resetFlags (unchanged)
@@ -46,7 +60,7 @@ func TestConfigLoading(t *testing.T) {
 		if err := flag.CommandLine.Parse(args); err != nil {
 			t.Fatalf("failed to parse flags: %v", err)
 		}
-		cfg, err := loadConfig()
+		cfg, _, err := loadConfig()
 		if err != nil {
 			t.Fatalf("loadConfig failed: %v", err)
 		}
@@ -80,7 +94,7 @@ func TestConfigLoading(t *testing.T) {
 		if err := flag.CommandLine.Parse(args); err != nil {
 			t.Fatalf("failed to parse flags: %v", err)
 		}
-		cfg, err := loadConfig()
+		cfg, _, err := loadConfig()
 		if err != nil {
 			t.Fatalf("loadConfig failed: %v", err)
 		}
@@ -124,7 +138,7 @@ func TestConfigLoading(t *testing.T) {
 			t.Fatalf("failed to parse flags: %v", err)
 		}
 		// 4. Load config
-		cfg, err := loadConfig()
+		cfg, _, err := loadConfig()
 		if err != nil {
 			t.Fatalf("loadConfig failed: %v", err)
 		}
@@ -225,3 +239,1459 @@ func TestCalculateNonSilentSegments(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigLoadingReturnsWarnings(t *testing.T) {
+	resetFlags()
+	defineFlags()
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"input_file": "video.mp4",`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := flag.CommandLine.Parse([]string{"-config=" + configFile}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Could not parse config file") {
+		t.Errorf("Expected a parse warning, got %q", warnings)
+	}
+	if cfg.InputFile != defaultConfig.InputFile {
+		t.Errorf("Expected defaults after a parse failure, got InputFile %s", cfg.InputFile)
+	}
+}
+
+func TestConfigLoadingLayersFiles(t *testing.T) {
+	resetFlags()
+	defineFlags()
+	team, cleanupTeam := createTempConfig(t, Config{SilenceThreshold: "-35dB", MinSongLength: 90, RcloneRemote: "team:", DriveSubfolder: "Rehearsals"})
+	defer cleanupTeam()
+	mine, cleanupMine := createTempConfig(t, Config{MinSongLength: 150, DriveSubfolder: "Rehearsals/Mine"})
+	defer cleanupMine()
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if err := flag.CommandLine.Parse([]string{"-config=" + team + "," + missing + ", " + mine}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if cfg.SilenceThreshold != "-35dB" || cfg.RcloneRemote != "team:" {
+		t.Errorf("Expected the team file's settings to stay, got threshold %s, remote %s", cfg.SilenceThreshold, cfg.RcloneRemote)
+	}
+	if cfg.MinSongLength != 150 || cfg.DriveSubfolder != "Rehearsals/Mine" {
+		t.Errorf("Expected the later file to win, got min song length %g, subfolder %s", cfg.MinSongLength, cfg.DriveSubfolder)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], missing) {
+		t.Errorf("Expected a warning for the missing file, got %q", warnings)
+	}
+}
+
+// fakeCall records a single command built through execCommand.
+type fakeCall struct {
+	name string
+	args []string
+}
+
+// fakeResult is the canned output a faked command produces.
+type fakeResult struct {
+	stdout   string
+	stderr   string
+	exitCode int
+	delay    time.Duration // how long the command takes to finish
+}
+
+// fakeExec stands in for execCommand. Each command it builds re-runs the
+// test binary as TestHelperProcess, which replays the canned result.
+type fakeExec struct {
+	mu      sync.Mutex // parallel exports run commands concurrently
+	calls   []fakeCall
+	respond func(call fakeCall) fakeResult
+}
+
+// installFakeExec swaps execCommand for a recorder until the test ends.
+// respond may be nil, in which case every command succeeds silently.
+func installFakeExec(t *testing.T, respond func(call fakeCall) fakeResult) *fakeExec {
+	t.Helper()
+	f := &fakeExec{respond: respond}
+	orig := execCommand
+	execCommand = f.command
+	t.Cleanup(func() { execCommand = orig })
+	return f
+}
+
+func (f *fakeExec) command(name string, args ...string) *exec.Cmd {
+	call := fakeCall{name: name, args: append([]string(nil), args...)}
+	// Calls are handled one at a time, so respond needn't be safe for
+	// concurrent use.
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	var res fakeResult
+	if f.respond != nil {
+		res = f.respond(call)
+	}
+	f.mu.Unlock()
+	cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess", "--")
+	cmd.Env = append(os.Environ(),
+		"GO_WANT_HELPER_PROCESS=1",
+		"HELPER_STDOUT="+res.stdout,
+		"HELPER_STDERR="+res.stderr,
+		"HELPER_EXIT="+strconv.Itoa(res.exitCode),
+		"HELPER_DELAY="+res.delay.String(),
+	)
+	return cmd
+}
+
+// TestMain points the system temp dir at a scratch folder, so the detection
+// cache written by runs under test doesn't outlive them.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "splitter-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("TMPDIR", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// TestHelperProcess isn't a real test; it's the body of every faked command.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	if d, err := time.ParseDuration(os.Getenv("HELPER_DELAY")); err == nil {
+		time.Sleep(d)
+	}
+	fmt.Fprint(os.Stdout, os.Getenv("HELPER_STDOUT"))
+	fmt.Fprint(os.Stderr, os.Getenv("HELPER_STDERR"))
+	code, _ := strconv.Atoi(os.Getenv("HELPER_EXIT"))
+	os.Exit(code)
+}
+
+func TestDetectSilentSegments(t *testing.T) {
+	stderr := `[silencedetect @ 0x1] silence_start: 180.5
+[silencedetect @ 0x1] silence_end: 190.25 | silence_duration: 9.75
+[silencedetect @ 0x1] silence_start: 400
+[silencedetect @ 0x1] silence_end: 410.5 | silence_duration: 10.5
+`
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: stderr}
+	})
+	cfg := Config{InputFile: "in.mp4", SilenceThreshold: "-20dB", MinSilenceDur: 5.0}
+
+	silences := detectSilentSegments(cfg, nil)
+
+	expected := []segment{{start: 180.5, end: 190.25}, {start: 400, end: 410.5}}
+	if !reflect.DeepEqual(silences, expected) {
+		t.Errorf("Expected silences %+v, got %+v", expected, silences)
+	}
+	if len(fake.calls) != 1 {
+		t.Fatalf("Expected 1 ffmpeg call, got %d", len(fake.calls))
+	}
+	expectedArgs := []string{"-hide_banner", "-loglevel", "info", "-i", "in.mp4", "-af", "silencedetect=noise=-20dB:d=5.0", "-f", "null", "-"}
+	if fake.calls[0].name != "ffmpeg" || !reflect.DeepEqual(fake.calls[0].args, expectedArgs) {
+		t.Errorf("Expected ffmpeg %v, got %s %v", expectedArgs, fake.calls[0].name, fake.calls[0].args)
+	}
+}
+
+func TestSplitVideoIntoSegmentsCopyMode(t *testing.T) {
+	fake := installFakeExec(t, nil)
+	outDir := filepath.Join(t.TempDir(), "out")
+	cfg := Config{InputFile: "practice.mkv", OutputDir: outDir, OutputPrefix: "Song"}
+	segments := []segment{{start: 0, end: 120.5}, {start: 130, end: 300}}
+
+	exported := exportedPaths(splitVideoIntoSegments(cfg, segments, exportOptions{}))
+
+	if len(exported) != 2 {
+		t.Fatalf("Expected 2 exported files, got %d", len(exported))
+	}
+	if _, err := os.Stat(outDir); err != nil {
+		t.Errorf("Expected output directory to be created: %v", err)
+	}
+	expectedArgs := [][]string{
+		{"-hide_banner", "-loglevel", "warning", "-ss", "0.000", "-i", "practice.mkv", "-t", "120.500", "-c:v", "copy", "-c:a", "copy", outDir + "/Song_01.mkv"},
+		{"-hide_banner", "-loglevel", "warning", "-ss", "130.000", "-i", "practice.mkv", "-t", "170.000", "-c:v", "copy", "-c:a", "copy", outDir + "/Song_02.mkv"},
+	}
+	for i, call := range fake.calls {
+		if call.name != "ffmpeg" || !reflect.DeepEqual(call.args, expectedArgs[i]) {
+			t.Errorf("Call %d: expected ffmpeg %v, got %s %v", i, expectedArgs[i], call.name, call.args)
+		}
+	}
+}
+
+func TestSplitVideoIntoSegmentsSkipsFailures(t *testing.T) {
+	outDir := t.TempDir()
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		if call.args[len(call.args)-1] == outDir+"/Song_01.mp4" {
+			return fakeResult{stderr: "boom", exitCode: 1}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{InputFile: "in.mp4", OutputDir: outDir, OutputPrefix: "Song"}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 0, end: 10}, {start: 20, end: 30}}, exportOptions{})
+
+	if exported := exportedPaths(results); !reflect.DeepEqual(exported, []string{outDir + "/Song_02.mp4"}) {
+		t.Errorf("Expected only the second segment to be exported, got %v", exported)
+	}
+	if results[0].Status != statusFailed || results[0].Error == "" {
+		t.Errorf("Expected the first segment to be recorded as failed with an error, got %+v", results[0])
+	}
+}
+
+func TestSplitVideoIntoSegmentsWritesErrorLog(t *testing.T) {
+	outDir := t.TempDir()
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: "Unknown encoder 'libfoo'", exitCode: 1}
+	})
+	cfg := Config{InputFile: "in.mp4", OutputDir: outDir, OutputPrefix: "Song"}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 0, end: 10}}, exportOptions{})
+
+	expectedLog := outDir + "/Song_01.mp4.error.log"
+	if results[0].ErrorLog != expectedLog {
+		t.Fatalf("Expected the result to reference %s, got %+v", expectedLog, results[0])
+	}
+	data, err := os.ReadFile(expectedLog)
+	if err != nil {
+		t.Fatalf("Expected an error log to be written: %v", err)
+	}
+	if !strings.Contains(string(data), "Unknown encoder 'libfoo'") {
+		t.Errorf("Expected the error log to hold ffmpeg's output, got %q", data)
+	}
+}
+
+func TestRcloneDestinations(t *testing.T) {
+	fake := installFakeExec(t, nil)
+	cfg := Config{RcloneRemote: "gdrive:", DriveSubfolder: "Band/Shows", OutputDir: "output"}
+
+	if err := testRcloneConnection(uploadDestinations(cfg)[0], nil); err != nil {
+		t.Fatalf("testRcloneConnection failed: %v", err)
+	}
+	uploadToDrive(cfg)
+
+	expected := []fakeCall{
+		{name: "rclone", args: []string{"mkdir", "gdrive:Band/Shows"}},
+		{name: "rclone", args: []string{"copy", "output", "gdrive:Band/Shows/output", "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"}},
+	}
+	if !reflect.DeepEqual(fake.calls, expected) {
+		t.Errorf("Expected rclone calls %+v, got %+v", expected, fake.calls)
+	}
+}
+
+func TestBuildRemotePath(t *testing.T) {
+	testCases := []struct {
+		remote, subfolder, dir string
+		want                   string
+	}{
+		{"gdrive:", "Band/Shows", "output", "gdrive:Band/Shows/output"},
+		{"gdrive:", "Band Practice/", "my output/", "gdrive:Band Practice/my output"},
+		{"gdrive:", "/Band//Shows/", "./output", "gdrive:Band/Shows/output"},
+		{"gdrive:", "", "output", "gdrive:output"},
+		{"gdrive:Music", "Band", "output", "gdrive:Music/Band/output"},
+		{"gdrive:", "Band", `renders\2024`, "gdrive:Band/renders/2024"},
+		{"gdrive:", "Band", "../output", "gdrive:Band/output"},
+		{"/mnt/nas/", "Band", "output", "/mnt/nas/Band/output"},
+	}
+	for _, tc := range testCases {
+		if got := buildRemotePath(tc.remote, tc.subfolder, tc.dir); got != tc.want {
+			t.Errorf("buildRemotePath(%q, %q, %q) = %q, want %q", tc.remote, tc.subfolder, tc.dir, got, tc.want)
+		}
+	}
+}
+
+func TestRcloneConnectionFailure(t *testing.T) {
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: "didn't find section in config file", exitCode: 1}
+	})
+	err := testRcloneConnection(UploadDestination{Remote: "nope:", Subfolder: "x"}, nil)
+	if err == nil {
+		t.Fatal("Expected an error for a failing rclone mkdir")
+	}
+}
+
+func TestRcloneConnectionRetries(t *testing.T) {
+	oldDelay := precheckRetryDelay
+	precheckRetryDelay = 0
+	defer func() { precheckRetryDelay = oldDelay }()
+	testCases := []struct {
+		name        string
+		stderrs     []string // one per attempt; past the end, mkdir succeeds
+		wantCalls   int
+		wantErr     bool
+		unreachable bool
+	}{
+		{"RecoversAfterATimeout", []string{"Failed to mkdir: dial tcp: i/o timeout"}, 2, false, false},
+		{"MissingRemoteIsFatal", []string{"didn't find section in config file", "", ""}, 1, true, false},
+		{"KeepsTimingOut", []string{"context deadline exceeded (Client.Timeout exceeded)", "connection reset by peer", "googleapi: Error 503: Backend Error"}, 3, true, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var fake *fakeExec
+			fake = installFakeExec(t, func(call fakeCall) fakeResult {
+				if n := len(fake.calls); n <= len(tc.stderrs) {
+					return fakeResult{stderr: tc.stderrs[n-1], exitCode: 1}
+				}
+				return fakeResult{}
+			})
+			err := testRcloneConnection(UploadDestination{Remote: "gdrive:", Subfolder: "Band"}, nil)
+			if (err != nil) != tc.wantErr || errors.Is(err, errRemoteUnreachable) != tc.unreachable {
+				t.Errorf("Expected error=%v unreachable=%v, got %v", tc.wantErr, tc.unreachable, err)
+			}
+			if len(fake.calls) != tc.wantCalls {
+				t.Errorf("Expected %d mkdir attempt(s), got %d", tc.wantCalls, len(fake.calls))
+			}
+		})
+	}
+}
+
+func TestProceedWithoutPrecheck(t *testing.T) {
+	if !proceedWithoutPrecheck(strings.NewReader(""), io.Discard, false) {
+		t.Error("Expected unattended runs to proceed")
+	}
+	for input, want := range map[string]bool{"\n": true, "y\n": true, "YES\n": true, "n\n": false, "no\n": false} {
+		if got := proceedWithoutPrecheck(strings.NewReader(input), io.Discard, true); got != want {
+			t.Errorf("Answer %q: expected %v, got %v", input, want, got)
+		}
+	}
+}
+
+func TestLoadSetlistFromStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
+	go func() {
+		w.WriteString("Opener\n\nSlow One | extra-args: -af volume=2\nCloser\n")
+		w.Close()
+	}()
+
+	list, err := loadSetlist("-")
+	if err != nil {
+		t.Fatalf("loadSetlist failed: %v", err)
+	}
+	if want := []string{"Opener", "Slow One", "Closer"}; !reflect.DeepEqual(list.titles, want) {
+		t.Errorf("Expected titles %q, got %q", want, list.titles)
+	}
+	if want := []string{"-af", "volume=2"}; !reflect.DeepEqual(list.extraArgs[1], want) {
+		t.Errorf("Expected extra args %v for song 2, got %v", want, list.extraArgs[1])
+	}
+}
+
+func TestStdinConflict(t *testing.T) {
+	resetFlags()
+	defineFlags()
+	if err := flag.CommandLine.Parse([]string{"-config=non-existent-file.json", "-input=-", "-setlist=-"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if _, _, err := loadConfig(); err == nil {
+		t.Error("Expected an error when both the input and setlist read stdin")
+	}
+}
+
+func TestLoadSetlistDirectives(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "setlist.txt")
+	content := "Reba\n\nQuiet One | extra-args: -af volume=2\nSabotage | colour: red\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := loadSetlist(path)
+	if err != nil {
+		t.Fatalf("loadSetlist failed: %v", err)
+	}
+
+	expectedTitles := []string{"Reba", "Quiet One", "Sabotage"}
+	if !reflect.DeepEqual(list.titles, expectedTitles) {
+		t.Errorf("Expected titles %v, got %v", expectedTitles, list.titles)
+	}
+	expectedExtra := map[int][]string{1: {"-af", "volume=2"}}
+	if !reflect.DeepEqual(list.extraArgs, expectedExtra) {
+		t.Errorf("Expected extra args %v, got %v", expectedExtra, list.extraArgs)
+	}
+}
+
+func TestSplitVideoIntoSegmentsExtraArgs(t *testing.T) {
+	fake := installFakeExec(t, nil)
+	outDir := t.TempDir()
+	cfg := Config{InputFile: "in.mp4", OutputDir: outDir, OutputPrefix: "Song"}
+	segments := []segment{{start: 0, end: 10}, {start: 20, end: 30}}
+
+	splitVideoIntoSegments(cfg, segments, exportOptions{extraArgs: map[int][]string{1: {"-af", "volume=2"}}})
+
+	if len(fake.calls) != 2 {
+		t.Fatalf("Expected 2 ffmpeg calls, got %d", len(fake.calls))
+	}
+	untagged := []string{"-hide_banner", "-loglevel", "warning", "-ss", "0.000", "-i", "in.mp4", "-t", "10.000", "-c:v", "copy", "-c:a", "copy", outDir + "/Song_01.mp4"}
+	if !reflect.DeepEqual(fake.calls[0].args, untagged) {
+		t.Errorf("Expected untagged segment args %v, got %v", untagged, fake.calls[0].args)
+	}
+	tagged := []string{"-hide_banner", "-loglevel", "warning", "-ss", "20.000", "-i", "in.mp4", "-t", "10.000", "-c:v", "copy", "-c:a", "aac", "-af", "volume=2", outDir + "/Song_02.mp4"}
+	if !reflect.DeepEqual(fake.calls[1].args, tagged) {
+		t.Errorf("Expected tagged segment args %v, got %v", tagged, fake.calls[1].args)
+	}
+}
+
+func TestStreamMapArgs(t *testing.T) {
+	seg := segment{start: 5, end: 65}
+
+	t.Run("Default", func(t *testing.T) {
+		args := buildExportArgs(Config{InputFile: "in.mkv"}, seg, "out.mkv", nil)
+		expected := []string{"-hide_banner", "-loglevel", "warning", "-ss", "5.000", "-i", "in.mkv", "-t", "60.000", "-c:v", "copy", "-c:a", "copy", "out.mkv"}
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("Expected %v, got %v", expected, args)
+		}
+	})
+
+	t.Run("MapAllAudio", func(t *testing.T) {
+		args := buildExportArgs(Config{InputFile: "in.mkv", MapAllAudio: true}, seg, "out.mkv", nil)
+		expected := []string{"-hide_banner", "-loglevel", "warning", "-ss", "5.000", "-i", "in.mkv", "-t", "60.000", "-map", "0:v?", "-map", "0:a", "-c:v", "copy", "-c:a", "copy", "out.mkv"}
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("Expected %v, got %v", expected, args)
+		}
+	})
+}
+
+func TestCheckSegmentCount(t *testing.T) {
+	testCases := []struct {
+		name    string
+		count   int
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "ChecksDisabled", count: 500, cfg: Config{}, wantErr: false},
+		{name: "WithinRange", count: 8, cfg: Config{MinExpectedSegments: 5, MaxExpectedSegments: 15}, wantErr: false},
+		{name: "AtBounds", count: 5, cfg: Config{MinExpectedSegments: 5, MaxExpectedSegments: 5}, wantErr: false},
+		{name: "BelowMin", count: 0, cfg: Config{MinExpectedSegments: 1}, wantErr: true},
+		{name: "AboveMax", count: 500, cfg: Config{MaxExpectedSegments: 30}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkSegmentCount(tc.count, tc.cfg)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("checkSegmentCount(%d) error = %v, wantErr %v", tc.count, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvertSegments(t *testing.T) {
+	testCases := []struct {
+		name     string
+		songs    []segment
+		total    float64
+		expected []segment
+	}{
+		{
+			name:     "NoSongs",
+			songs:    []segment{},
+			total:    300.0,
+			expected: []segment{{start: 0, end: 300.0}},
+		},
+		{
+			name:  "LeadingAndTrailingGaps",
+			songs: []segment{{start: 30.0, end: 200.0}, {start: 210.0, end: 280.0}},
+			total: 300.0,
+			expected: []segment{
+				{start: 0, end: 30.0},
+				{start: 200.0, end: 210.0},
+				{start: 280.0, end: 300.0},
+			},
+		},
+		{
+			name:     "SongsCoverEdges",
+			songs:    []segment{{start: 0, end: 100.0}, {start: 110.0, end: 300.0}},
+			total:    300.0,
+			expected: []segment{{start: 100.0, end: 110.0}},
+		},
+		{
+			name:     "IgnoresSlivers",
+			songs:    []segment{{start: 0.05, end: 299.95}},
+			total:    300.0,
+			expected: []segment{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := invertSegments(tc.songs, tc.total)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestUploadToMultipleDestinations(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if call.args[2] == "gdrive:Shows/output" {
+			return fakeResult{exitCode: 1}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{
+		RcloneRemote:   "ignored:",
+		DriveSubfolder: "Ignored",
+		OutputDir:      "output",
+		UploadDestinations: []UploadDestination{
+			{Remote: "gdrive:", Subfolder: "Shows"},
+			{Remote: "nas:", Subfolder: "Backups/Shows"},
+		},
+	}
+
+	uploadToDrive(cfg)
+
+	expected := []fakeCall{
+		{name: "rclone", args: []string{"copy", "output", "gdrive:Shows/output", "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"}},
+		{name: "rclone", args: []string{"copy", "output", "nas:Backups/Shows/output", "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"}},
+	}
+	if !reflect.DeepEqual(fake.calls, expected) {
+		t.Errorf("Expected both destinations to be attempted %+v, got %+v", expected, fake.calls)
+	}
+}
+
+func TestUploadDestinationsFallback(t *testing.T) {
+	cfg := Config{RcloneRemote: "gdrive:", DriveSubfolder: "SplitSongs"}
+	expected := []UploadDestination{{Remote: "gdrive:", Subfolder: "SplitSongs"}}
+	if got := uploadDestinations(cfg); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected fallback destination %+v, got %+v", expected, got)
+	}
+}
+
+func TestCodecArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      Config
+		expected []string
+	}{
+		{
+			name:     "CopyByDefault",
+			cfg:      Config{VideoCRF: 18, VideoBitrate: "4M"},
+			expected: []string{"-c:v", "copy", "-c:a", "copy"},
+		},
+		{
+			name:     "ReencodeDefaultCRF",
+			cfg:      Config{Reencode: true},
+			expected: []string{"-c:v", "libx264", "-crf", "20", "-c:a", "aac"},
+		},
+		{
+			name:     "ReencodeCustomCRF",
+			cfg:      Config{Reencode: true, VideoCRF: 23, AudioBitrate: "192k"},
+			expected: []string{"-c:v", "libx264", "-crf", "23", "-c:a", "aac", "-b:a", "192k"},
+		},
+		{
+			name:     "ReencodeBitrate",
+			cfg:      Config{Reencode: true, VideoBitrate: "4M"},
+			expected: []string{"-c:v", "libx264", "-b:v", "4M", "-c:a", "aac"},
+		},
+		{
+			name:     "TrimSilenceReencodesAudio",
+			cfg:      Config{TrimSilence: true, AudioBitrate: "192k"},
+			expected: []string{"-c:v", "copy", "-c:a", "aac", "-b:a", "192k"},
+		},
+		{
+			name:     "CRFWinsOverBitrate",
+			cfg:      Config{Reencode: true, VideoCRF: 18, VideoBitrate: "4M"},
+			expected: []string{"-c:v", "libx264", "-crf", "18", "-c:a", "aac"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := codecArgs(tc.cfg); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTightenSegment(t *testing.T) {
+	seg := segment{start: 100.0, end: 300.0} // 200s song
+	testCases := []struct {
+		name     string
+		starts   []float64
+		ends     []float64
+		expected segment
+	}{
+		{
+			name:     "NoSilence",
+			expected: seg,
+		},
+		{
+			name:     "LeadingTuning",
+			starts:   []float64{0},
+			ends:     []float64{12.5},
+			expected: segment{start: 112.5, end: 300.0},
+		},
+		{
+			name:     "TrailingTailUnterminated",
+			starts:   []float64{180.0},
+			expected: segment{start: 100.0, end: 280.0},
+		},
+		{
+			name:     "TrailingTailEndsAtEOF",
+			starts:   []float64{190.0},
+			ends:     []float64{200.0},
+			expected: segment{start: 100.0, end: 290.0},
+		},
+		{
+			name:     "BothEdgesWithRestInMiddle",
+			starts:   []float64{0, 90.0, 195.0},
+			ends:     []float64{5.0, 92.0},
+			expected: segment{start: 105.0, end: 295.0},
+		},
+		{
+			name:     "MostlySilentIsLeftAlone",
+			starts:   []float64{0, 40.0},
+			ends:     []float64{30.0},
+			expected: seg,
+		},
+		{
+			name:     "SilentThroughout",
+			starts:   []float64{0},
+			expected: seg,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tightenSegment(seg, tc.starts, tc.ends); got != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAutoTrimSegments(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: "silence_start: 0\nsilence_end: 8 | silence_duration: 8\n"}
+	})
+	cfg := Config{InputFile: "in.mp4", SilenceThreshold: "-20dB"}
+
+	trimmed := autoTrimSegments(cfg, []segment{{start: 60, end: 260}})
+
+	if expected := []segment{{start: 68, end: 260}}; !reflect.DeepEqual(trimmed, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, trimmed)
+	}
+	expectedArgs := []string{"-hide_banner", "-loglevel", "info", "-ss", "60.000", "-t", "200.000", "-i", "in.mp4", "-af", "silencedetect=noise=-20dB:d=1.0", "-f", "null", "-"}
+	if len(fake.calls) != 1 || !reflect.DeepEqual(fake.calls[0].args, expectedArgs) {
+		t.Errorf("Expected one scoped detection call %v, got %+v", expectedArgs, fake.calls)
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	testCases := []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{180 * 1024 * 1024, "180 MB"},
+		{2254857830, "2.1 GB"},
+		{5 * 1024 * 1024 * 1024 * 1024 * 1024, "5120 TB"},
+	}
+	for _, tc := range testCases {
+		if got := humanizeBytes(tc.n); got != tc.expected {
+			t.Errorf("humanizeBytes(%d): expected %q, got %q", tc.n, tc.expected, got)
+		}
+	}
+}
+
+func TestCompressionRatio(t *testing.T) {
+	if got := compressionRatio(1000, 86); got != 8.6 {
+		t.Errorf("Expected 8.6%%, got %v", got)
+	}
+	if got := compressionRatio(0, 100); got != 0 {
+		t.Errorf("Expected 0 for an empty input, got %v", got)
+	}
+}
+
+func TestRenameFilesFromSetlistReturnsNewPaths(t *testing.T) {
+	dir := t.TempDir()
+	var exported []string
+	for _, name := range []string{"Song_01.mp4", "Song_02.mp4", "Song_03.mp4"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		exported = append(exported, path)
+	}
+
+	final := renameFilesFromSetlist(exported, []string{"Reba", "Kid Charlemagne"}, "")
+
+	expected := []string{
+		filepath.Join(dir, "01 - Reba.mp4"),
+		filepath.Join(dir, "02 - Kid_Charlemagne.mp4"),
+		filepath.Join(dir, "Song_03.mp4"),
+	}
+	if !reflect.DeepEqual(final, expected) {
+		t.Errorf("Expected %v, got %v", expected, final)
+	}
+	for _, path := range final {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected '%s' to exist: %v", path, err)
+		}
+	}
+}
+
+func TestSessionNamePrefixesFileNames(t *testing.T) {
+	installFakeExec(t, nil)
+	dir := t.TempDir()
+	cfg := Config{InputFile: "in.mp4", OutputDir: dir, OutputPrefix: "Song", SessionName: "Spring Rehearsal!"}
+
+	exported := exportedPaths(splitVideoIntoSegments(cfg, []segment{{start: 0, end: 200}, {start: 210, end: 400}}, exportOptions{}))
+
+	want := []string{filepath.Join(dir, "Spring_Rehearsal_Song_01.mp4"), filepath.Join(dir, "Spring_Rehearsal_Song_02.mp4")}
+	if !reflect.DeepEqual(exported, want) {
+		t.Fatalf("Expected %q, got %q", want, exported)
+	}
+	for _, path := range exported {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	final := renameFilesFromSetlist(exported, []string{"Reba", ""}, cfg.SessionName)
+	want = []string{filepath.Join(dir, "Spring_Rehearsal_01 - Reba.mp4"), want[1]}
+	if !reflect.DeepEqual(final, want) {
+		t.Errorf("Expected %q, got %q", want, final)
+	}
+}
+
+func TestFFmpegLogLevels(t *testing.T) {
+	fake := installFakeExec(t, nil)
+	cfg := Config{InputFile: "in.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", SilenceThreshold: "-20dB", FFmpegLogLevel: "error"}
+
+	getVideoDuration(cfg)
+	detectSilentSegments(cfg, nil)
+	splitVideoIntoSegments(cfg, []segment{{start: 0, end: 10}}, exportOptions{})
+
+	if len(fake.calls) != 3 {
+		t.Fatalf("Expected 3 ffmpeg calls, got %d", len(fake.calls))
+	}
+	// Duration and detection parse info-level output, so they must stay at info.
+	for i, want := range []string{"info", "info", "error"} {
+		args := fake.calls[i].args
+		if len(args) < 3 || args[0] != "-hide_banner" || args[1] != "-loglevel" || args[2] != want {
+			t.Errorf("Call %d: expected to start with -hide_banner -loglevel %s, got %v", i, want, args)
+		}
+	}
+}
+
+func TestMonoDetectionOnlyAffectsDetection(t *testing.T) {
+	stderr := "silence_start: 180.5\nsilence_end: 190.25 | silence_duration: 9.75\n"
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: stderr}
+	})
+	cfg := Config{InputFile: "in.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", SilenceThreshold: "-20dB", MinSilenceDur: 5.0, MonoDetection: true}
+
+	silences := detectSilentSegments(cfg, nil)
+	splitVideoIntoSegments(cfg, []segment{{start: 0, end: 180.5}}, exportOptions{})
+
+	// The downmix doesn't change the reported timestamps.
+	if expected := []segment{{start: 180.5, end: 190.25}}; !reflect.DeepEqual(silences, expected) {
+		t.Errorf("Expected silences %+v, got %+v", expected, silences)
+	}
+	detectArgs := strings.Join(fake.calls[0].args, " ")
+	if !strings.Contains(detectArgs, "-af aformat=channel_layouts=mono,silencedetect=noise=-20dB:d=5.0") {
+		t.Errorf("Expected the detection filter to downmix first, got %s", detectArgs)
+	}
+	exportArgs := strings.Join(fake.calls[1].args, " ")
+	if strings.Contains(exportArgs, "mono") || strings.Contains(exportArgs, "-ac") {
+		t.Errorf("Expected the export to keep all channels, got %s", exportArgs)
+	}
+}
+
+func TestFindSongSegments(t *testing.T) {
+	t.Run("NoSplit", func(t *testing.T) {
+		fake := installFakeExec(t, nil)
+		// MinSongLength is longer than the file, which NoSplit must ignore.
+		cfg := Config{InputFile: "in.mp4", MinSongLength: 600, NoSplit: true}
+
+		songs, silences := findSongSegments(cfg, 300.0)
+
+		if expected := []segment{{start: 0, end: 300.0}}; !reflect.DeepEqual(songs, expected) {
+			t.Errorf("Expected one full-length segment %+v, got %+v", expected, songs)
+		}
+		if silences != nil || len(fake.calls) != 0 {
+			t.Errorf("Expected detection to be skipped, got silences %+v and calls %+v", silences, fake.calls)
+		}
+	})
+
+	t.Run("Chunks", func(t *testing.T) {
+		fake := installFakeExec(t, nil)
+		// The last chunk is kept even though it's under MinSongLength.
+		cfg := Config{InputFile: "in.mp4", MinSongLength: 120, ChunkLength: 600}
+
+		songs, silences := findSongSegments(cfg, 1500.0)
+
+		expected := []segment{{start: 0, end: 600}, {start: 600, end: 1200}, {start: 1200, end: 1500}}
+		if !reflect.DeepEqual(songs, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, songs)
+		}
+		if songs, _ := findSongSegments(cfg, 1230.0); len(songs) != 3 || songs[2] != (segment{start: 1200, end: 1230}) {
+			t.Errorf("Expected a final 30s chunk, got %+v", songs)
+		}
+		if songs, _ := findSongSegments(cfg, 1200.0); len(songs) != 2 {
+			t.Errorf("Expected exactly two chunks for twice the chunk length, got %+v", songs)
+		}
+		if silences != nil || len(fake.calls) != 0 {
+			t.Errorf("Expected detection to be skipped, got silences %+v and calls %+v", silences, fake.calls)
+		}
+	})
+
+	t.Run("NoSilenceRespectsMinSongLength", func(t *testing.T) {
+		installFakeExec(t, nil)
+		songs, _ := findSongSegments(Config{InputFile: "in.mp4", MinSongLength: 600}, 300.0)
+		if len(songs) != 0 {
+			t.Errorf("Expected no songs when the whole file is shorter than MinSongLength, got %+v", songs)
+		}
+	})
+
+	t.Run("Detected", func(t *testing.T) {
+		installFakeExec(t, func(call fakeCall) fakeResult {
+			return fakeResult{stderr: "silence_start: 100\nsilence_end: 110\n"}
+		})
+		songs, silences := findSongSegments(Config{InputFile: "in.mp4", MinSongLength: 10}, 300.0)
+		if expected := []segment{{start: 0, end: 100}, {start: 110, end: 300}}; !reflect.DeepEqual(songs, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, songs)
+		}
+		if len(silences) != 1 {
+			t.Errorf("Expected the detected silence to be returned, got %+v", silences)
+		}
+	})
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected string
+	}{
+		{in: "Kid Charlemagne", expected: "Kid_Charlemagne"},
+		{in: "Don't Stop (Live)", expected: "Dont_Stop_Live"},
+		{in: "AC/DC Cover", expected: "AC_DC_Cover"},
+		{in: "../../etc/evil", expected: "etc_evil"},
+		{in: `..\..\windows\evil`, expected: "windows_evil"},
+		{in: "..", expected: "Untitled_Song"},
+		{in: "   ", expected: "Untitled_Song"},
+	}
+	for _, tc := range testCases {
+		got := sanitizeFilename(tc.in)
+		if got != tc.expected {
+			t.Errorf("sanitizeFilename(%q): expected %q, got %q", tc.in, tc.expected, got)
+		}
+		if strings.ContainsAny(got, `/\`) || strings.Contains(got, "..") {
+			t.Errorf("sanitizeFilename(%q) = %q still contains a path component", tc.in, got)
+		}
+	}
+}
+
+func TestRenameFilesFromSetlistStaysInOutputDir(t *testing.T) {
+	root := t.TempDir()
+	outDir := filepath.Join(root, "output")
+	if err := os.Mkdir(outDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	exported := filepath.Join(outDir, "Song_01.mp4")
+	if err := os.WriteFile(exported, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	final := renameFilesFromSetlist([]string{exported}, []string{"../../etc/evil"}, "")
+
+	if filepath.Dir(final[0]) != outDir {
+		t.Errorf("Expected the renamed file to stay in '%s', got '%s'", outDir, final[0])
+	}
+	if _, err := os.Stat(final[0]); err != nil {
+		t.Errorf("Expected '%s' to exist: %v", final[0], err)
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 1 {
+		t.Errorf("Expected nothing to be written outside the output dir, found %v", entries)
+	}
+}
+
+func TestWithinDir(t *testing.T) {
+	dir := filepath.Join("out", "songs")
+	testCases := []struct {
+		path     string
+		expected bool
+	}{
+		{filepath.Join(dir, "01 - Reba.mp4"), true},
+		{dir, true},
+		{filepath.Join(dir, "..", "escape.mp4"), false},
+		{filepath.Join(dir, "..", "..", "..", "etc", "evil"), false},
+		{filepath.Join(dir, "..songs.mp4"), true},
+	}
+	for _, tc := range testCases {
+		if got := withinDir(dir, tc.path); got != tc.expected {
+			t.Errorf("withinDir(%q, %q): expected %v, got %v", dir, tc.path, tc.expected, got)
+		}
+	}
+}
+
+func TestGroupQuietWindows(t *testing.T) {
+	inf := math.Inf(-1)
+	// A spike at 2.0s breaks the first quiet run; the 1.5s run is too short.
+	dbs := []float64{-10, -50, -45, -48, -20, -60, -55, -52, -50, -58, -12, inf, inf, inf, inf, inf}
+	levels := make([]rmsLevel, len(dbs))
+	for i, db := range dbs {
+		levels[i] = rmsLevel{time: float64(i) * 0.5, level: db}
+	}
+
+	got := groupQuietWindows(levels, -40, 0.5, 2.0)
+
+	want := []segment{{start: 2.5, end: 5.0}, {start: 5.5, end: 8.0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected silences %v, got %v", want, got)
+	}
+}
+
+func TestParseRMSLevels(t *testing.T) {
+	output := `[Parsed_ametadata_3 @ 0x55d] frame:0    pts:0       pts_time:0
+[Parsed_ametadata_3 @ 0x55d] lavfi.astats.Overall.RMS_level=-23.456
+[Parsed_ametadata_3 @ 0x55d] frame:1    pts:4000    pts_time:0.5
+[Parsed_ametadata_3 @ 0x55d] lavfi.astats.Overall.RMS_level=-inf
+`
+	got := parseRMSLevels(output)
+	if len(got) != 2 || got[0] != (rmsLevel{time: 0, level: -23.456}) || got[1].time != 0.5 || !math.IsInf(got[1].level, -1) {
+		t.Errorf("Unexpected levels: %+v", got)
+	}
+}
+
+func TestParseDecibels(t *testing.T) {
+	if db, err := parseDecibels("-30dB"); err != nil || db != -30 {
+		t.Errorf("Expected -30, got %v (%v)", db, err)
+	}
+	if _, err := parseDecibels("0.001"); err == nil {
+		t.Error("Expected an amplitude ratio to be rejected")
+	}
+}
+
+func TestSeekModeArgOrder(t *testing.T) {
+	seg := segment{start: 300, end: 360}
+	testCases := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"CopyDefaultsToFast", Config{InputFile: "in.mp4"}, []string{"-ss", "300.000", "-i", "in.mp4", "-t", "60.000"}},
+		{"ReencodeDefaultsToAccurate", Config{InputFile: "in.mp4", Reencode: true}, []string{"-i", "in.mp4", "-ss", "300.000", "-t", "60.000"}},
+		{"AccurateCopy", Config{InputFile: "in.mp4", SeekMode: seekAccurate}, []string{"-i", "in.mp4", "-ss", "300.000", "-t", "60.000"}},
+		{"FastReencode", Config{InputFile: "in.mp4", Reencode: true, SeekMode: seekFast}, []string{"-ss", "300.000", "-i", "in.mp4", "-t", "60.000"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := buildExportArgs(tc.cfg, seg, "out.mp4", nil)
+			if got := args[3:9]; !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUnknownSeekModeWarnsOnce(t *testing.T) {
+	cfg := defaultConfig
+	cfg.SeekMode = "precise"
+
+	cfg, warnings, err := checkConfig(cfg)
+
+	if err != nil || cfg.SeekMode != "" || len(warnings) != 1 || !strings.Contains(warnings[0], "seek_mode 'precise'") {
+		t.Errorf("Expected one seek_mode warning and the default mode, got %q (warnings %q, err %v)", cfg.SeekMode, warnings, err)
+	}
+}
+
+func TestLosslessBoundaries(t *testing.T) {
+	cfg := Config{MinSongLength: 60, LosslessBoundaries: true}
+	testCases := []struct {
+		name     string
+		silences []segment
+		want     []segment
+	}{
+		{
+			name:     "CutsAtMidpoints",
+			silences: []segment{{start: 200, end: 210}, {start: 400, end: 404}},
+			want:     []segment{{start: 0, end: 205}, {start: 205, end: 402}, {start: 402, end: 600}},
+		},
+		{
+			// Cuts at 7.5, 205, 253 and 597.5: the 7.5s head joins song 1, and
+			// the 48s piece and 2.5s tail join the song before them.
+			name:     "ShortPiecesMergeIntoANeighbour",
+			silences: []segment{{start: 5, end: 10}, {start: 200, end: 210}, {start: 250, end: 256}, {start: 595, end: 600}},
+			want:     []segment{{start: 0, end: 253}, {start: 253, end: 600}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := calculateNonSilentSegments(tc.silences, 600, cfg)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Expected %v, got %v", tc.want, got)
+			}
+			if got[0].start != 0 || got[len(got)-1].end != 600 {
+				t.Errorf("Expected the songs to cover the whole timeline, got %v", got)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].start != got[i-1].end {
+					t.Errorf("Expected song %d to start where song %d ends, got %v", i+1, i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSongsFromSilencesBoundarySilence(t *testing.T) {
+	cfg := defaultConfig
+	cfg.MinSongLength = 60
+	// A 2.5s rest inside the first song, then real 6s and 8s gaps between songs.
+	silences := []segment{{start: 150, end: 152.5}, {start: 300, end: 306}, {start: 500, end: 508}}
+
+	t.Run("EverySilenceSplits", func(t *testing.T) {
+		got := songsFromSilences(cfg, silences, 800)
+
+		want := []segment{{start: 0, end: 150}, {start: 152.5, end: 300}, {start: 306, end: 500}, {start: 508, end: 800}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected a cut at every silence, got %v", got)
+		}
+	})
+
+	t.Run("RestsMergedThrough", func(t *testing.T) {
+		cfg := cfg
+		cfg.BoundarySilenceDur = 5
+		got := songsFromSilences(cfg, silences, 800)
+
+		want := []segment{{start: 0, end: 300}, {start: 306, end: 500}, {start: 508, end: 800}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected the rest kept inside the first song, got %v", got)
+		}
+	})
+
+	t.Run("OnlyRests", func(t *testing.T) {
+		cfg := cfg
+		cfg.BoundarySilenceDur = 10
+		got := songsFromSilences(cfg, silences, 800)
+
+		if want := []segment{{start: 0, end: 800}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected one song when no silence is long enough, got %v", got)
+		}
+	})
+}
+
+func TestTrimSilenceFilter(t *testing.T) {
+	cfg := Config{InputFile: "in.mp4", SilenceThreshold: "-40dB", TrimSilence: true}
+	args := strings.Join(buildExportArgs(cfg, segment{start: 0, end: 60}, "out.mp4", nil), " ")
+
+	want := "-af silenceremove=start_periods=1:start_threshold=-40dB,areverse,silenceremove=start_periods=1:start_threshold=-40dB,areverse out.mp4"
+	if !strings.HasSuffix(args, want) {
+		t.Errorf("Expected the export to end with %q, got %q", want, args)
+	}
+	cfg.TrimSilence = false
+	if args := strings.Join(buildExportArgs(cfg, segment{start: 0, end: 60}, "out.mp4", nil), " "); strings.Contains(args, "silenceremove") {
+		t.Errorf("Expected no silenceremove when disabled, got %q", args)
+	}
+}
+
+func TestTrimSilenceWithSongFilters(t *testing.T) {
+	cfg := Config{InputFile: "in.mp4", SilenceThreshold: "-40dB", TrimSilence: true}
+	extra := []string{"-metadata", "title=Quiet Ballad", "-af", "volume=2", "-filter:a", "highpass=f=80"}
+
+	args := buildExportArgs(cfg, segment{start: 0, end: 60}, "out.mp4", extra)
+
+	if n := slices.Index(args, "-af"); n < 0 || slices.Contains(args[n+1:], "-af") || slices.Contains(args, "-filter:a") {
+		t.Fatalf("Expected a single -af, got %q", args)
+	}
+	trim := "silenceremove=start_periods=1:start_threshold=-40dB"
+	want := "-af " + trim + ",areverse," + trim + ",areverse,volume=2,highpass=f=80 -metadata title=Quiet Ballad out.mp4"
+	if joined := strings.Join(args, " "); !strings.HasSuffix(joined, want) {
+		t.Errorf("Expected the export to end with %q, got %q", want, joined)
+	}
+}
+
+func TestAbsoluteTimingArgs(t *testing.T) {
+	cfg := Config{InputFile: "in.mp4", PreserveAbsoluteTiming: true}
+	args := strings.Join(buildExportArgs(cfg, segment{start: 725.5, end: 960}, "out.mp4", nil), " ")
+
+	if !strings.HasSuffix(args, "-output_ts_offset 725.500 out.mp4") {
+		t.Errorf("Expected the song's timestamps offset by its start, got %q", args)
+	}
+	cfg.PreserveAbsoluteTiming = false
+	if args := strings.Join(buildExportArgs(cfg, segment{start: 725.5, end: 960}, "out.mp4", nil), " "); strings.Contains(args, "-output_ts_offset") {
+		t.Errorf("Expected timestamps from zero by default, got %q", args)
+	}
+}
+
+func TestUploadModes(t *testing.T) {
+	testCases := map[string][]string{
+		uploadCopy:   {"copy", "output", "gdrive:Band/output", "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"},
+		uploadUpdate: {"copy", "output", "gdrive:Band/output", "--update", "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"},
+		uploadSync:   {"sync", "output", "gdrive:Band/output", "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"},
+	}
+	for mode, want := range testCases {
+		fake := installFakeExec(t, nil)
+		uploadToDrive(Config{OutputDir: "output", RcloneRemote: "gdrive:", DriveSubfolder: "Band", UploadMode: mode})
+		if len(fake.calls) != 1 || !reflect.DeepEqual(fake.calls[0].args, want) {
+			t.Errorf("Mode %s: expected rclone %v, got %+v", mode, want, fake.calls)
+		}
+	}
+}
+
+func TestSyncUploadNeedsConfirmation(t *testing.T) {
+	fake := installFakeExec(t, nil)
+	err := NewSplitter(Config{UploadToDrive: true, UploadMode: uploadSync}).Run()
+	if exitCodeFor(err) != exitConfig || len(fake.calls) != 0 {
+		t.Errorf("Expected sync without -confirm-sync to stop before running anything, got %v after %d call(s)", err, len(fake.calls))
+	}
+}
+
+func TestResolveOutputDir(t *testing.T) {
+	if got := resolveOutputDir("output_{count}songs", 12); got != "output_12songs" {
+		t.Errorf("Expected output_12songs, got %q", got)
+	}
+	if got := resolveOutputDir("shows/{count}/{count}", 3); got != "shows/3/3" {
+		t.Errorf("Expected every placeholder filled in, got %q", got)
+	}
+	if got := resolveOutputDir("output", 12); got != "output" {
+		t.Errorf("Expected a plain folder to be left alone, got %q", got)
+	}
+}
+
+func TestOutputDirCountUpload(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "practice.mp4")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 140\nsilence_end: 150\n"}
+		case strings.HasSuffix(args, "-i "+input):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+	out := filepath.Join(dir, "out_{count}songs")
+	cfg := Config{InputFile: input, OutputDir: out, OutputPrefix: "Song", SilenceThreshold: "-20dB", MinSilenceDur: 5, MinSongLength: 60,
+		UploadToDrive: true, RcloneRemote: "gdrive:", DriveSubfolder: "Band", UploadMode: uploadCopy}
+	splitter := NewSplitter(cfg)
+	if err := splitter.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	resolved := filepath.Join(dir, "out_2songs")
+	last := fake.calls[len(fake.calls)-1]
+	if last.name != "rclone" || last.args[1] != resolved {
+		t.Errorf("Expected the upload to use %s, got %s %v", resolved, last.name, last.args)
+	}
+	if splitter.Report.Segments[0].File != filepath.Join(resolved, "Song_01.mp4") || splitter.Report.Config.OutputDir != resolved {
+		t.Errorf("Expected the report to use the resolved folder, got %+v", splitter.Report.Segments[0])
+	}
+}
+
+func TestExportFallsBackToStreamCopy(t *testing.T) {
+	testCases := []struct {
+		name       string
+		stderr     string
+		noFallback bool
+		wantCalls  int
+		wantStatus string
+	}{
+		{"MissingEncoder", "Unknown encoder 'libx264'\n", false, 2, statusExported},
+		{"MissingEncoderFallbackOff", "Unknown encoder 'libx264'\n", true, 1, statusFailed},
+		{"OtherFailure", "practice.mp4: No such file or directory\n", false, 1, statusFailed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var fake *fakeExec
+			fake = installFakeExec(t, func(call fakeCall) fakeResult {
+				if len(fake.calls) == 1 {
+					return fakeResult{stderr: tc.stderr, exitCode: 1}
+				}
+				return fakeResult{}
+			})
+			cfg := Config{InputFile: "practice.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", Reencode: true, TrimSilence: true, SilenceThreshold: "-30dB"}
+			cfg.NoCopyFallback = tc.noFallback
+
+			results := splitVideoIntoSegments(cfg, []segment{{start: 10, end: 100}}, exportOptions{})
+
+			if len(fake.calls) != tc.wantCalls {
+				t.Fatalf("Expected %d ffmpeg call(s), got %d", tc.wantCalls, len(fake.calls))
+			}
+			if results[0].Status != tc.wantStatus || results[0].CopyFallback != (tc.wantCalls == 2) {
+				t.Errorf("Expected status %s, got %+v", tc.wantStatus, results[0])
+			}
+			if tc.wantCalls == 2 {
+				retry := strings.Join(fake.calls[1].args, " ")
+				if !strings.Contains(retry, "-c:v copy") || strings.Contains(retry, "silenceremove") {
+					t.Errorf("Expected a plain stream copy retry, got %s", retry)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyFallbackReencodesFilteredAudio(t *testing.T) {
+	var fake *fakeExec
+	fake = installFakeExec(t, func(call fakeCall) fakeResult {
+		if len(fake.calls) == 1 {
+			return fakeResult{stderr: "Unknown encoder 'libx264'\n", exitCode: 1}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{InputFile: "practice.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", Reencode: true}
+	opts := exportOptions{extraArgs: map[int][]string{0: {"-af", "volume=2"}}}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 0, end: 100}}, opts)
+
+	if len(fake.calls) != 2 || results[0].Status != statusExported {
+		t.Fatalf("Expected one stream copy retry that exports, got %d calls and %+v", len(fake.calls), results[0])
+	}
+	retry := strings.Join(fake.calls[1].args, " ")
+	for _, want := range []string{"-c:v copy", "-c:a aac", "-af volume=2"} {
+		if !strings.Contains(retry, want) {
+			t.Errorf("Expected %q in the retry, got %s", want, retry)
+		}
+	}
+}
+
+func TestParallelExportKeepsSegmentOrder(t *testing.T) {
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(orig) })
+
+	// Earlier songs take longer, so they finish last.
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		out := call.args[len(call.args)-1]
+		n, _ := strconv.Atoi(strings.TrimSuffix(out[len(out)-6:], ".mp4"))
+		return fakeResult{stderr: "boom\n", exitCode: 1, delay: time.Duration(6-n) * 30 * time.Millisecond}
+	})
+	cfg := Config{InputFile: "practice.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", Jobs: 4}
+	cfg.OrderedLogs = true
+	var segments []segment
+	for i := range 6 {
+		segments = append(segments, segment{start: float64(i * 100), end: float64(i*100 + 90)})
+	}
+
+	results := splitVideoIntoSegments(cfg, segments, exportOptions{})
+
+	for i, r := range results {
+		if r.Index != i+1 {
+			t.Errorf("Expected song %d at position %d, got %+v", i+1, i, r)
+		}
+	}
+	// With -ordered-logs each song's lines come out together, in song order.
+	last := 0
+	for _, m := range regexp.MustCompile(`(?:Exporting|Error splitting) segment (\d+)`).FindAllStringSubmatch(buf.String(), -1) {
+		n, _ := strconv.Atoi(m[1])
+		if n < last {
+			t.Fatalf("Expected song %d's lines before song %d's, got log:\n%s", n, last, buf.String())
+		}
+		last = n
+	}
+	if last != 6 {
+		t.Errorf("Expected lines for all 6 songs, got log:\n%s", buf.String())
+	}
+}
+
+func TestParallelExportReportsDoneInOrder(t *testing.T) {
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(orig) })
+
+	// Earlier songs take longer, so they finish last.
+	installFakeExec(t, func(call fakeCall) fakeResult {
+		out := call.args[len(call.args)-1]
+		n, _ := strconv.Atoi(strings.TrimSuffix(out[len(out)-6:], ".mp4"))
+		return fakeResult{delay: time.Duration(6-n) * 30 * time.Millisecond}
+	})
+	cfg := Config{InputFile: "practice.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", Jobs: 4}
+	cfg.OrderedLogs = true
+	var segments []segment
+	for i := range 6 {
+		segments = append(segments, segment{start: float64(i * 100), end: float64(i*100 + 90)})
+	}
+	s := &Splitter{ProgressFunc: logProgress}
+
+	splitVideoIntoSegments(cfg, segments, exportOptions{onDone: s.segmentDone})
+
+	var got []string
+	for _, m := range regexp.MustCompile(`Progress: song (\d+)/6 done`).FindAllStringSubmatch(buf.String(), -1) {
+		got = append(got, m[1])
+	}
+	if want := []string{"1", "2", "3", "4", "5", "6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the done lines in song order %q, got %q in log:\n%s", want, got, buf.String())
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SETLISTS", "/srv/setlists")
+	testCases := []struct {
+		in, expected string
+	}{
+		{"~", home},
+		{"~/Documents/setlists/tonight.txt", filepath.Join(home, "Documents", "setlists", "tonight.txt")},
+		{"$HOME/tonight.txt", filepath.Join(home, "tonight.txt")},
+		{"${SETLISTS}/tonight.txt", "/srv/setlists/tonight.txt"},
+		{"~/$SETLISTS.txt", filepath.Join(home, "srv", "setlists.txt")},
+		{"take$2.mp4", "take$2.mp4"},
+		{"$UNSET_SPLITTER_VAR/${UNSET_SPLITTER_VAR}.mp4", "$UNSET_SPLITTER_VAR/${UNSET_SPLITTER_VAR}.mp4"},
+		{"price $ list.txt", "price $ list.txt"},
+		{"~bob/tonight.txt", "~bob/tonight.txt"},
+		{"setlists/~/tonight.txt", "setlists/~/tonight.txt"},
+		{"-", "-"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		got, err := expandPath(tc.in)
+		if err != nil {
+			t.Errorf("expandPath(%q) failed: %v", tc.in, err)
+		} else if got != tc.expected {
+			t.Errorf("expandPath(%q): expected %q, got %q", tc.in, tc.expected, got)
+		}
+	}
+}
+
+func TestConfigLoadingExpandsPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configFile := filepath.Join(home, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"setlist_file": "~/setlists/tonight.txt", "output_dir": "$HOME/songs"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resetFlags()
+	defineFlags()
+	if err := flag.CommandLine.Parse([]string{"-config=~/config.json", "-input=~/practice.mp4"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	cfg, warnings, err := loadConfig()
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("loadConfig failed: %v %q", err, warnings)
+	}
+	if cfg.SetlistFile != filepath.Join(home, "setlists", "tonight.txt") || cfg.OutputDir != filepath.Join(home, "songs") || cfg.InputFile != filepath.Join(home, "practice.mp4") {
+		t.Errorf("Expected expanded paths, got %+v", cfg)
+	}
+}
+
+func TestRcloneGlobalFlags(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "practice.mp4")
+	if err := os.WriteFile(input, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		args := strings.Join(call.args, " ")
+		switch {
+		case strings.Contains(args, "silencedetect"):
+			return fakeResult{stderr: "silence_start: 140\nsilence_end: 150\n"}
+		case strings.HasSuffix(args, "-i "+input):
+			return fakeResult{stderr: "  Duration: 00:05:00.00, start: 0.000000, bitrate: 1000 kb/s\n"}
+		}
+		return fakeResult{}
+	})
+	out := filepath.Join(dir, "out")
+	flags := []string{"--fast-list", "--drive-acknowledge-abuse"}
+	cfg := Config{InputFile: input, OutputDir: out, OutputPrefix: "Song", SilenceThreshold: "-20dB", MinSilenceDur: 5, MinSongLength: 60,
+		UploadToDrive: true, RcloneRemote: "gdrive:", DriveSubfolder: "Band", UploadMode: uploadCopy, RcloneGlobalFlags: flags}
+	if err := NewSplitter(cfg).Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var rclone [][]string
+	for _, call := range fake.calls {
+		if call.name == "rclone" && len(call.args) > 0 && call.args[0] != "version" {
+			rclone = append(rclone, call.args)
+		}
+	}
+	expected := [][]string{
+		{"--fast-list", "--drive-acknowledge-abuse", "mkdir", "gdrive:Band"},
+		{"--fast-list", "--drive-acknowledge-abuse", "copy", out, buildRemotePath("gdrive:", "Band", out), "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"},
+	}
+	if !reflect.DeepEqual(rclone, expected) {
+		t.Errorf("Expected rclone calls %q, got %q", expected, rclone)
+	}
+}
+
+func TestUploadToggles(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult { return fakeResult{} })
+	out := t.TempDir()
+	cfg := Config{OutputDir: out, RcloneRemote: "gdrive:", DriveSubfolder: "Band", UploadMode: uploadCopy,
+		RcloneGlobalFlags: []string{"--fast-list", "--immutable"}, UploadNoTraverse: true, UploadImmutable: true}
+
+	uploadToDrive(cfg)
+
+	expected := []fakeCall{{name: "rclone", args: []string{"--fast-list", "--immutable", "--no-traverse", "copy", out, buildRemotePath("gdrive:", "Band", out), "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"}}}
+	if !reflect.DeepEqual(fake.calls, expected) {
+		t.Errorf("Expected %q, got %q", expected, fake.calls)
+	}
+
+	if flags := uploadRcloneFlags(Config{}); len(flags) != 0 {
+		t.Errorf("Expected no flags with the toggles off, got %q", flags)
+	}
+}
+
+func TestCheckRcloneGlobalFlags(t *testing.T) {
+	kept, warnings := checkRcloneGlobalFlags([]string{"--fast-list", "copy", "-P", "--progress=true", "--tpslimit=10"})
+	if expected := []string{"--fast-list", "--tpslimit=10"}; !reflect.DeepEqual(kept, expected) {
+		t.Errorf("Expected %q to be kept, got %q", expected, kept)
+	}
+	if len(warnings) != 3 {
+		t.Errorf("Expected a warning per dropped entry, got %q", warnings)
+	}
+}
+
+func TestConfigLoadingUploadOverride(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"upload_to_drive": true, "rclone_remote": "gdrive:"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		args []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"-upload=false"}, false},
+		{[]string{"-no-upload"}, false},
+		{[]string{"-upload", "-no-upload"}, false},
+	}
+	for _, tc := range testCases {
+		resetFlags()
+		defineFlags()
+		if err := flag.CommandLine.Parse(append([]string{"-config=" + configFile}, tc.args...)); err != nil {
+			t.Fatalf("failed to parse flags: %v", err)
+		}
+		cfg, _, err := loadConfig()
+		if err != nil {
+			t.Fatalf("loadConfig failed: %v", err)
+		}
+		if cfg.UploadToDrive != tc.want {
+			t.Errorf("%q: expected UploadToDrive %v, got %v", tc.args, tc.want, cfg.UploadToDrive)
+		}
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "01 - Opener.mp4")
+	if got := uniquePath(path); got != path {
+		t.Errorf("Expected a free path to be kept, got %s", got)
+	}
+	for _, name := range []string{"01 - Opener.mp4", "01 - Opener (2).mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := uniquePath(path), filepath.Join(dir, "01 - Opener (3).mp4"); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// subtitleCodecs are the subtitle codecs each output container can hold
+// as-is. Containers not listed aren't checked.
+var subtitleCodecs = map[string][]string{
+	"mp4":  {"mov_text"},
+	"mov":  {"mov_text"},
+	"m4v":  {"mov_text"},
+	"mkv":  {"subrip", "ass", "ssa", "webvtt", "text", "dvd_subtitle", "hdmv_pgs_subtitle", "dvb_subtitle"},
+	"webm": {"webvtt"},
+}
+
+// unsupportedSubtitles returns the input's subtitle codecs, from ffprobe's
+// JSON output, that the output container can't take with -c:s copy.
+func unsupportedSubtitles(cfg Config, probeJSON string) []string {
+	allowed, ok := subtitleCodecs[normalizeContainer(outputExt(cfg))]
+	info, err := parseProbeJSON(probeJSON)
+	if !ok || err != nil {
+		return nil
+	}
+	var unsupported []string
+	for _, s := range info.Streams {
+		if s.CodecType == "subtitle" && !slices.Contains(allowed, s.CodecName) && !slices.Contains(unsupported, s.CodecName) {
+			unsupported = append(unsupported, s.CodecName)
+		}
+	}
+	return unsupported
+}
+
+// warnUnsupportedSubtitles logs the subtitle codecs that will make every
+// export fail, before any time is spent on detection.
+func warnUnsupportedSubtitles(cfg Config, probeJSON string) {
+	for _, codec := range unsupportedSubtitles(cfg, probeJSON) {
+		log.Printf("Warning: keep_subtitles: a %s container can't hold %s subtitles, so exports will fail. "+
+			"Convert them first or pick another output_container.", normalizeContainer(outputExt(cfg)), codec)
+	}
+}
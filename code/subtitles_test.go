@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeepSubtitlesArgs(t *testing.T) {
+	seg := segment{start: 10, end: 100}
+	testCases := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{"Copy", Config{InputFile: "in.mp4", KeepSubtitles: true},
+			"-map 0:v:0? -map 0:a:0? -map 0:s? -c:v copy -c:a copy -c:s copy out.mp4"},
+		{"AllAudio", Config{InputFile: "in.mp4", KeepSubtitles: true, MapAllAudio: true},
+			"-map 0:v? -map 0:a -map 0:s? -c:v copy -c:a copy -c:s copy out.mp4"},
+		{"Reencode", Config{InputFile: "in.mp4", KeepSubtitles: true, Reencode: true},
+			"-map 0:v:0? -map 0:a:0? -map 0:s? -c:v libx264 -crf 20 -c:a aac -c:s copy out.mp4"},
+		{"Off", Config{InputFile: "in.mp4"},
+			"-t 90.000 -c:v copy -c:a copy out.mp4"},
+	}
+	for _, tc := range testCases {
+		args := strings.Join(buildExportArgs(tc.cfg, seg, "out.mp4", nil), " ")
+		if !strings.HasSuffix(args, tc.expected) {
+			t.Errorf("%s: expected args ending in %q, got %q", tc.name, tc.expected, args)
+		}
+	}
+}
+
+func TestUnsupportedSubtitles(t *testing.T) {
+	probe := `{"streams": [
+	  {"index": 0, "codec_type": "video", "codec_name": "h264"},
+	  {"index": 1, "codec_type": "audio", "codec_name": "aac"},
+	  {"index": 2, "codec_type": "subtitle", "codec_name": "mov_text"},
+	  {"index": 3, "codec_type": "subtitle", "codec_name": "mov_text"}
+	]}`
+	if got := unsupportedSubtitles(Config{InputFile: "in.mp4"}, probe); len(got) != 0 {
+		t.Errorf("Expected mov_text to fit mp4, got %q", got)
+	}
+	if got := unsupportedSubtitles(Config{InputFile: "in.mp4", OutputContainer: "mkv"}, probe); !reflect.DeepEqual(got, []string{"mov_text"}) {
+		t.Errorf("Expected mov_text to be flagged once for mkv, got %q", got)
+	}
+	if got := unsupportedSubtitles(Config{InputFile: "in.ts"}, probe); len(got) != 0 {
+		t.Errorf("Expected unknown containers to be skipped, got %q", got)
+	}
+}
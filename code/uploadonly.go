@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// uploadOnly uploads an already-split output folder without running ffmpeg,
+// for -upload-only: after fixing rclone, say, when a run's export worked
+// but its upload didn't. It runs the rclone pre-check on every destination,
+// uploads, and logs what went where.
+func uploadOnly(cfg Config) error {
+	if strings.Contains(cfg.OutputDir, "{count}") {
+		return withExitCode(exitConfig, fmt.Errorf("output_dir '%s' is only named once songs are found; pass the folder to upload with -output", cfg.OutputDir))
+	}
+	files, size, err := uploadFiles(cfg.OutputDir)
+	if err != nil {
+		return withExitCode(exitConfig, fmt.Errorf("cannot read output folder '%s': %v", cfg.OutputDir, err))
+	}
+	if len(files) == 0 {
+		return withExitCode(exitConfig, fmt.Errorf("output folder '%s' is empty; nothing to upload", cfg.OutputDir))
+	}
+	if cfg.UploadMode == uploadSync && !cfg.ConfirmSync {
+		return withExitCode(exitConfig, errors.New("upload_mode 'sync' deletes remote files that aren't in the output folder; pass -confirm-sync to allow it"))
+	}
+	if cfg.Archive != "" {
+		if archive, _ := archivePath(cfg.OutputDir, cfg.Archive); !fileExists(archive) {
+			log.Printf("Warning: archive '%s' not found; uploading the folder instead.", archive)
+			cfg.Archive = ""
+		}
+	}
+
+	log.Println("Running rclone pre-check...")
+	if !isRcloneInstalled() {
+		return withExitCode(exitMissingTool, errors.New("'rclone' was not found in your PATH"))
+	}
+	for _, dest := range uploadDestinations(cfg) {
+		if err := testRcloneConnection(dest, cfg.RcloneGlobalFlags); err != nil {
+			return withExitCode(exitUploadFailed, fmt.Errorf("rclone pre-check failed: %v\nPlease check 'rclone config' and your remote permissions", err))
+		}
+	}
+
+	log.Printf("Uploading %d file(s), %s, from '%s':", len(files), humanizeBytes(size), cfg.OutputDir)
+	for _, f := range files {
+		log.Printf("  %s", f)
+	}
+	results := uploadToDrive(cfg)
+	for _, r := range results {
+		if r.Status == statusFailed {
+			log.Printf("  %s: failed", r.Destination)
+		} else {
+			log.Printf("  %s: uploaded", r.Destination)
+		}
+	}
+	if failed := failedUploads(results); failed > 0 {
+		return withExitCode(exitUploadFailed, fmt.Errorf("upload failed for %d of %d destination(s)", failed, len(results)))
+	}
+	return nil
+}
+
+// uploadFiles lists the files under dir that get uploaded, relative to it,
+// and their total size.
+func uploadFiles(dir string) ([]string, int64, error) {
+	var files []string
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || uploadExcluded(dir, path) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, rel)
+		size += info.Size()
+		return nil
+	})
+	return files, size, err
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUploadOnly(t *testing.T) {
+	resetFlags()
+	defineFlags()
+	out := t.TempDir()
+	for _, name := range []string{"Song_01.mp4", "Song_02.mp4"} {
+		if err := os.WriteFile(filepath.Join(out, name), []byte("song"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fake := installFakeExec(t, func(call fakeCall) fakeResult { return fakeResult{} })
+	cfg := Config{OutputDir: out, RcloneRemote: "gdrive:", DriveSubfolder: "Band", UploadMode: uploadCopy}
+
+	if err := uploadOnly(cfg); err != nil {
+		t.Fatalf("uploadOnly failed: %v", err)
+	}
+
+	expected := []fakeCall{
+		{name: "rclone", args: []string{"version"}},
+		{name: "rclone", args: []string{"mkdir", "gdrive:Band"}},
+		{name: "rclone", args: []string{"copy", out, buildRemotePath("gdrive:", "Band", out), "-P", "--exclude", "*.error.log", "--exclude", "/.splitter-state.json"}},
+	}
+	if !reflect.DeepEqual(fake.calls, expected) {
+		t.Errorf("Expected only the pre-check and upload %q, got %q", expected, fake.calls)
+	}
+}
+
+func TestUploadOnlyFailures(t *testing.T) {
+	resetFlags()
+	defineFlags()
+	t.Run("EmptyFolder", func(t *testing.T) {
+		fake := installFakeExec(t, func(call fakeCall) fakeResult { return fakeResult{} })
+
+		err := uploadOnly(Config{OutputDir: t.TempDir(), RcloneRemote: "gdrive:"})
+
+		if exitCodeFor(err) != exitConfig || len(fake.calls) != 0 {
+			t.Errorf("Expected a config error before running rclone, got %v after %d call(s)", err, len(fake.calls))
+		}
+	})
+
+	t.Run("PreCheck", func(t *testing.T) {
+		out := t.TempDir()
+		if err := os.WriteFile(filepath.Join(out, "Song_01.mp4"), []byte("song"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fake := installFakeExec(t, func(call fakeCall) fakeResult {
+			if call.args[0] == "mkdir" {
+				return fakeResult{stderr: "didn't find section in config file", exitCode: 1}
+			}
+			return fakeResult{}
+		})
+
+		err := uploadOnly(Config{OutputDir: out, RcloneRemote: "nope:", UploadMode: uploadCopy})
+
+		if exitCodeFor(err) != exitUploadFailed {
+			t.Errorf("Expected an upload failure, got %v", err)
+		}
+		for _, call := range fake.calls {
+			if call.args[0] == "copy" {
+				t.Errorf("Expected no upload after a failed pre-check, got %q", strings.Join(call.args, " "))
+			}
+		}
+	})
+}
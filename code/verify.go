@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// probeDuration reads a media file's duration in seconds from its container.
+func probeDuration(path string) (float64, error) {
+	output, err := runFFprobe("-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", path)
+	if err != nil {
+		return 0, err
+	}
+	d, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not read duration of '%s' from ffprobe output %q", path, output)
+	}
+	return d, nil
+}
+
+// durationDeviates reports whether an export's actual length is more than
+// tolerance seconds off the expected length.
+func durationDeviates(expected, actual, tolerance float64) bool {
+	return math.Abs(actual-expected) > tolerance
+}
+
+// verifyDuration probes a finished export and, with verify_durations, logs a
+// song whose length is off by more than duration_tolerance. With
+// recut_durations the song is cut again with an accurate seek, which lands
+// on the exact frame rather than the nearest keyframe. trim_silence exports
+// are meant to come out shorter, so they aren't checked.
+func verifyDuration(cfg Config, i int, seg segment, result segmentResult, metadata []string, opts exportOptions, logf func(format string, args ...any)) segmentResult {
+	if !cfg.VerifyDurations || cfg.TrimSilence || result.Status != statusExported || result.Cached {
+		return result
+	}
+	expected := seg.end - seg.start
+	actual, err := probeDuration(result.File)
+	if err != nil {
+		logf("Warning: Could not check the length of segment %d: %v", i+1, err)
+		return result
+	}
+	if !durationDeviates(expected, actual, cfg.DurationTolerance) {
+		return result
+	}
+	logf("Warning: Segment %d came out %.2fs long, expected %.2fs (more than %gs off)", i+1, actual, expected, cfg.DurationTolerance)
+	result.DurationMismatch = actual - expected
+	if !cfg.RecutDurations || seekMode(cfg) == seekAccurate {
+		return result
+	}
+	logf("Re-cutting segment %d with an accurate seek", i+1)
+	accurate := cfg
+	accurate.SeekMode = seekAccurate
+	os.Remove(result.File) // ffmpeg won't overwrite it
+	recut := exportSegment(accurate, i, seg, result.File, metadata, opts, logf)
+	if recut.Status != statusExported {
+		return recut
+	}
+	recut.Recut = true
+	if actual, err := probeDuration(recut.File); err == nil && durationDeviates(expected, actual, cfg.DurationTolerance) {
+		logf("Warning: Segment %d is still %.2fs long after re-cutting, expected %.2fs", i+1, actual, expected)
+		recut.DurationMismatch = actual - expected
+	}
+	return recut
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDurationDeviates(t *testing.T) {
+	testCases := []struct {
+		expected, actual, tolerance float64
+		want                        bool
+	}{
+		{245, 245.04, 1, false},
+		{245, 244.2, 1, false},
+		{245, 246.5, 1, true}, // started a GOP early
+		{245, 240, 1, true},
+		{245, 246.5, 2, false},
+	}
+	for _, tc := range testCases {
+		if got := durationDeviates(tc.expected, tc.actual, tc.tolerance); got != tc.want {
+			t.Errorf("expected %.2fs, got %.2fs, tolerance %gs: expected %v, got %v", tc.expected, tc.actual, tc.tolerance, tc.want, got)
+		}
+	}
+}
+
+func TestVerifyDurationRecuts(t *testing.T) {
+	var fake *fakeExec
+	fake = installFakeExec(t, func(call fakeCall) fakeResult {
+		if call.name != "ffprobe" {
+			return fakeResult{}
+		}
+		if len(fake.calls) < 3 { // the stream copy came out long
+			return fakeResult{stdout: "251.480000\n"}
+		}
+		return fakeResult{stdout: "245.010000\n"}
+	})
+	cfg := Config{InputFile: "practice.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", VerifyDurations: true, DurationTolerance: 1, RecutDurations: true}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 600, end: 845}}, exportOptions{})
+
+	var exports [][]string
+	for _, c := range fake.calls {
+		if c.name == "ffmpeg" {
+			exports = append(exports, c.args)
+		}
+	}
+	if len(exports) != 2 {
+		t.Fatalf("Expected the song exported then re-cut, got %d export(s)", len(exports))
+	}
+	if seek, input := slices.Index(exports[1], "-ss"), slices.Index(exports[1], "-i"); seek < input {
+		t.Errorf("Expected the re-cut to seek after the input, got %q", exports[1])
+	}
+	if r := results[0]; !r.Recut || r.DurationMismatch != 0 || r.Status != statusExported {
+		t.Errorf("Expected a successful re-cut, got %+v", r)
+	}
+}
+
+func TestVerifyDurationWarnsOnly(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if call.name == "ffprobe" {
+			return fakeResult{stdout: "251.48\n"}
+		}
+		return fakeResult{}
+	})
+	cfg := Config{InputFile: "practice.mp4", OutputDir: t.TempDir(), OutputPrefix: "Song", VerifyDurations: true, DurationTolerance: 1}
+
+	results := splitVideoIntoSegments(cfg, []segment{{start: 600, end: 845}}, exportOptions{})
+
+	if len(fake.calls) != 2 {
+		t.Errorf("Expected one export and one probe, got %v", fake.calls)
+	}
+	if r := results[0]; r.Recut || r.DurationMismatch < 6.4 || r.DurationMismatch > 6.5 {
+		t.Errorf("Expected a 6.48s mismatch without a re-cut, got %+v", r)
+	}
+}
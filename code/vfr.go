@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// HandleVFR values: what to do with variable frame rate input.
+const (
+	vfrWarn     = "warn"     // log a warning and stream copy anyway
+	vfrReencode = "reencode" // re-encode to constant frame rate
+	vfrIgnore   = "ignore"   // don't check
+)
+
+// vfrTolerance is how far apart (relative) r_frame_rate and avg_frame_rate
+// can be before the video counts as variable frame rate. NTSC rates such as
+// 30000/1001 match exactly, so this only absorbs rounding in the average.
+const vfrTolerance = 0.01
+
+// parseFrameRate parses an ffprobe rate such as "30000/1001" or "30".
+func parseFrameRate(s string) (float64, bool) {
+	num, den, found := strings.Cut(s, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	if !found {
+		return n, true
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return n / d, true
+}
+
+// isVFR reports whether the first video stream in `ffprobe -show_streams -of
+// json` output has a variable frame rate. ffprobe's r_frame_rate is the
+// lowest rate that can represent every timestamp, so for phone recordings it
+// is often far above the average rate the frames actually arrive at.
+func isVFR(probeJSON string) bool {
+	info, err := parseProbeJSON(probeJSON)
+	if err != nil {
+		return false
+	}
+	for _, s := range info.Streams {
+		if s.CodecType == "video" {
+			return streamIsVFR(s)
+		}
+	}
+	return false
+}
+
+// streamIsVFR reports whether a video stream's r_frame_rate and
+// avg_frame_rate disagree.
+func streamIsVFR(s probeStream) bool {
+	r, okR := parseFrameRate(s.RFrameRate)
+	avg, okAvg := parseFrameRate(s.AvgFrameRate)
+	return okR && okAvg && math.Abs(r-avg)/avg > vfrTolerance
+}
+
+// formatFrameRate formats an ffprobe rate for people: "30000/1001" as
+// "29.97", "30/1" as "30". Unparseable rates are returned as they are.
+func formatFrameRate(rate string) string {
+	fps, ok := parseFrameRate(rate)
+	if !ok {
+		return rate
+	}
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", fps), "0"), ".")
+}
+
+// checkVFR warns about variable frame rate video in the input's probe
+// output, or switches the export to a constant frame rate re-encode, as
+// HandleVFR says.
+func checkVFR(cfg Config, probeJSON string) Config {
+	if cfg.HandleVFR == vfrIgnore {
+		return cfg
+	}
+	if !isVFR(probeJSON) {
+		return cfg
+	}
+	switch {
+	case cfg.HandleVFR == vfrReencode:
+		log.Println("Input has a variable frame rate; re-encoding to a constant frame rate (handle_vfr: reencode).")
+		cfg.Reencode = true
+	case !reencoding(cfg):
+		log.Println("Warning: Input has a variable frame rate, so stream-copied songs may drift out of sync. " +
+			"Use -handle-vfr reencode to export at a constant frame rate.")
+	}
+	return cfg
+}
+
+// frameRateArgs forces constant frame rate output when re-encoding with
+// handle_vfr "reencode".
+func frameRateArgs(cfg Config) []string {
+	if cfg.HandleVFR == vfrReencode && reencoding(cfg) {
+		return []string{"-vsync", "cfr"}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const cfrProbeJSON = `{"streams": [
+  {"index": 0, "codec_type": "video", "codec_name": "h264", "r_frame_rate": "30000/1001", "avg_frame_rate": "30000/1001"},
+  {"index": 1, "codec_type": "audio", "codec_name": "aac", "r_frame_rate": "0/0", "avg_frame_rate": "0/0"}
+]}`
+
+const vfrProbeJSON = `{"streams": [
+  {"index": 0, "codec_type": "audio", "codec_name": "aac", "r_frame_rate": "0/0", "avg_frame_rate": "0/0"},
+  {"index": 1, "codec_type": "video", "codec_name": "hevc", "r_frame_rate": "120/1", "avg_frame_rate": "1795500/60103"}
+]}`
+
+func TestIsVFR(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected bool
+	}{
+		{"CFR", cfrProbeJSON, false},
+		{"VFRPhoneVideo", vfrProbeJSON, true},
+		{"RoundedAverage", `{"streams": [{"codec_type": "video", "r_frame_rate": "25/1", "avg_frame_rate": "2499/100"}]}`, false},
+		{"AudioOnly", `{"streams": [{"codec_type": "audio", "r_frame_rate": "0/0", "avg_frame_rate": "0/0"}]}`, false},
+		{"UnknownAverage", `{"streams": [{"codec_type": "video", "r_frame_rate": "30/1", "avg_frame_rate": "0/0"}]}`, false},
+		{"NotJSON", "ffprobe: oops", false},
+	}
+	for _, tc := range testCases {
+		if got := isVFR(tc.json); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestCheckVFR(t *testing.T) {
+	for _, probe := range []string{cfrProbeJSON, vfrProbeJSON} {
+		for _, mode := range []string{vfrWarn, vfrReencode, vfrIgnore} {
+			cfg := checkVFR(Config{InputFile: "phone.mov", HandleVFR: mode}, probe)
+
+			want := probe == vfrProbeJSON && mode == vfrReencode
+			if cfg.Reencode != want {
+				t.Errorf("%s, VFR %v: expected Reencode %v, got %v", mode, probe == vfrProbeJSON, want, cfg.Reencode)
+			}
+		}
+	}
+}
+
+func TestCheckInputStreams(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		if slices.Contains(call.args, "-show_streams") {
+			return fakeResult{stdout: vfrProbeJSON}
+		}
+		return fakeResult{}
+	})
+	if cfg := checkInputStreams(Config{InputFile: "phone.mov", HandleVFR: vfrReencode}); !cfg.Reencode {
+		t.Error("Expected the probe to switch on re-encoding")
+	}
+
+	fake.calls = nil
+	checkInputStreams(Config{InputFile: "phone.mov", HandleVFR: vfrIgnore})
+	if len(fake.calls) != 0 {
+		t.Errorf("Expected no ffprobe calls with handle_vfr ignore, got %v", fake.calls)
+	}
+}
+
+func TestBuildExportArgsConstantFrameRate(t *testing.T) {
+	seg := segment{start: 10, end: 100}
+	cfg := Config{InputFile: "phone.mov", HandleVFR: vfrReencode, Reencode: true}
+	if args := strings.Join(buildExportArgs(cfg, seg, "out.mov", nil), " "); !strings.Contains(args, "-c:v libx264") || !strings.Contains(args, "-vsync cfr") {
+		t.Errorf("Expected a constant frame rate re-encode, got %s", args)
+	}
+	cfg.Reencode = false
+	if args := strings.Join(buildExportArgs(cfg, seg, "out.mov", nil), " "); strings.Contains(args, "-vsync") {
+		t.Errorf("Expected no -vsync for a stream copy, got %s", args)
+	}
+}
+
+func TestProbeInfoFrameRate(t *testing.T) {
+	testCases := []struct {
+		name, probe, want string
+	}{
+		{"CFR", cfrProbeJSON, "video h264, 0x0, 29.97 fps\n"},
+		{"VFR", vfrProbeJSON, "video hevc, 0x0, 29.87 fps average, variable frame rate (r_frame_rate 120)"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := parseProbeJSON(tc.probe)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			printProbeInfo(&out, "phone.mp4", info)
+			if !strings.Contains(out.String(), tc.want) {
+				t.Errorf("Expected %q in\n%s", tc.want, out.String())
+			}
+		})
+	}
+}
+
+func TestFormatFrameRate(t *testing.T) {
+	for rate, want := range map[string]string{"30000/1001": "29.97", "30/1": "30", "25": "25", "24000/1001": "23.98", "0/0": "0/0"} {
+		if got := formatFrameRate(rate); got != want {
+			t.Errorf("formatFrameRate(%q): expected %q, got %q", rate, want, got)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parseAnalysisWindows parses analysis_windows, a comma-separated list of
+// START-END times such as "0:00-30:00,45:00-60:00", into sorted windows. An
+// empty string means the whole recording and gives no windows.
+func parseAnalysisWindows(s string) ([]segment, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var windows []segment
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		startText, endText, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("window '%s' should be START-END", part)
+		}
+		start, err := parseTimestamp(strings.TrimSpace(startText))
+		if err != nil {
+			return nil, fmt.Errorf("window '%s': %v", part, err)
+		}
+		end, err := parseTimestamp(strings.TrimSpace(endText))
+		if err != nil {
+			return nil, fmt.Errorf("window '%s': %v", part, err)
+		}
+		if end <= start {
+			return nil, fmt.Errorf("window '%s' ends before it starts", part)
+		}
+		windows = append(windows, segment{start: start, end: end})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].start < windows[j].start })
+	for i := 1; i < len(windows); i++ {
+		if windows[i].start < windows[i-1].end {
+			return nil, fmt.Errorf("windows %s and %s overlap", formatTrackTime(windows[i-1].start), formatTrackTime(windows[i].start))
+		}
+	}
+	return windows, nil
+}
+
+// analysisInput is the input part of a detection pass's ffmpeg command: the
+// whole recording, or only window if one is given.
+func analysisInput(cfg Config, window *segment) []string {
+	if window == nil {
+		return []string{"-i", cfg.InputFile}
+	}
+	return []string{
+		"-ss", fmt.Sprintf("%.3f", window.start),
+		"-t", fmt.Sprintf("%.3f", window.end-window.start),
+		"-i", cfg.InputFile,
+	}
+}
+
+// windowToAbsolute moves silences detected inside window, which ffmpeg times
+// from the window's start, onto the recording's timeline. A silence still
+// open when the window ends is closed at the window's end.
+func windowToAbsolute(silences []segment, window segment) []segment {
+	absolute := make([]segment, 0, len(silences))
+	for _, s := range silences {
+		s.start += window.start
+		s.end = min(s.end+window.start, window.end)
+		if s.end > s.start {
+			absolute = append(absolute, s)
+		}
+	}
+	return absolute
+}
+
+// withUnanalyzed treats everything outside analysis_windows as silence, so
+// no song is cut from a part of the recording that was never analysed.
+// Overlapping silences are merged.
+func withUnanalyzed(cfg Config, silences []segment, total float64) []segment {
+	windows, _ := parseAnalysisWindows(cfg.AnalysisWindows)
+	if len(windows) == 0 {
+		return silences
+	}
+	inside := make([]segment, 0, len(windows))
+	for _, w := range windows {
+		if w.start < total {
+			inside = append(inside, segment{start: w.start, end: min(w.end, total)})
+		}
+	}
+	all := append(append([]segment(nil), silences...), invertSegments(inside, total)...)
+	sort.Slice(all, func(i, j int) bool { return all[i].start < all[j].start })
+	merged := make([]segment, 0, len(all))
+	for _, s := range all {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, s.end)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
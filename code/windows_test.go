@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseAnalysisWindows(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    []segment
+		wantErr bool
+	}{
+		{input: "", want: nil},
+		{input: "0:00-30:00,45:00-1:00:00", want: []segment{{0, 1800}, {2700, 3600}}},
+		{input: "45:00-60:00, 0:00-30:00", want: []segment{{0, 1800}, {2700, 3600}}},
+		{input: "90-120.5", want: []segment{{90, 120.5}}},
+		{input: "30:00", wantErr: true},
+		{input: "30:00-10:00", wantErr: true},
+		{input: "0:00-30:00,29:00-40:00", wantErr: true},
+		{input: "0:00-soon", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := parseAnalysisWindows(tc.input)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", tc.input, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: expected %v, got %v (err %v)", tc.input, tc.want, got, err)
+		}
+	}
+}
+
+func TestWindowToAbsolute(t *testing.T) {
+	window := segment{start: 2700, end: 3600}
+	got := windowToAbsolute([]segment{{0, 4}, {300, 310}, {895, 905}}, window)
+	want := []segment{{2700, 2704}, {3000, 3010}, {3595, 3600}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestAnalysisWindowSeeksBeforeInput(t *testing.T) {
+	fake := installFakeExec(t, func(call fakeCall) fakeResult {
+		return fakeResult{stderr: "[silencedetect @ 0x1] silence_start: 290\n[silencedetect @ 0x1] silence_end: 300 | silence_duration: 10\n"}
+	})
+	cfg := defaultConfig
+	cfg.InputFile = "practice.mp4"
+	cfg.AnalysisWindows = "45:00-60:00"
+
+	got := runDetection(cfg)
+
+	want := []string{"-ss", "2700.000", "-t", "900.000", "-i", "practice.mp4"}
+	if len(fake.calls) != 1 {
+		t.Fatalf("Expected one pass over the window, got %v", fake.calls)
+	}
+	args := fake.calls[0].args
+	if i := slices.Index(args, "-ss"); i < 0 || !slices.Equal(args[i:i+len(want)], want) {
+		t.Errorf("Expected %q in the detection pass, got %q", want, args)
+	}
+	if !reflect.DeepEqual(got, []segment{{2990, 3000}}) {
+		t.Errorf("Expected the silence on the recording's timeline, got %v", got)
+	}
+}
+
+func TestSongsFromSilencesIgnoresUnanalyzed(t *testing.T) {
+	cfg := defaultConfig
+	cfg.MinSongLength = 60
+	cfg.AnalysisWindows = "0:00-30:00,45:00-60:00"
+	silences := []segment{{600, 610}, {3000, 3010}}
+
+	got := songsFromSilences(cfg, silences, 4000)
+
+	want := []segment{{0, 600}, {610, 1800}, {2700, 3000}, {3010, 3600}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected songs only inside the windows, got %v", got)
+	}
+}